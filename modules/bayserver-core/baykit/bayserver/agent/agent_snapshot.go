@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"bayserver-core/baykit/bayserver/common/memusage"
+	"time"
+)
+
+/**
+ * Read-only copy of the state of a grand agent.
+ * The snapshot is taken in the agent loop. Snapshots of different agents
+ * are taken at slightly different times.
+ */
+type AgentSnapshot struct {
+	AgentId int
+	Time    time.Time
+
+	/** Active inbound ships */
+	Ships []*ShipSnapshot
+
+	/** Number of inbound ships which are waiting for next request (keep-alive) */
+	KeepAliveShips int
+
+	/** Sizes of object stores */
+	Stores []memusage.StoreUsage
+
+	/** Sizes of warp ship pools */
+	Warps []memusage.WarpUsage
+}
+
+/**
+ * State of an inbound ship (client connection)
+ */
+type ShipSnapshot struct {
+	ShipId        int
+	RemoteAddress string
+	Protocol      string
+	Keeping       bool
+
+	/** Number of tours carried by the ship */
+	TourCount int
+
+	/** Tours in process */
+	Tours []*TourSnapshot
+}
+
+/**
+ * State of a tour (request) in process
+ */
+type TourSnapshot struct {
+	TourId  int
+	Method  string
+	Uri     string
+	Elapsed time.Duration
+}
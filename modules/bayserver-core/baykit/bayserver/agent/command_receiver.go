@@ -85,6 +85,9 @@ catch:
 		case CMD_MEM_USAGE:
 			memusage.Get(agent.AgentId()).PrintUsage(0)
 
+		case CMD_REOPEN_LOGS:
+			agent.ReopenLogs()
+
 		case CMD_SHUTDOWN:
 			agent.Shutdown()
 
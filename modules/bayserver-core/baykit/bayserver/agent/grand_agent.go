@@ -13,6 +13,7 @@ const CMD_MEM_USAGE = 3
 const CMD_SHUTDOWN = 4
 const CMD_ABORT = 5
 const CMD_CATCHUP = 6
+const CMD_REOPEN_LOGS = 7
 
 type GrandAgent interface {
 	String() string
@@ -28,6 +29,7 @@ type GrandAgent interface {
 	Abort()
 	ReloadCert()
 	PrintUsage()
+	ReopenLogs()
 	AddTimerHandler(th common.TimerHandler)
 	RemoveTimerHandler(th common.TimerHandler)
 	Ring()
@@ -38,13 +40,20 @@ type GrandAgent interface {
 	SendWroteLetter(st *common.RudderState, n int, akeup bool)
 	SendClosedLetter(st *common.RudderState, wakeup bool)
 	SendErrorLetter(st *common.RudderState, err exception.Exception, wakeup bool)
+	// SendRunLetter requests the agent to run the task in its own goroutine
+	SendRunLetter(task func(), wakeup bool)
 	Start()
 	AddPostpone(pp Postpone)
 	ReqCatchUp()
 	CatchUp()
+
+	// Snapshot requests read-only copy of the current state.
+	// The copy is taken in the agent loop and sent to the returned channel.
+	Snapshot() <-chan *AgentSnapshot
 }
 
 var Init func(agentIds []int, nShips int)
 var Get func(agentId int) GrandAgent
+var Agents func() []GrandAgent
 var AddLifeCycleListener func(lis common.LifecycleListener)
 var Add func(agentId int, anchorable bool) GrandAgent
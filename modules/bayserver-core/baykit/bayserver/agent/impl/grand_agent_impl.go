@@ -8,6 +8,9 @@ import (
 	"bayserver-core/baykit/bayserver/common"
 	"bayserver-core/baykit/bayserver/common/baymessage"
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/inboundship"
+	"bayserver-core/baykit/bayserver/common/inboundship/inboundshipstore"
+	"bayserver-core/baykit/bayserver/common/memusage"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/rudder"
 	"bayserver-core/baykit/bayserver/rudder/impl"
@@ -16,8 +19,10 @@ import (
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/sysutil"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 )
 
 const SELECT_TIMEOUT_SEC = 10
@@ -26,6 +31,7 @@ var agentCount int
 var maxShips int
 var maxAgentId int
 var agents = map[int]agent.GrandAgent{}
+var agentsLock sync.Mutex
 var listeners []common.LifecycleListener
 
 type GrandAgentImpl struct {
@@ -172,6 +178,9 @@ catch:
 
 			case *letter.ErrorLetter:
 				err = g.onError(let2)
+
+			case *letter.RunLetter:
+				let2.Task()
 			}
 
 			if err != nil {
@@ -236,7 +245,9 @@ func (g *GrandAgentImpl) Shutdown() {
 		lis.Remove(g.agentId)
 	}
 
+	agentsLock.Lock()
 	delete(agents, g.agentId)
+	agentsLock.Unlock()
 }
 
 func (g *GrandAgentImpl) Abort() {
@@ -251,6 +262,14 @@ func (g *GrandAgentImpl) PrintUsage() {
 
 }
 
+func (g *GrandAgentImpl) ReopenLogs() {
+	for _, lis := range listeners {
+		if rlis, ok := lis.(common.LogReopenListener); ok {
+			rlis.ReopenLogs(g.agentId)
+		}
+	}
+}
+
 func (g *GrandAgentImpl) AddTimerHandler(th common.TimerHandler) {
 	g.timerHandlers = append(g.timerHandlers, th)
 }
@@ -297,6 +316,10 @@ func (g *GrandAgentImpl) SendErrorLetter(st *common.RudderState, err exception.E
 	g.sendLetter(letter.NewErrorLetter(st, err), wakeup)
 }
 
+func (g *GrandAgentImpl) SendRunLetter(task func(), wakeup bool) {
+	g.sendLetter(letter.NewRunLetter(task), wakeup)
+}
+
 func (g *GrandAgentImpl) AddPostpone(pp agent.Postpone) {
 	g.postponeQueue = append(g.postponeQueue, pp)
 }
@@ -558,6 +581,14 @@ func (g *GrandAgentImpl) countPostpone() int {
 	return len(g.postponeQueue)
 }
 
+func (g *GrandAgentImpl) Snapshot() <-chan *agent.AgentSnapshot {
+	ch := make(chan *agent.AgentSnapshot, 1)
+	g.SendRunLetter(func() {
+		ch <- g.takeSnapshot()
+	}, true)
+	return ch
+}
+
 func (g *GrandAgentImpl) CatchUp() {
 	g.postponeQueueLock.Lock()
 	baylog.Debug("%s catchUp", g)
@@ -569,12 +600,38 @@ func (g *GrandAgentImpl) CatchUp() {
 	g.postponeQueueLock.Unlock()
 }
 
+// takeSnapshot copies the state of ships and stores. (Must be called in the agent loop)
+func (g *GrandAgentImpl) takeSnapshot() *agent.AgentSnapshot {
+	snap := &agent.AgentSnapshot{
+		AgentId: g.agentId,
+		Time:    time.Now(),
+		Ships:   []*agent.ShipSnapshot{},
+	}
+
+	if store := inboundshipstore.Get(g.agentId); store != nil {
+		for _, obj := range store.ActiveList() {
+			sipSnap := obj.(inboundship.InboundShip).Snapshot()
+			if sipSnap.Keeping && len(sipSnap.Tours) == 0 {
+				snap.KeepAliveShips++
+			}
+			snap.Ships = append(snap.Ships, sipSnap)
+		}
+	}
+
+	if mu := memusage.Get(g.agentId); mu != nil {
+		snap.Stores = mu.StoreUsages()
+		snap.Warps = mu.WarpUsages()
+	}
+	return snap
+}
+
 /****************************************/
 /* static functions                    */
 /****************************************/
 
 func Init() {
 	agent.Get = _get
+	agent.Agents = _agents
 	agent.AddLifeCycleListener = _addLifecycleListener
 	agent.Add = _add
 	agent.Init = _init
@@ -599,7 +656,9 @@ func _add(
 	}
 
 	agt := NewGrandAgent(agentId, maxShips, anchorable)
+	agentsLock.Lock()
 	agents[agentId] = agt
+	agentsLock.Unlock()
 
 	for _, lis := range listeners {
 		lis.Add(agentId)
@@ -609,9 +668,24 @@ func _add(
 }
 
 func _get(agtId int) agent.GrandAgent {
+	agentsLock.Lock()
+	defer agentsLock.Unlock()
+
 	return agents[agtId]
 }
 
+func _agents() []agent.GrandAgent {
+	agentsLock.Lock()
+	defer agentsLock.Unlock()
+
+	agtList := make([]agent.GrandAgent, 0, len(agents))
+	for _, agt := range agents {
+		agtList = append(agtList, agt)
+	}
+	sort.Slice(agtList, func(i, j int) bool { return agtList[i].AgentId() < agtList[j].AgentId() })
+	return agtList
+}
+
 func _addLifecycleListener(lis common.LifecycleListener) {
 	listeners = append(listeners, lis)
 }
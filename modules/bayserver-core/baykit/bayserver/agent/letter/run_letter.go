@@ -0,0 +1,19 @@
+package letter
+
+/**
+ * Letter to run a task in the agent which receives the letter
+ */
+
+type RunLetter struct {
+	LetterStruct
+	Task func()
+}
+
+func NewRunLetter(task func()) *RunLetter {
+	return &RunLetter{
+		LetterStruct: LetterStruct{
+			state: nil,
+		},
+		Task: task,
+	}
+}
@@ -92,6 +92,11 @@ func (gm *GrandAgentMonitor) ReloadCert() exception.IOException {
 	return gm.send(agent.CMD_RELOAD_CERT)
 }
 
+func (gm *GrandAgentMonitor) ReopenLogs() exception.IOException {
+	baylog.Debug("%s send reopen logs command", gm)
+	return gm.send(agent.CMD_REOPEN_LOGS)
+}
+
 func (gm *GrandAgentMonitor) PrintUsage() exception.IOException {
 	baylog.Debug("%s send usage command", gm)
 	ioerr := gm.send(agent.CMD_MEM_USAGE)
@@ -150,6 +155,21 @@ func ReloadCertAll() exception.IOException {
 	return nil
 }
 
+/**
+ * Reopen log files of all agents
+ */
+
+func ReopenLogsAll() exception.IOException {
+	baylog.Debug("Reopen logs All")
+	for _, mon := range monitors {
+		ioerr := mon.ReopenLogs()
+		if ioerr != nil {
+			return ioerr
+		}
+	}
+	return nil
+}
+
 /**
  * Restart all agents
  */
@@ -196,6 +216,7 @@ func grandAgentMonitorAdd(anchorable bool) exception.IOException {
 	agtId := curId
 	if agtId > 100 {
 		baylog.Error("Too many agents started")
+		baylog.CloseSink()
 		os.Exit(1)
 	}
 
@@ -237,6 +258,11 @@ func agentAborted(agtId int, anchorable bool) {
 	}
 
 	if finale && len(monitors) == 0 {
+		if trc := bayserver.Harbor().Tracer(); trc != nil {
+			// Send spans which are not exported yet
+			trc.Close()
+		}
+		baylog.CloseSink()
 		os.Exit(0)
 	}
 }
@@ -128,9 +128,9 @@ func (mpx *JobMultiplexer) ReqConnect(rd rudder.Rudder, adr net.Addr) exception.
 		var ioerr exception.IOException = nil
 		for { // try catch
 			var tcpRd = rd.(*impl.TcpConnRudder)
-			var tcpAddr = adr.(*net.TCPAddr)
 
-			con, err := net.DialTCP("tcp", nil, tcpAddr)
+			// Destination may be either TCP or unix domain socket
+			con, err := net.Dial(adr.Network(), adr.String())
 			if err != nil {
 				baylog.Debug("Connect error: rd=%s err=%s", tcpRd, err.Error())
 				ioerr = exception.NewIOExceptionFromError(err)
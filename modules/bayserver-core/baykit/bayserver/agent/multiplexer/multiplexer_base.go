@@ -53,22 +53,21 @@ func (h *MultiplexerBase) GetTransporter(rd rudder.Rudder) common.Transporter {
 }
 
 func (h *MultiplexerBase) ConsumeOldestUnit(st *common.RudderState) bool {
-	var consumed bool
+	var u *common.WriteUnit = nil
 	st.WriteLock.Lock()
-	for { // try-catch
-		if len(st.WriteQueue) == 0 {
-			consumed = false
-			break
-		}
-		u := st.WriteQueue[0]
+	if len(st.WriteQueue) > 0 {
+		u = st.WriteQueue[0]
 		st.WriteQueue = arrayutil.RemoveAt(st.WriteQueue, 0)
-		u.Done()
-		consumed = true
-		break
 	}
 	st.WriteLock.Unlock()
 
-	return consumed
+	if u == nil {
+		return false
+	}
+
+	// Call listener outside of lock so that it can request next write
+	u.Done()
+	return true
 }
 
 func (h *MultiplexerBase) CloseRudder(st *common.RudderState) {
@@ -8,6 +8,7 @@ import (
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"net"
+	"sync/atomic"
 )
 
 type PlainTransporter struct {
@@ -20,6 +21,8 @@ type PlainTransporter struct {
 	ship           ship.Ship
 	closed         bool
 	tmpAddress     []net.Addr
+	bytesRead      int64
+	bytesWritten   int64
 }
 
 func NewPlainTransporter(mpx common.Multiplexer, sip ship.Ship, serverMode bool, bufsize int, traceSSL bool) *PlainTransporter {
@@ -51,6 +54,8 @@ func (tp *PlainTransporter) String() string {
 
 func (tp *PlainTransporter) Reset() {
 	tp.closed = false
+	atomic.StoreInt64(&tp.bytesRead, 0)
+	atomic.StoreInt64(&tp.bytesWritten, 0)
 }
 
 /****************************************/
@@ -67,6 +72,7 @@ func (tp *PlainTransporter) OnRead(rd rudder.Rudder, buf []byte, addr net.Addr)
 		return tp.ship.NotifyEof(), nil
 
 	} else {
+		atomic.AddInt64(&tp.bytesRead, int64(len(buf)))
 		nextAct, err := tp.ship.NotifyRead(buf)
 
 		if err != nil {
@@ -118,7 +124,11 @@ func (tp *PlainTransporter) ReqRead(rd rudder.Rudder) {
 }
 
 func (tp *PlainTransporter) ReqWrite(rd rudder.Rudder, buf []byte, addr net.Addr, tag interface{}, listener common.DataConsumeListener) exception.IOException {
-	return tp.multiplexer.ReqWrite(rd, buf, addr, tag, listener)
+	ioerr := tp.multiplexer.ReqWrite(rd, buf, addr, tag, listener)
+	if ioerr == nil {
+		atomic.AddInt64(&tp.bytesWritten, int64(len(buf)))
+	}
+	return ioerr
 }
 
 func (tp *PlainTransporter) ReqClose(rd rudder.Rudder) {
@@ -137,6 +147,14 @@ func (tp *PlainTransporter) GetReadBufferSize() int {
 func (tp *PlainTransporter) PrintUsage(indent int) {
 }
 
+func (tp *PlainTransporter) BytesRead() int64 {
+	return atomic.LoadInt64(&tp.bytesRead)
+}
+
+func (tp *PlainTransporter) BytesWritten() int64 {
+	return atomic.LoadInt64(&tp.bytesWritten)
+}
+
 /****************************************/
 /* Public methods                       */
 /****************************************/
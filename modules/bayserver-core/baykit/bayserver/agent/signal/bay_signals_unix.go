@@ -13,6 +13,8 @@ import (
 var signalMap = map[unix.Signal]string{
 	unix.SIGALRM: SIGN_AGENT_COMMAND_RELOAD_CERT,
 	unix.SIGTRAP: SIGN_AGENT_COMMAND_MEM_USAGE,
+	unix.SIGUSR1: SIGN_AGENT_COMMAND_REOPEN_LOGS,
+	unix.SIGUSR2: SIGN_AGENT_COMMAND_TOGGLE_DEBUG,
 	unix.SIGHUP:  SIGN_AGENT_COMMAND_RESTART_AGENTS,
 	unix.SIGTERM: SIGN_AGENT_COMMAND_SHUTDOWN,
 	unix.SIGABRT: SIGN_AGENT_COMMAND_ABORT,
@@ -29,6 +29,14 @@ func SignalAgentInit(port int) {
 func handleCommand(cmd string) {
 	baylog.Debug("handle command: %s", cmd)
 	var ioerr exception.IOException = nil
+
+	// Command may have argument (e.g. "loglevel info h2=debug")
+	arg := ""
+	if pos := strings.IndexAny(cmd, " \t"); pos > 0 {
+		arg = strings.TrimSpace(cmd[pos+1:])
+		cmd = cmd[:pos]
+	}
+
 	for { // try-catch
 		switch strings.ToLower(cmd) {
 		case SIGN_AGENT_COMMAND_RELOAD_CERT:
@@ -37,6 +45,21 @@ func handleCommand(cmd string) {
 		case SIGN_AGENT_COMMAND_MEM_USAGE:
 			agent.PrintUsageAll()
 
+		case SIGN_AGENT_COMMAND_REOPEN_LOGS:
+			ioerr = agent.ReopenLogsAll()
+
+		case SIGN_AGENT_COMMAND_LOG_LEVEL:
+			err := baylog.SetLogLevels(arg)
+			if err != nil {
+				baylog.ErrorE(err, "")
+			} else {
+				baylog.Info("Log level changed: %s", baylog.LogLevels())
+			}
+
+		case SIGN_AGENT_COMMAND_TOGGLE_DEBUG:
+			baylog.ToggleDebug()
+			baylog.Info("Log level changed: %s", baylog.LogLevels())
+
 		case SIGN_AGENT_COMMAND_RESTART_AGENTS:
 			ioerr = agent.RestartAll()
 			if ioerr != nil {
@@ -47,6 +70,7 @@ func handleCommand(cmd string) {
 			ioerr = agent.ShutdownAll()
 
 		case SIGN_AGENT_COMMAND_ABORT:
+			baylog.CloseSink()
 			os.Exit(1)
 
 		default:
@@ -8,6 +8,9 @@ import (
 
 const SIGN_AGENT_COMMAND_RELOAD_CERT = "reloadcert"
 const SIGN_AGENT_COMMAND_MEM_USAGE = "memusage"
+const SIGN_AGENT_COMMAND_REOPEN_LOGS = "reopenlogs"
+const SIGN_AGENT_COMMAND_LOG_LEVEL = "loglevel"
+const SIGN_AGENT_COMMAND_TOGGLE_DEBUG = "toggledebug"
 const SIGN_AGENT_COMMAND_RESTART_AGENTS = "restartagents"
 const SIGN_AGENT_COMMAND_SHUTDOWN = "shutdown"
 const SIGN_AGENT_COMMAND_ABORT = "abort"
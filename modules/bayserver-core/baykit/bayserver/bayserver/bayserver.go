@@ -8,6 +8,7 @@ import (
 	"bayserver-core/baykit/bayserver/rudder"
 	"bayserver-core/baykit/bayserver/util"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"time"
 )
 
 const ENV_BAYSERVER_HOME = "BSERV_HOME"
@@ -55,6 +56,9 @@ var ParsePath func(location string) (string, exception.BayException)
 
 var SoftwareName func() string
 
+// Time when the server started
+var StartTime func() time.Time
+
 var FatalError func(err exception2.Exception)
 
 var BDefer func()
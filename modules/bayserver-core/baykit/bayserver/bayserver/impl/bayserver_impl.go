@@ -29,6 +29,7 @@ import (
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"bayserver-core/baykit/bayserver/util/logsink"
 	"bayserver-core/baykit/bayserver/util/mimes"
 	"bayserver-core/baykit/bayserver/util/strutil"
 	"bayserver-core/baykit/bayserver/util/sysutil"
@@ -39,6 +40,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var bservHome string /** BayServer home directory */
@@ -55,6 +57,7 @@ var anchorablePortMap map[rudder.Rudder]docker.Port
 var unanchorablePortMap map[rudder.Rudder]docker.Port
 var cities common3.Cities
 var logFile *os.File
+var startTime time.Time /** Time when the server started */
 
 var resource embed.FS
 
@@ -72,6 +75,7 @@ func Init(res embed.FS) {
 	anchorablePortMap = map[rudder.Rudder]docker.Port{}
 	unanchorablePortMap = map[rudder.Rudder]docker.Port{}
 	cities = common3.NewCities()
+	startTime = time.Now()
 
 	bayserver.BservHome = func() string { return bservHome }
 	bayserver.BservPlan = func() string { return bservPlan }
@@ -91,6 +95,7 @@ func Init(res embed.FS) {
 	bayserver.SoftwareName = SoftwareName
 	bayserver.FatalError = FatalError
 	bayserver.BDefer = BDefer
+	bayserver.StartTime = func() time.Time { return startTime }
 }
 
 func Main(args []string) {
@@ -118,6 +123,15 @@ func Main(args []string) {
 		} else if strings.EqualFold(arg, "-memUsage") {
 			cmd = signal.SIGN_AGENT_COMMAND_MEM_USAGE
 
+		} else if strings.EqualFold(arg, "-reopenLogs") {
+			cmd = signal.SIGN_AGENT_COMMAND_REOPEN_LOGS
+
+		} else if strings.EqualFold(arg, "-toggleDebug") {
+			cmd = signal.SIGN_AGENT_COMMAND_TOGGLE_DEBUG
+
+		} else if strutil.StartsWith(strings.ToLower(arg), "-setloglevel=") {
+			cmd = signal.SIGN_AGENT_COMMAND_LOG_LEVEL + " " + arg[13:]
+
 		} else if strings.EqualFold(arg, "-abort") {
 			cmd = signal.SIGN_AGENT_COMMAND_ABORT
 
@@ -259,6 +273,7 @@ func FatalError(err exception.Exception) {
 func BDefer() {
 	if r := recover(); r != nil {
 		baylog.FatalE(exception.NewSink("Panic: %v\n", r), "")
+		baylog.FlushSink()
 		if logFile != nil {
 			logFile.Sync()
 			logFile.Close()
@@ -383,6 +398,15 @@ func start() exception2.BayException {
 			os.Stdout = logFile
 			os.Stderr = logFile
 		}
+
+		if bayserver.Harbor().LogSink() != "" {
+			sink, err := logsink.NewLogSink(bayserver.Harbor().LogSink(), "bayserver")
+			if err != nil {
+				ex = err
+				break
+			}
+			baylog.SetSink(sink, logsink.DEFAULT_QUEUE_SIZE)
+		}
 		printVersion()
 
 		if len(ports) == 0 {
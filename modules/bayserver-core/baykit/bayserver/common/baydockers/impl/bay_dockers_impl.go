@@ -8,12 +8,15 @@ import (
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/docker/builtin"
 	"bayserver-core/baykit/bayserver/docker/file"
+	"bayserver-core/baykit/bayserver/docker/metrics"
 	"bayserver-core/baykit/bayserver/symbol"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	ajpimpl "bayserver-docker-ajp/baykit/bayserver/docker/ajp/impl"
 	"bayserver-docker-cgi/bayserver/docker/cgi"
 	fcgiimpl "bayserver-docker-fcgi/baykit/bayserver/docker/fcgi/impl"
 	httpimpl "bayserver-docker-http/baykit/bayserver/docker/http/impl"
+	scgiimpl "bayserver-docker-scgi/baykit/bayserver/docker/scgi/impl"
+	uwsgiimpl "bayserver-docker-uwsgi/baykit/bayserver/docker/uwsgi/impl"
 	wpimpl "bayserver-docker-wordpress/baykit/bayserver/docker/wordpress/impl"
 )
 
@@ -51,6 +54,9 @@ func Init(m map[string]string) exception.ConfigException {
 		case "baykit.bayserver.docker.file.FileDocker":
 			f = func() docker.Docker { return file.NewFileDocker() }
 
+		case "baykit.bayserver.docker.metrics.MetricsDocker":
+			f = func() docker.Docker { return metrics.NewMetricsDocker() }
+
 		case "baykit.bayserver.docker.cgi.CgiDocker":
 			f = func() docker.Docker { return cgi.NewCgiDocker() }
 
@@ -63,6 +69,12 @@ func Init(m map[string]string) exception.ConfigException {
 		case "baykit.bayserver.docker.fcgi.FcgWarpDocker":
 			f = func() docker.Docker { return fcgiimpl.NewFcgWarpDocker() }
 
+		case "baykit.bayserver.docker.scgi.ScgWarpDocker":
+			f = func() docker.Docker { return scgiimpl.NewScgWarpDocker() }
+
+		case "baykit.bayserver.docker.uwsgi.UwsWarpDocker":
+			f = func() docker.Docker { return uwsgiimpl.NewUwsWarpDocker() }
+
 		case "baykit.bayserver.docker.http.HtpWarpDocker":
 			f = func() docker.Docker { return httpimpl.NewHtpWarpDocker() }
 
@@ -72,12 +84,21 @@ func Init(m map[string]string) exception.ConfigException {
 		case "baykit.bayserver.docker.builtin.BuiltInPermissionDocker":
 			f = func() docker.Docker { return builtin.NewPermissionDocker() }
 
+		case "baykit.bayserver.docker.fcgi.FcgAuthorizerDocker":
+			f = func() docker.Docker { return fcgiimpl.NewFcgAuthorizerDocker() }
+
 		case "baykit.bayserver.docker.builtin.BuiltInSecureDocker":
 			f = func() docker.Docker { return builtin.NewBuiltInSecureDocker() }
 
+		case "baykit.bayserver.docker.builtin.BuiltInStatusDocker":
+			f = func() docker.Docker { return builtin.NewBuiltInStatusDocker() }
+
 		case "baykit.bayserver.docker.builtin.BuiltInTroubleDocker":
 			f = func() docker.Docker { return builtin.NewBuiltInTroubleDocker() }
 
+		case "baykit.bayserver.docker.builtin.BuiltInTracerDocker":
+			f = func() docker.Docker { return builtin.NewBuiltInTracerDocker() }
+
 		case "baykit.bayserver.docker.wordpress.WordPressDocker":
 			f = func() docker.Docker { return wpimpl.NewWordPressDocker() }
 
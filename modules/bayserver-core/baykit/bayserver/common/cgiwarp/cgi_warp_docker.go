@@ -0,0 +1,23 @@
+package cgiwarp
+
+import (
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/protocol"
+)
+
+const DEFAULT_MAX_REQUEST_BUFFER = 1024 * 1024
+
+// CgiWarpDocker is implemented by warp dockers which send requests as CGI environment (e.g. SCGI, uWSGI)
+type CgiWarpDocker interface {
+	ScriptBase() string
+	DocRoot() string
+
+	// MaxRequestBuffer returns the limit of request content buffered when its length is unknown
+	MaxRequestBuffer() int
+}
+
+// HeaderEncoder creates the command which sends CGI environment and content length to the server
+type HeaderEncoder func(dkr docker.Warp, env map[string]string, contLen int) protocol.Command
+
+// ContentEncoder creates the command which sends request content to the server
+type ContentEncoder func(buf []byte, start int, length int) protocol.Command
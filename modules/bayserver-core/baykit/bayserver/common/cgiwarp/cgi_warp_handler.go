@@ -0,0 +1,440 @@
+package cgiwarp
+
+import (
+	"bayserver-core/baykit/bayserver/bayserver"
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/warpship"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/ship"
+	"bayserver-core/baykit/bayserver/tour"
+	tourimpl "bayserver-core/baykit/bayserver/tour/impl"
+	"bayserver-core/baykit/bayserver/util"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	"bayserver-core/baykit/bayserver/util/cgiutil"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/headers"
+	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const STATE_READ_HEADER = 1
+const STATE_READ_CONTENT = 2
+
+/**
+ * Warp handler for protocols which send requests as CGI environment and receive CGI responses (e.g. SCGI, uWSGI)
+ *   The commands of each protocol are created by HeaderEncoder and ContentEncoder
+ */
+type CgiWarpHandler struct {
+	protocolHandler protocol.ProtocolHandler
+	name            string
+	newHeader       HeaderEncoder
+	newContent      ContentEncoder
+	state           int
+	lineBuf         []byte
+	resContLen      int
+	resReadLen      int
+
+	// Response may be completed before request content is written out
+	reqEnded bool
+	resEnded bool
+
+	// Request content is buffered (up to maxRequestBuffer of docker) when the length is unknown
+	//  because the server reads content by CONTENT_LENGTH in the header
+	pendingHeader bool
+	pendingEnv    map[string]string
+	reqBuf        bytes.Buffer
+
+	// for read header/contents
+	pos  int
+	last int
+	data []byte
+}
+
+func NewCgiWarpHandler(name string, newHeader HeaderEncoder, newContent ContentEncoder) *CgiWarpHandler {
+	h := &CgiWarpHandler{
+		name:       name,
+		newHeader:  newHeader,
+		newContent: newContent,
+	}
+	h.Reset()
+
+	var _ tour.TourHandler = h     // cast check
+	var _ warpship.WarpHandler = h // cast check
+	var _ util.Reusable = h        // cast check
+	return h
+}
+
+func (h *CgiWarpHandler) Init(handler protocol.ProtocolHandler) {
+	h.protocolHandler = handler
+}
+
+func (h *CgiWarpHandler) String() string {
+	return "CgiWarpHandler(" + h.name + ")"
+}
+
+/****************************************/
+/* Implements Reusable                  */
+/****************************************/
+
+func (h *CgiWarpHandler) Reset() {
+	h.resetState()
+	h.pos = 0
+	h.last = 0
+	h.data = nil
+}
+
+/****************************************/
+/* Implements TourHandler               */
+/****************************************/
+
+func (h *CgiWarpHandler) SendHeaders(tur tour.Tour) exception2.IOException {
+	h.resetState()
+
+	env, ioerr := h.getEnv(tur)
+	if ioerr != nil {
+		return ioerr
+	}
+
+	contLen := tur.Req().Headers().ContentLength()
+	if contLen < 0 {
+		// Send header when request content ends
+		h.pendingHeader = true
+		h.pendingEnv = env
+		return nil
+	}
+
+	return h.sendHeader(env, contLen)
+}
+
+func (h *CgiWarpHandler) SendInformational(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	return nil
+}
+
+func (h *CgiWarpHandler) SendContent(tur tour.Tour, buf []byte, start int, length int, lis common.DataConsumeListener) exception2.IOException {
+	if h.pendingHeader {
+		maxLen := h.Ship().Docker().(CgiWarpDocker).MaxRequestBuffer()
+		if h.reqBuf.Len()+length > maxLen {
+			// Give up the warp tour. The error is sent to the client when the request content ends
+			baylog.Debug("%s %s: request content exceeds buffer limit: %d", h.Ship(), h.name, maxLen)
+			h.pendingHeader = false
+			h.pendingEnv = nil
+			h.reqBuf.Reset()
+			h.Ship().EndWarpTour(tur, false)
+			h.Ship().Abort(ship.SHIP_ID_NOCHECK)
+			tur.SetHttpError(exception.NewHttpException(httpstatus.REQUEST_ENTITY_TOO_LARGE, "Request content is too large to buffer (limit=%d)", maxLen))
+			lis()
+			return nil
+		}
+		h.reqBuf.Write(buf[start : start+length])
+		return h.Ship().Post(nil, lis)
+	}
+
+	return h.Ship().Post(h.newContent(buf, start, length), lis)
+}
+
+func (h *CgiWarpHandler) SendEnd(tur tour.Tour, keepAlive bool, lis common.DataConsumeListener) exception2.IOException {
+	if h.pendingHeader {
+		h.pendingHeader = false
+		ioerr := h.sendHeader(h.pendingEnv, h.reqBuf.Len())
+		if ioerr != nil {
+			return ioerr
+		}
+
+		if h.reqBuf.Len() > 0 {
+			data := append([]byte(nil), h.reqBuf.Bytes()...)
+			h.reqBuf.Reset()
+			maxLen := h.protocolHandler.MaxReqPacketDataSize()
+			for pos := 0; pos < len(data); pos += maxLen {
+				length := len(data) - pos
+				if length > maxLen {
+					length = maxLen
+				}
+				ioerr = h.Ship().Post(h.newContent(data, pos, length), nil)
+				if ioerr != nil {
+					return ioerr
+				}
+			}
+		}
+	}
+
+	return h.Ship().Post(nil, func() {
+		if lis != nil {
+			lis()
+		}
+		h.reqEnded = true
+		if h.resEnded {
+			ioerr := h.endResContent(tur)
+			if ioerr != nil {
+				baylog.DebugE(ioerr, "")
+			}
+		}
+	})
+}
+
+func (h *CgiWarpHandler) OnProtocolError(e exception.ProtocolException) (bool, exception2.IOException) {
+	bayserver.FatalError(exception2.NewSink(""))
+	return false, nil
+}
+
+/****************************************/
+/* Implements WarpHandler               */
+/****************************************/
+
+func (h *CgiWarpHandler) NextWarpId() int {
+	return 1
+}
+
+func (h *CgiWarpHandler) NewWarpData(warpId int) *warpship.WarpData {
+	return warpship.NewWarpData(h.Ship(), warpId)
+}
+
+func (h *CgiWarpHandler) VerifyProtocol(protocol string) exception2.IOException {
+	return nil
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+// HandleResData handles response data (CGI headers and content) received from the server
+func (h *CgiWarpHandler) HandleResData(data []byte, start int, length int) (common.NextSocketAction, exception2.IOException) {
+	tur, perr := h.Ship().GetTour(h.NextWarpId(), false)
+	if perr != nil {
+		return -1, perr
+	}
+	if tur == nil || h.resEnded {
+		// Response data after the content ended
+		baylog.Debug("%s %s: ignore data (tour not found)", h.Ship(), h.name)
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+
+	h.data = data
+	h.pos = start
+	h.last = start + length
+
+	var ioerr exception2.IOException = nil
+	for { // try catch
+		if h.state == STATE_READ_HEADER {
+			ioerr = h.readHeader(tur)
+			if ioerr != nil {
+				break
+			}
+		}
+
+		if h.state == STATE_READ_CONTENT {
+			if h.pos < h.last {
+				length := h.last - h.pos
+				if h.resContLen >= 0 && h.resReadLen+length > h.resContLen {
+					length = h.resContLen - h.resReadLen
+				}
+				h.resReadLen += length
+
+				var available bool
+				available, ioerr = tur.Res().SendResContent(tourimpl.TOUR_ID_NOCHECK, h.data, h.pos, length)
+				if ioerr != nil {
+					break
+				}
+				h.pos = h.last
+
+				if h.resContLen >= 0 && h.resReadLen >= h.resContLen {
+					ioerr = h.resContentEnded(tur)
+					if ioerr != nil {
+						break
+					}
+					return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+				}
+
+				if !available {
+					return common.NEXT_SOCKET_ACTION_SUSPEND, nil
+				}
+
+			} else if h.resContLen == 0 {
+				ioerr = h.resContentEnded(tur)
+				if ioerr != nil {
+					break
+				}
+			}
+		}
+
+		// Otherwise response ends when server closes the connection
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+
+	return -1, ioerr
+}
+
+func (h *CgiWarpHandler) Ship() warpship.WarpShip {
+	return h.protocolHandler.Ship().(warpship.WarpShip)
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (h *CgiWarpHandler) resetState() {
+	h.state = STATE_READ_HEADER
+	h.lineBuf = h.lineBuf[:0]
+	h.resContLen = -1
+	h.resReadLen = 0
+	h.reqEnded = false
+	h.resEnded = false
+	h.pendingHeader = false
+	h.pendingEnv = nil
+	h.reqBuf.Reset()
+}
+
+func (h *CgiWarpHandler) resContentEnded(tur tour.Tour) exception2.IOException {
+	h.resEnded = true
+	if !h.reqEnded {
+		// End the tour after request content is sent
+		return nil
+	}
+	return h.endResContent(tur)
+}
+
+func (h *CgiWarpHandler) endResContent(tur tour.Tour) exception2.IOException {
+	// Server handles only one request per connection
+	h.Ship().EndWarpTour(tur, false)
+	return tur.Res().EndResContent(tourimpl.TOUR_ID_NOCHECK)
+}
+
+func (h *CgiWarpHandler) readHeader(tur tour.Tour) exception2.IOException {
+	wdat := warpship.WarpDataGet(tur)
+
+	var ioerr exception2.IOException = nil
+	for { // try catch
+
+		var headerFinished bool
+		headerFinished, ioerr = h.parseHeader(wdat.ResHeaders)
+		if ioerr != nil {
+			break
+		}
+		if headerFinished {
+			wdat.ResHeaders.CopyTo(tur.Res().Headers())
+
+			// Check HTTP Status from headers
+			status := wdat.ResHeaders.Get(headers.STATUS)
+			if status != "" {
+				parts := strings.Split(status, " ")
+				stCode, err := strconv.Atoi(parts[0])
+				if err != nil {
+					ioerr = exception.NewProtocolException("warp: Status header of server is invalid: %s", status)
+					break
+				}
+
+				tur.Res().Headers().SetStatus(stCode)
+				tur.Res().Headers().Remove(headers.STATUS)
+			}
+
+			h.resContLen = wdat.ResHeaders.ContentLength()
+			stCode := tur.Res().Headers().Status()
+			if tur.Req().Method() == "HEAD" || stCode == httpstatus.NO_CONTENT || stCode == httpstatus.NOT_MODIFIED {
+				h.resContLen = 0
+			}
+
+			sip := h.Ship()
+			baylog.Debug("%s %s: read header status=%s contlen=%d", sip, h.name, status, h.resContLen)
+			sid := sip.ShipId()
+			tur.Res().SetConsumeListener(func(length int, resume bool) {
+				if resume {
+					sip.ResumeRead(sid)
+				}
+			})
+
+			ioerr = tur.Res().SendHeaders(tourimpl.TOUR_ID_NOCHECK)
+			if ioerr != nil {
+				break
+			}
+
+			h.state = STATE_READ_CONTENT
+		}
+
+		return nil
+	}
+
+	return ioerr
+}
+
+func (h *CgiWarpHandler) parseHeader(hdrs *headers.Headers) (bool, exception2.IOException) {
+
+	for h.pos < h.last {
+		c := h.data[h.pos]
+		h.pos++
+
+		if c == '\r' {
+			continue
+
+		} else if c == '\n' {
+			line := string(h.lineBuf)
+			h.lineBuf = h.lineBuf[:0]
+			if line == "" {
+				return true, nil
+			}
+
+			if strings.HasPrefix(line, "HTTP/") && len(hdrs.HeaderNames()) == 0 {
+				// Some servers send status line instead of Status header
+				spacePos := strings.Index(line, " ")
+				if spacePos < 0 {
+					return false, exception.NewProtocolException("%s: Status line of server is invalid: %s", h.name, line)
+				}
+				hdrs.Set(headers.STATUS, strings.TrimSpace(line[spacePos+1:]))
+				continue
+			}
+
+			colonPos := strings.Index(line, ":")
+			if colonPos < 0 {
+				return false, exception.NewProtocolException("%s: Header line of server is invalid: %s", h.name, line)
+			}
+
+			name := strings.TrimRightFunc(line[:colonPos], unicode.IsSpace)
+			value := strings.TrimLeftFunc(line[colonPos+1:], unicode.IsSpace)
+			if name == "" {
+				return false, exception.NewProtocolException("%s: Header line of server is invalid: %s", h.name, line)
+			}
+			hdrs.Add(name, value)
+			if bayserver.Harbor().TraceHeader() {
+				baylog.Info("%s %s_warp: resHeader: %s=%s", h.Ship(), h.name, name, value)
+			}
+
+		} else {
+			h.lineBuf = append(h.lineBuf, c)
+		}
+	}
+
+	// Need more data
+	return false, nil
+}
+
+func (h *CgiWarpHandler) getEnv(tur tour.Tour) (map[string]string, exception2.IOException) {
+	scriptBase := h.Ship().Docker().(CgiWarpDocker).ScriptBase()
+	if scriptBase == "" {
+		scriptBase = tur.Town().(docker.Town).Location()
+	}
+
+	docRoot := h.Ship().Docker().(CgiWarpDocker).DocRoot()
+	if docRoot == "" {
+		docRoot = tur.Town().(docker.Town).Location()
+	}
+
+	if docRoot == "" {
+		return nil, exception2.NewIOException("%s docRoot of %s docker or location of town is not specified.", tur.Town(), h.name)
+	}
+
+	env := cgiutil.GetEnv(tur.Town().(docker.Town).Name(), docRoot, scriptBase, tur)
+
+	if bayserver.Harbor().TraceHeader() {
+		for name, value := range env {
+			baylog.Info("%s %s_warp: env: %s=%s", h.Ship(), h.name, name, value)
+		}
+	}
+	return env, nil
+}
+
+func (h *CgiWarpHandler) sendHeader(env map[string]string, contLen int) exception2.IOException {
+	return h.Ship().Post(h.newHeader(h.Ship().Docker(), env, contLen), nil)
+}
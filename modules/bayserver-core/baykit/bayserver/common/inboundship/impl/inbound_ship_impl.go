@@ -1,6 +1,7 @@
 package common
 
 import (
+	"bayserver-core/baykit/bayserver/agent"
 	"bayserver-core/baykit/bayserver/bayserver"
 	"bayserver-core/baykit/bayserver/common"
 	"bayserver-core/baykit/bayserver/common/exception"
@@ -21,6 +22,7 @@ import (
 	"net"
 	"strconv"
 	"sync"
+	"time"
 )
 
 type InboundShipImpl struct {
@@ -33,6 +35,9 @@ type InboundShipImpl struct {
 	SocketTimeoutSec int
 	tourStore        *tourstore.TourStore
 	activeTours      []tour.Tour
+	tourCount        int
+	bytesReadMark    int64
+	bytesWrittenMark int64
 	lock             sync.Mutex
 }
 
@@ -88,6 +93,9 @@ func (sip *InboundShipImpl) Reset() {
 	sip.NeedEnd = false
 	sip.Conn = nil
 	sip.protocolHandler = nil
+	sip.tourCount = 0
+	sip.bytesReadMark = 0
+	sip.bytesWrittenMark = 0
 }
 
 /****************************************/
@@ -187,8 +195,11 @@ func (sip *InboundShipImpl) GetTour(turKey int, force bool, rent bool) tour.Tour
 			}
 			tur.Init(turKey, sip)
 			sip.activeTours = append(sip.activeTours, tur)
+			sip.tourCount++
+		}
+		if tur != nil {
+			tur.CheckTourId(tur.TourId())
 		}
-		tur.CheckTourId(tur.TourId())
 		break
 	}
 	return tur
@@ -210,6 +221,9 @@ func (sip *InboundShipImpl) SendHeaders(checkId int, tour tour.Tour) exception2.
 	for _, nv := range sip.portDocker.AdditionalHeaders() {
 		tour.Res().Headers().Add(nv[0], nv[1])
 	}
+	if tour.Req().RequestId() != "" {
+		tour.Res().Headers().Set(bayserver.Harbor().RequestIdHeader(), tour.Req().RequestId())
+	}
 
 	ioerr := sip.tourHandler().SendHeaders(tour)
 	if ioerr != nil {
@@ -218,6 +232,12 @@ func (sip *InboundShipImpl) SendHeaders(checkId int, tour tour.Tour) exception2.
 	return nil
 }
 
+func (sip *InboundShipImpl) SendInformational(checkId int, tour tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	sip.CheckShipId(checkId)
+
+	return sip.tourHandler().SendInformational(tour, status, hdrs)
+}
+
 func (sip *InboundShipImpl) SendResContent(checkId int, tour tour.Tour, bytes []byte, ofs int, length int, lis common.DataConsumeListener) exception2.IOException {
 	sip.CheckShipId(checkId)
 
@@ -293,6 +313,53 @@ func (sip *InboundShipImpl) ReturnTour(tur tour.Tour) {
 	}
 }
 
+func (sip *InboundShipImpl) TakeBytesCount() (int, int) {
+	tp := sip.Transporter()
+	if tp == nil {
+		return 0, 0
+	}
+
+	read := tp.BytesRead()
+	written := tp.BytesWritten()
+	nread := read - sip.bytesReadMark
+	nwritten := written - sip.bytesWrittenMark
+	sip.bytesReadMark = read
+	sip.bytesWrittenMark = written
+	return int(nread), int(nwritten)
+}
+
+func (sip *InboundShipImpl) Snapshot() *agent.ShipSnapshot {
+	sip.lock.Lock()
+	defer sip.lock.Unlock()
+
+	snap := &agent.ShipSnapshot{
+		ShipId:    sip.ShipId(),
+		Keeping:   sip.Keeping,
+		TourCount: sip.tourCount,
+		Tours:     []*agent.TourSnapshot{},
+	}
+	if sip.Conn != nil {
+		snap.RemoteAddress = sip.Conn.RemoteAddr().String()
+	}
+	if sip.protocolHandler != nil {
+		snap.Protocol = sip.protocolHandler.Protocol()
+	}
+
+	now := time.Now()
+	for _, tur := range sip.activeTours {
+		if !tur.IsValid() {
+			continue
+		}
+		snap.Tours = append(snap.Tours, &agent.TourSnapshot{
+			TourId:  tur.TourId(),
+			Method:  tur.Req().Method(),
+			Uri:     tur.Req().Uri(),
+			Elapsed: now.Sub(tur.StartTime()),
+		})
+	}
+	return snap
+}
+
 /****************************************/
 /* Private functions                    */
 /****************************************/
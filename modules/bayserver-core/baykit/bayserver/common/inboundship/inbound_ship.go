@@ -1,12 +1,14 @@
 package inboundship
 
 import (
+	"bayserver-core/baykit/bayserver/agent"
 	"bayserver-core/baykit/bayserver/common"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/protocol"
 	"bayserver-core/baykit/bayserver/ship"
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/headers"
 )
 
 type InboundShip interface {
@@ -20,7 +22,14 @@ type InboundShip interface {
 	GetErrorTour() tour.Tour
 
 	SendHeaders(checkId int, tour tour.Tour) exception.IOException
+	SendInformational(checkId int, tour tour.Tour, status int, hdrs *headers.Headers) exception.IOException
 	SendResContent(checkId int, tour tour.Tour, bytes []byte, ofs int, length int, lis common.DataConsumeListener) exception.IOException
 	SendEndTour(checkId int, tour tour.Tour, lis func()) exception.IOException
 	ReturnTour(tour tour.Tour)
+
+	// TakeBytesCount returns the number of bytes read from and written to the client since the last call
+	TakeBytesCount() (int, int)
+
+	// Snapshot returns current state of the ship
+	Snapshot() *agent.ShipSnapshot
 }
@@ -4,10 +4,12 @@ import (
 	"bayserver-core/baykit/bayserver/agent"
 	"bayserver-core/baykit/bayserver/common"
 	inboundship "bayserver-core/baykit/bayserver/common/inboundship/impl"
+	"bayserver-core/baykit/bayserver/common/metrics"
 	"bayserver-core/baykit/bayserver/common/objectstore"
 	"bayserver-core/baykit/bayserver/util"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/strutil"
+	"strconv"
 )
 
 var inboundShipStores = map[int]*InboundShipStore{}
@@ -21,10 +23,17 @@ type InboundShipStore struct {
 }
 
 func (l *InboundShipStore_LifeCycleListener) Add(agentId int) {
-	inboundShipStores[agentId] = NewInboundShipStore()
+	st := NewInboundShipStore()
+	inboundShipStores[agentId] = st
+
+	agtId := strconv.Itoa(agentId)
+	metrics.InboundShips.SetCollector(agtId, func(emit metrics.Emitter) {
+		emit(float64(st.ActiveCount()), agtId)
+	})
 }
 
 func (l *InboundShipStore_LifeCycleListener) Remove(agentId int) {
+	metrics.InboundShips.RemoveCollector(strconv.Itoa(agentId))
 	delete(inboundShipStores, agentId)
 }
 
@@ -0,0 +1,5 @@
+package common
+
+type LogReopenListener interface {
+	ReopenLogs(agentId int)
+}
@@ -0,0 +1,515 @@
+package logfile
+
+import (
+	"bayserver-core/baykit/bayserver/agent"
+	"bayserver-core/baykit/bayserver/bayserver"
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/rudder"
+	"bayserver-core/baykit/bayserver/rudder/impl"
+	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	"bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/logsink"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"bayserver-core/baykit/bayserver/util/sysutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DEFAULT_BUFFER_SIZE = 1024 * 1024
+const RECONNECT_INTERVAL_SEC = 5
+
+/****************************************/
+/* type agentFile                       */
+/****************************************/
+
+/**
+ * Log file opened by an agent
+ */
+type agentFile struct {
+	fileName    string
+	rudder      rudder.Rudder
+	multiplexer common.Multiplexer
+	size        int
+	period      string
+	openedTime  time.Time
+
+	/** Bytes queued to the multiplexer but not yet written */
+	pending int64
+	/** Records dropped because the buffer was full */
+	dropped int
+}
+
+/****************************************/
+/* type LogFile                         */
+/****************************************/
+
+/**
+ * Log file written by each agent through the log multiplexer.
+ * File names are "<prefix>_<agentId>.<ext>".
+ * If the name is a sink target (stdout, stderr, syslog://...), records are sent to the sink instead.
+ */
+type LogFile struct {
+	/** Log file name parts */
+	filePrefix string
+	fileExt    string
+
+	/** Sink settings */
+	sink       logsink.LogSink
+	target     string
+	severity   int
+	bufferSize int
+
+	/** Last time of reconnecting to the sink (unix time) */
+	reconnected int64
+
+	/** Rotation settings */
+	rotateDate     string
+	rotateSize     int
+	rotateCount    int
+	rotateCompress bool
+
+	/** Opened files (index is agentId - 1) */
+	files []*agentFile
+	lock  sync.Mutex
+}
+
+func NewLogFile() *LogFile {
+	f := &LogFile{
+		files:      make([]*agentFile, 0),
+		severity:   logsink.SEVERITY_INFO,
+		bufferSize: DEFAULT_BUFFER_SIZE,
+	}
+
+	var _ common.LifecycleListener = f // implement check
+	var _ common.LogReopenListener = f // implement check
+	return f
+}
+
+func (f *LogFile) String() string {
+	if f.target != "" {
+		return "LogFile(" + f.target + ")"
+	}
+	return "LogFile(" + f.filePrefix + "." + f.fileExt + ")"
+}
+
+// SetFileName sets base name of the log file. (Relative path is resolved from BayServer home)
+// The name can be a sink target such as "stdout" or "syslog://host:port".
+func (f *LogFile) SetFileName(name string) {
+	if logsink.IsSinkTarget(name) {
+		f.target = name
+		return
+	}
+
+	p := strings.Index(name, ".")
+	if p == -1 {
+		f.filePrefix = name
+		f.fileExt = ""
+	} else {
+		f.filePrefix = name[:p]
+		f.fileExt = name[p+1:]
+	}
+}
+
+func (f *LogFile) FilePrefix() string {
+	return f.filePrefix
+}
+
+func (f *LogFile) FileExt() string {
+	return f.fileExt
+}
+
+// SetSeverity sets the severity of records sent to syslog
+func (f *LogFile) SetSeverity(severity int) {
+	f.severity = severity
+}
+
+// InitKeyVal handles rotation parameters. Returns false if the key is not for log file.
+func (f *LogFile) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.ConfigException) {
+	switch strings.ToLower(kv.Key) {
+	default:
+		return false, nil
+
+	case "rotatedate":
+		if strutil.Strftime(kv.Value, time.Now()) == kv.Value {
+			// Pattern contains no date fields
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+		f.rotateDate = kv.Value
+
+	case "rotatesize":
+		var err exception.Exception
+		f.rotateSize, err = strutil.ParseSize(kv.Value)
+		if err != nil || f.rotateSize < 0 {
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "rotatecount":
+		var err exception.Exception
+		f.rotateCount, err = strutil.ParseInt(kv.Value)
+		if err != nil || f.rotateCount < 0 {
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "rotatecompress":
+		var err exception.Exception
+		f.rotateCompress, err = strutil.ParseBool(kv.Value)
+		if err != nil {
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "buffersize":
+		var err exception.Exception
+		f.bufferSize, err = strutil.ParseSize(kv.Value)
+		if err != nil || f.bufferSize <= 0 {
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+	}
+	return true, nil
+}
+
+// Open prepares the log directory and registers the log file to agents.
+// Each agent opens its own file when it starts.
+func (f *LogFile) Open() exception.Exception {
+	if f.target != "" {
+		var err exception.Exception
+		f.sink, err = logsink.NewLogSink(f.target, "bayserver")
+		if err != nil {
+			return err
+		}
+		agent.AddLifeCycleListener(f)
+		return nil
+	}
+
+	if !filepath.IsAbs(f.filePrefix) {
+		f.filePrefix = filepath.Join(bayserver.BservHome(), f.filePrefix)
+	}
+
+	logDir := filepath.Dir(f.filePrefix)
+	if !sysutil.IsDirectory(logDir) {
+		err := os.MkdirAll(logDir, 0755)
+		if err != nil {
+			return exception.NewExceptionFromError(err)
+		}
+	}
+
+	agent.AddLifeCycleListener(f)
+	return nil
+}
+
+// Write writes data to the log file of the agent. The file is rotated before writing if needed.
+func (f *LogFile) Write(agentId int, buf []byte) exception.IOException {
+	af := f.getFile(agentId)
+	if af == nil {
+		return exception.NewIOException("Log file is not opened: %s agt#%d", f, agentId)
+	}
+
+	if f.sink != nil {
+		f.writeToSink(agentId, af, buf)
+		return nil
+	}
+
+	if f.needRotate(af, len(buf), time.Now()) {
+		ioerr := f.rotate(agentId, af)
+		if ioerr != nil {
+			baylog.ErrorE(ioerr, "%s Cannot rotate log file: %s", f, af.fileName)
+		}
+		af = f.getFile(agentId)
+	}
+
+	af.size += len(buf)
+	return af.multiplexer.ReqWrite(af.rudder, buf, nil, "log", nil)
+}
+
+/****************************************/
+/* Implements LifecycleListener         */
+/****************************************/
+
+func (f *LogFile) Add(agentId int) {
+	var fileName string
+	if f.sink != nil {
+		fileName = f.sink.String()
+	} else {
+		fileName = f.filePrefix + "_" + strconv.Itoa(agentId) + "." + f.fileExt
+	}
+
+	af, ioerr := f.openFile(agentId, fileName)
+	if ioerr != nil && f.sink != nil {
+		// Sink may be down at startup. Retry on writing.
+		baylog.ErrorE(ioerr, "%s Cannot connect to log sink", f)
+		af = &agentFile{fileName: fileName}
+		atomic.StoreInt64(&f.reconnected, time.Now().Unix())
+
+	} else if ioerr != nil {
+		baylog.Fatal(baymessage.Get(symbol.INT_CANNOT_OPEN_LOG_FILE, fileName))
+		baylog.FatalE(ioerr, "")
+		return
+	}
+
+	f.lock.Lock()
+	for len(f.files) < agentId {
+		f.files = append(f.files, nil)
+	}
+	f.files[agentId-1] = af
+	f.lock.Unlock()
+}
+
+func (f *LogFile) Remove(agentId int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if agentId > len(f.files) || f.files[agentId-1] == nil {
+		return
+	}
+	af := f.files[agentId-1]
+	if af.rudder != nil {
+		af.multiplexer.ReqClose(af.rudder)
+	}
+	f.files[agentId-1] = nil
+}
+
+/****************************************/
+/* Implements LogReopenListener         */
+/****************************************/
+
+// ReopenLogs reopens the log file of the agent. Use this after moving the file by external tools.
+func (f *LogFile) ReopenLogs(agentId int) {
+	af := f.getFile(agentId)
+	if af == nil || f.sink != nil {
+		return
+	}
+
+	ioerr := f.swap(agentId, af, nil)
+	if ioerr != nil {
+		baylog.ErrorE(ioerr, "%s Cannot reopen log file: %s", f, af.fileName)
+	} else {
+		baylog.Info("%s Reopened log file: %s", f, af.fileName)
+	}
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (f *LogFile) getFile(agentId int) *agentFile {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if agentId < 1 || agentId > len(f.files) {
+		return nil
+	}
+	return f.files[agentId-1]
+}
+
+func (f *LogFile) openFile(agentId int, fileName string) (*agentFile, exception.IOException) {
+	agt := agent.Get(agentId)
+	var rd rudder.Rudder
+	var mpx common.Multiplexer
+	switch bayserver.Harbor().LogMultiplexer() {
+	case docker.MULTI_PLEXER_TYPE_JOB:
+		if f.sink != nil {
+			w, ioerr := f.sink.Open()
+			if ioerr != nil {
+				return nil, ioerr
+			}
+			rd = impl.NewWriteCloserRudder(w, f.sink.String())
+		} else {
+			file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+			if err != nil {
+				return nil, exception.NewIOExceptionFromError(err)
+			}
+			rd = impl.NewFileRudder(file)
+		}
+		mpx = agt.JobMultiplexer()
+
+	default:
+		bayserver.FatalError(exception.NewSink("Multiplexer type not supported: %d", bayserver.Harbor().LogMultiplexer()))
+	}
+
+	st := common.NewRudderState(rd, nil)
+	st.BytesWrote = -1
+	mpx.AddRudderState(rd, st)
+
+	af := &agentFile{
+		fileName:    fileName,
+		rudder:      rd,
+		multiplexer: mpx,
+		openedTime:  time.Now(),
+	}
+	if f.sink != nil {
+		return af, nil
+	}
+
+	info, err := os.Stat(fileName)
+	if err == nil {
+		af.size = int(info.Size())
+		if af.size > 0 {
+			af.openedTime = info.ModTime()
+		}
+	}
+	if f.rotateDate != "" {
+		af.period = strutil.Strftime(f.rotateDate, af.openedTime)
+	}
+	return af, nil
+}
+
+// swap opens the file again and replaces the rudder. Old rudder is closed after all the queued data are written,
+// and then onWritten is called.
+func (f *LogFile) swap(agentId int, old *agentFile, onWritten func()) exception.IOException {
+	af, ioerr := f.openFile(agentId, old.fileName)
+	if ioerr != nil {
+		return ioerr
+	}
+
+	f.lock.Lock()
+	f.files[agentId-1] = af
+	f.lock.Unlock()
+
+	done := func() {
+		old.multiplexer.ReqClose(old.rudder)
+		if onWritten != nil {
+			onWritten()
+		}
+	}
+	ioerr = old.multiplexer.ReqWrite(old.rudder, []byte{}, nil, "log", done)
+	if ioerr != nil {
+		done()
+	}
+	return nil
+}
+
+// writeToSink sends each line as a record. Records are dropped when the sink is too slow to consume them.
+func (f *LogFile) writeToSink(agentId int, af *agentFile, buf []byte) {
+	lines := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	for _, line := range lines {
+		rec := f.sink.Format(f.severity, line)
+
+		if af.rudder == nil || atomic.LoadInt64(&af.pending)+int64(len(rec)) > int64(f.bufferSize) {
+			af.dropped++
+			continue
+		}
+
+		if af.dropped > 0 {
+			baylog.Warn("%s %d log records dropped", f, af.dropped)
+			af.dropped = 0
+		}
+
+		n := int64(len(rec))
+		atomic.AddInt64(&af.pending, n)
+		ioerr := af.multiplexer.ReqWrite(af.rudder, rec, nil, "log", func() {
+			atomic.AddInt64(&af.pending, -n)
+		})
+		if ioerr != nil {
+			// Rudder is closed by write error
+			baylog.DebugE(ioerr, "%s Cannot write to log sink", f)
+			af.rudder = nil
+			af.dropped++
+		}
+	}
+
+	if af.rudder == nil {
+		f.reconnect(agentId, af)
+	}
+}
+
+// reconnect opens the sink again in background (at most once in RECONNECT_INTERVAL_SEC)
+func (f *LogFile) reconnect(agentId int, old *agentFile) {
+	now := time.Now().Unix()
+	last := atomic.LoadInt64(&f.reconnected)
+	if now-last < RECONNECT_INTERVAL_SEC || !atomic.CompareAndSwapInt64(&f.reconnected, last, now) {
+		return
+	}
+
+	go func() {
+		defer func() {
+			bayserver.BDefer()
+		}()
+
+		af, ioerr := f.openFile(agentId, old.fileName)
+		if ioerr != nil {
+			baylog.ErrorE(ioerr, "%s Cannot connect to log sink", f)
+			return
+		}
+		af.dropped = old.dropped
+
+		f.lock.Lock()
+		replaced := agentId <= len(f.files) && f.files[agentId-1] == old
+		if replaced {
+			f.files[agentId-1] = af
+		}
+		f.lock.Unlock()
+
+		if replaced {
+			baylog.Info("%s Reconnected to log sink", f)
+		} else {
+			af.multiplexer.ReqClose(af.rudder)
+		}
+	}()
+}
+
+func (f *LogFile) needRotate(af *agentFile, len int, now time.Time) bool {
+	if f.rotateSize > 0 && af.size > 0 && af.size+len > f.rotateSize {
+		return true
+	}
+
+	if f.rotateDate != "" && strutil.Strftime(f.rotateDate, now) != af.period {
+		return true
+	}
+
+	return false
+}
+
+// rotate renames the current file and opens new one.
+func (f *LogFile) rotate(agentId int, af *agentFile) exception.IOException {
+	var suffix string
+	if f.rotateDate != "" {
+		suffix = af.period
+	} else {
+		suffix = time.Now().Format("20060102-150405")
+	}
+
+	rotatedName := af.fileName + "." + suffix
+	for i := 1; sysutil.IsFile(rotatedName) || sysutil.IsFile(rotatedName+".gz"); i++ {
+		rotatedName = af.fileName + "." + suffix + "." + strconv.Itoa(i)
+	}
+
+	// Written data which is in the write queue goes to the renamed file
+	err := os.Rename(af.fileName, rotatedName)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+	baylog.Info("%s Rotated log file: %s", f, rotatedName)
+
+	// Compress and remove old files in background after queued data are written
+	return f.swap(agentId, af, func() {
+		if !f.rotateCompress && f.rotateCount == 0 {
+			return
+		}
+
+		go func() {
+			defer func() {
+				bayserver.BDefer()
+			}()
+
+			if f.rotateCompress {
+				ioerr := compressFile(rotatedName)
+				if ioerr != nil {
+					baylog.ErrorE(ioerr, "%s Cannot compress log file: %s", f, rotatedName)
+				}
+			}
+
+			if f.rotateCount > 0 {
+				removeOldFiles(af.fileName, f.rotateCount)
+			}
+		}()
+	})
+}
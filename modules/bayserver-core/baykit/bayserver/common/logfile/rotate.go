@@ -0,0 +1,103 @@
+package logfile
+
+import (
+	"bayserver-core/baykit/bayserver/util/baylog"
+	"bayserver-core/baykit/bayserver/util/exception"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/**
+ * Compresses file to "<fileName>.gz" and removes the original
+ */
+func compressFile(fileName string) exception.IOException {
+	in, err := os.Open(fileName)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+	defer in.Close()
+
+	gzName := fileName + ".gz"
+	out, err := os.OpenFile(gzName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+
+	w := gzip.NewWriter(out)
+	_, err = io.Copy(w, in)
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = out.Close()
+	} else {
+		out.Close()
+	}
+	if err != nil {
+		os.Remove(gzName)
+		return exception.NewIOExceptionFromError(err)
+	}
+
+	err = os.Remove(fileName)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+	return nil
+}
+
+/**
+ * Removes rotated files of "<fileName>.*" except the newest count files
+ */
+func removeOldFiles(fileName string, count int) {
+	names, err := filepath.Glob(escapeGlob(fileName) + ".*")
+	if err != nil {
+		baylog.ErrorE(exception.NewExceptionFromError(err), "")
+		return
+	}
+
+	type rotated struct {
+		name    string
+		modTime time.Time
+	}
+	files := make([]rotated, 0, len(names))
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, rotated{name, info.ModTime()})
+	}
+
+	if len(files) <= count {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	for _, f := range files[count:] {
+		baylog.Info("Remove old log file: %s", f.name)
+		err := os.Remove(f.name)
+		if err != nil {
+			baylog.ErrorE(exception.NewExceptionFromError(err), "")
+		}
+	}
+}
+
+func escapeGlob(name string) string {
+	var sb strings.Builder
+	for _, c := range name {
+		switch c {
+		case '*', '?', '[', '\\':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
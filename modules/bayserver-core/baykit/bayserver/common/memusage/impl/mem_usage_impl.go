@@ -6,11 +6,14 @@ import (
 	"bayserver-core/baykit/bayserver/common"
 	"bayserver-core/baykit/bayserver/common/inboundship/inboundshipstore"
 	"bayserver-core/baykit/bayserver/common/memusage"
+	"bayserver-core/baykit/bayserver/common/warpship"
+	"bayserver-core/baykit/bayserver/common/warpship/warpshipstore"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/protocol/packetstore"
 	"bayserver-core/baykit/bayserver/protocol/protocolhandlerstore"
 	"bayserver-core/baykit/bayserver/tour/tourstore"
 	"bayserver-core/baykit/bayserver/util/baylog"
+	"strings"
 )
 
 /** Agent ID => MemUsage */
@@ -68,6 +71,38 @@ func (m *MemUsageImpl) PrintUsage(indent int) {
 	}
 }
 
+func (m *MemUsageImpl) StoreUsages() []memusage.StoreUsage {
+	usages := []memusage.StoreUsage{}
+
+	isStore := inboundshipstore.Get(m.AgentId)
+	usages = append(usages, memusage.StoreUsage{Name: "InboundShip", Active: isStore.ActiveCount(), Free: isStore.FreeCount()})
+
+	turStore := tourstore.GetStore(m.AgentId)
+	usages = append(usages, memusage.StoreUsage{Name: "Tour", Active: turStore.ActiveCount(), Free: turStore.FreeCount()})
+
+	for _, store := range protocolhandlerstore.GetStores(m.AgentId) {
+		usages = append(usages, memusage.StoreUsage{Name: "ProtocolHandler(" + store.Name() + ")", Active: store.ActiveCount(), Free: store.FreeCount()})
+	}
+	for _, store := range packetstore.GetStores(m.AgentId) {
+		active, free := store.Counts()
+		usages = append(usages, memusage.StoreUsage{Name: "Packet(" + store.Protocol() + ")", Active: active, Free: free})
+	}
+	return usages
+}
+
+func (m *MemUsageImpl) WarpUsages() []memusage.WarpUsage {
+	usages := []memusage.WarpUsage{}
+	for _, city := range bayserver.Cities().Cities() {
+		usages = m.appendCityWarpUsages(usages, city)
+	}
+	for _, port := range bayserver.Ports() {
+		for _, city := range port.Cities() {
+			usages = m.appendCityWarpUsages(usages, city)
+		}
+	}
+	return usages
+}
+
 func (m *MemUsageImpl) PrintCityUsage(port docker.Port, city docker.City, indent int) {
 	/*
 	   pname := ""
@@ -91,6 +126,51 @@ func (m *MemUsageImpl) PrintCityUsage(port docker.Port, city docker.City, indent
 	*/
 }
 
+func (m *MemUsageImpl) appendCityWarpUsages(usages []memusage.WarpUsage, city docker.City) []memusage.WarpUsage {
+	if city == nil {
+		// Cities() may contain nil when "*" city is not defined
+		return usages
+	}
+	for _, club := range city.Clubs() {
+		usages = m.appendWarpUsage(usages, "", club)
+	}
+	for _, town := range city.Towns() {
+		for _, club := range town.Clubs() {
+			usages = m.appendWarpUsage(usages, town.Name(), club)
+		}
+	}
+	return usages
+}
+
+func (m *MemUsageImpl) appendWarpUsage(usages []memusage.WarpUsage, townName string, club docker.Club) []memusage.WarpUsage {
+	wdkr, ok := club.(docker.Warp)
+	if !ok {
+		return usages
+	}
+	base, ok := club.(interface {
+		GetShipStore(agtId int) *warpshipstore.WarpShipStore
+	})
+	if !ok {
+		return usages
+	}
+	store := base.GetShipStore(m.AgentId)
+	if store == nil {
+		return usages
+	}
+
+	clubName := club.FileName()
+	if club.Extension() != "" {
+		clubName += "." + club.Extension()
+	}
+	idle, busy := store.Counts()
+	return append(usages, memusage.WarpUsage{
+		Club:        strings.TrimSpace(townName + " " + clubName),
+		Destination: warpship.Destination(wdkr),
+		Idle:        idle,
+		Busy:        busy,
+	})
+}
+
 /****************************************/
 /*  Static methods                      */
 /****************************************/
@@ -1,11 +1,40 @@
 package memusage
 
+/****************************************/
+/*  Type StoreUsage                     */
+/****************************************/
+
+// StoreUsage is the number of objects in an object store
+type StoreUsage struct {
+	Name   string
+	Active int
+	Free   int
+}
+
+/****************************************/
+/*  Type WarpUsage                      */
+/****************************************/
+
+// WarpUsage is the number of warp ships in a pool of warp docker
+type WarpUsage struct {
+	Club        string
+	Destination string
+	Idle        int
+	Busy        int
+}
+
 /****************************************/
 /*  Type MemUsage                       */
 /****************************************/
 
 type MemUsage interface {
 	PrintUsage(indent int)
+
+	// StoreUsages returns sizes of object stores of the agent
+	StoreUsages() []StoreUsage
+
+	// WarpUsages returns sizes of warp ship pools of the agent
+	WarpUsages() []WarpUsage
 }
 
 /****************************************/
@@ -0,0 +1,311 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const TYPE_COUNTER = "counter"
+const TYPE_GAUGE = "gauge"
+const TYPE_HISTOGRAM = "histogram"
+
+/**
+ * Metric which is exposed in Prometheus text format.
+ * Metrics are shared by all grand agents, so that updates are guarded by lock.
+ */
+type Metric interface {
+	Name() string
+	Help() string
+	Type() string
+
+	// WriteTo writes samples of the metric
+	WriteTo(sb *strings.Builder)
+}
+
+/****************************************/
+/* type metricBase                      */
+/****************************************/
+
+type metricBase struct {
+	name       string
+	help       string
+	labelNames []string
+	lock       sync.Mutex
+}
+
+func (m *metricBase) Name() string {
+	return m.name
+}
+
+func (m *metricBase) Help() string {
+	return m.help
+}
+
+func (m *metricBase) checkLabels(labels []string) {
+	if len(labels) != len(m.labelNames) {
+		panic("Label count mismatch: " + m.name)
+	}
+}
+
+/****************************************/
+/* type CounterVec                      */
+/****************************************/
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+type CounterVec struct {
+	metricBase
+	values map[string]*counterValue
+}
+
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{
+		metricBase: metricBase{name: name, help: help, labelNames: labelNames},
+		values:     map[string]*counterValue{},
+	}
+
+	var _ Metric = c // implement check
+	return c
+}
+
+func (c *CounterVec) Type() string {
+	return TYPE_COUNTER
+}
+
+func (c *CounterVec) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *CounterVec) Add(v float64, labels ...string) {
+	c.checkLabels(labels)
+	key := labelKey(labels)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labels: copyLabels(labels)}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+func (c *CounterVec) WriteTo(sb *strings.Builder) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		writeSample(sb, c.name, c.labelNames, cv.labels, "", "", cv.value)
+	}
+}
+
+/****************************************/
+/* type HistogramVec                    */
+/****************************************/
+
+var DEFAULT_BUCKETS = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type HistogramVec struct {
+	metricBase
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+func NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	h := &HistogramVec{
+		metricBase: metricBase{name: name, help: help, labelNames: labelNames},
+		buckets:    buckets,
+		values:     map[string]*histogramValue{},
+	}
+
+	var _ Metric = h // implement check
+	return h
+}
+
+func (h *HistogramVec) Type() string {
+	return TYPE_HISTOGRAM
+}
+
+func (h *HistogramVec) Observe(v float64, labels ...string) {
+	h.checkLabels(labels)
+	key := labelKey(labels)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labels: copyLabels(labels), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+
+	for i, le := range h.buckets {
+		if v <= le {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+func (h *HistogramVec) WriteTo(sb *strings.Builder) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		for i, le := range h.buckets {
+			writeSample(sb, h.name+"_bucket", h.labelNames, hv.labels, "le", formatValue(le), float64(hv.counts[i]))
+		}
+		writeSample(sb, h.name+"_bucket", h.labelNames, hv.labels, "le", "+Inf", float64(hv.count))
+		writeSample(sb, h.name+"_sum", h.labelNames, hv.labels, "", "", hv.sum)
+		writeSample(sb, h.name+"_count", h.labelNames, hv.labels, "", "", float64(hv.count))
+	}
+}
+
+/****************************************/
+/* type GaugeFunc                       */
+/****************************************/
+
+// Emitter receives a sample from collector
+type Emitter func(value float64, labels ...string)
+
+// Collector emits current values when metrics are scraped
+type Collector func(emit Emitter)
+
+/**
+ * Gauge which values are collected at scrape time.
+ * Collectors are registered with a key by each owner (e.g. agent or docker).
+ */
+type GaugeFunc struct {
+	metricBase
+	collectors map[string]Collector
+}
+
+func NewGaugeFunc(name string, help string, labelNames ...string) *GaugeFunc {
+	g := &GaugeFunc{
+		metricBase: metricBase{name: name, help: help, labelNames: labelNames},
+		collectors: map[string]Collector{},
+	}
+
+	var _ Metric = g // implement check
+	return g
+}
+
+func (g *GaugeFunc) Type() string {
+	return TYPE_GAUGE
+}
+
+func (g *GaugeFunc) SetCollector(key string, col Collector) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.collectors[key] = col
+}
+
+func (g *GaugeFunc) RemoveCollector(key string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	delete(g.collectors, key)
+}
+
+func (g *GaugeFunc) WriteTo(sb *strings.Builder) {
+	g.lock.Lock()
+	keys := sortedKeys(g.collectors)
+	cols := make([]Collector, len(keys))
+	for i, key := range keys {
+		cols[i] = g.collectors[key]
+	}
+	g.lock.Unlock()
+
+	// Samples of the same labels from different collectors are summed up
+	values := map[string]*counterValue{}
+	for _, col := range cols {
+		col(func(value float64, labels ...string) {
+			g.checkLabels(labels)
+			key := labelKey(labels)
+			gv, ok := values[key]
+			if !ok {
+				gv = &counterValue{labels: copyLabels(labels)}
+				values[key] = gv
+			}
+			gv.value += value
+		})
+	}
+
+	for _, key := range sortedKeys(values) {
+		gv := values[key]
+		writeSample(sb, g.name, g.labelNames, gv.labels, "", "", gv.value)
+	}
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func labelKey(labels []string) string {
+	return strings.Join(labels, "\x00")
+}
+
+func copyLabels(labels []string) []string {
+	return append([]string{}, labels...)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeSample(sb *strings.Builder, name string, labelNames []string, labels []string, extraName string, extraValue string, value float64) {
+	sb.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		sb.WriteString("{")
+		for i, ln := range labelNames {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(ln + "=\"" + escapeLabel(labels[i]) + "\"")
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(extraName + "=\"" + extraValue + "\"")
+		}
+		sb.WriteString("}")
+	}
+	sb.WriteString(" ")
+	sb.WriteString(formatValue(value))
+	sb.WriteString("\n")
+}
+
+func escapeLabel(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return strings.ReplaceAll(s, "\n", "\\n")
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"strings"
+)
+
+/****************************************/
+/* Metrics of BayServer                 */
+/****************************************/
+
+var RequestsTotal = NewCounterVec(
+	"bayserver_requests_total",
+	"Number of requests",
+	"city", "town", "club", "status")
+
+var RequestDuration = NewHistogramVec(
+	"bayserver_request_duration_seconds",
+	"Time to process requests",
+	DEFAULT_BUCKETS,
+	"city", "town", "club")
+
+var ReceivedBytes = NewCounterVec(
+	"bayserver_received_bytes_total",
+	"Bytes received from clients including headers",
+	"city", "town")
+
+var SentBytes = NewCounterVec(
+	"bayserver_sent_bytes_total",
+	"Bytes sent to clients including headers",
+	"city", "town")
+
+var InboundShips = NewGaugeFunc(
+	"bayserver_inbound_ships",
+	"Number of active inbound ships (client connections)",
+	"agent")
+
+var ActiveTours = NewGaugeFunc(
+	"bayserver_tours",
+	"Number of active tours (requests in process)",
+	"agent")
+
+var WarpShips = NewGaugeFunc(
+	"bayserver_warp_ships",
+	"Number of warp ships (backend connections) in pool",
+	"destination", "agent", "state")
+
+var WarpConnectErrors = NewCounterVec(
+	"bayserver_warp_connect_errors_total",
+	"Number of errors in connecting to backend servers",
+	"destination")
+
+var CgiProcesses = NewGaugeFunc(
+	"bayserver_cgi_processes",
+	"Number of CGI processes",
+	"town", "club", "state")
+
+var TlsHandshakeFailures = NewCounterVec(
+	"bayserver_tls_handshake_failures_total",
+	"Number of failed TLS handshakes",
+	"port")
+
+var TraceSpans = NewCounterVec(
+	"bayserver_trace_spans_total",
+	"Number of trace spans processed by exporter",
+	"result")
+
+var registry = []Metric{
+	RequestsTotal,
+	RequestDuration,
+	ReceivedBytes,
+	SentBytes,
+	InboundShips,
+	ActiveTours,
+	WarpShips,
+	WarpConnectErrors,
+	CgiProcesses,
+	TlsHandshakeFailures,
+	TraceSpans,
+}
+
+// WriteText returns all metrics in Prometheus text exposition format
+func WriteText() string {
+	var sb strings.Builder
+	for _, m := range registry {
+		sb.WriteString("# HELP " + m.Name() + " " + m.Help() + "\n")
+		sb.WriteString("# TYPE " + m.Name() + " " + m.Type() + "\n")
+		m.WriteTo(&sb)
+	}
+	return sb.String()
+}
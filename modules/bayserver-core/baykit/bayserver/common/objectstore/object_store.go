@@ -7,12 +7,14 @@ import (
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/strutil"
+	"sync"
 )
 
 type ObjectStore struct {
 	freeList   []util.Reusable
 	activeList []util.Reusable
 	factory    ObjectFactory[util.Reusable]
+	lock       sync.Mutex
 }
 
 func NewObjectStore(fct ObjectFactory[util.Reusable]) *ObjectStore {
@@ -24,6 +26,9 @@ func NewObjectStore(fct ObjectFactory[util.Reusable]) *ObjectStore {
 }
 
 func (st *ObjectStore) Rent() util.Reusable {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
 	var obj util.Reusable
 	//baylog.debug(owner + " rent freeList=" + freeList);
 	if len(st.freeList) == 0 {
@@ -44,6 +49,9 @@ func (st *ObjectStore) Rent() util.Reusable {
 }
 
 func (st *ObjectStore) Return(obj util.Reusable, reuse bool) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
 	if arrayutil.Contains(st.freeList, obj) {
 		bayserver.FatalError(exception.NewSink("This object already returned: %s", obj))
 	}
@@ -59,6 +67,28 @@ func (st *ObjectStore) Return(obj util.Reusable, reuse bool) {
 	}
 }
 
+func (st *ObjectStore) ActiveCount() int {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	return len(st.activeList)
+}
+
+func (st *ObjectStore) FreeCount() int {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	return len(st.freeList)
+}
+
+// ActiveList returns copy of active objects
+func (st *ObjectStore) ActiveList() []util.Reusable {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	return append([]util.Reusable{}, st.activeList...)
+}
+
 // Print memory usage
 func (st *ObjectStore) printUsage(indent int) {
 	baylog.Info("%sfree list: %d", strutil.Indent(indent), len(st.freeList))
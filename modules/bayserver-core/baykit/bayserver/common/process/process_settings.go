@@ -0,0 +1,148 @@
+package process
+
+import (
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"bayserver-core/baykit/bayserver/util/sysutil"
+	"os/exec"
+	"strings"
+)
+
+/**
+ * Settings of child processes which dockers start
+ *   user         user        (Run as the user)
+ *   group        group       (Run with the group)
+ *   processGroup true|false  (Run in a new process group to kill its children together)
+ *   limitCpu     seconds
+ *   limitMemory  size
+ *   limitFiles   count
+ *   limitOutput  size
+ */
+type ProcessSettings struct {
+	user         string
+	group        string
+	processGroup bool
+	limits       sysutil.ProcessLimits
+}
+
+func NewProcessSettings() *ProcessSettings {
+	return &ProcessSettings{}
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+func (s *ProcessSettings) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.ConfigException) {
+	var err exception.Exception = nil
+	switch strings.ToLower(kv.Key) {
+	default:
+		return false, nil
+
+	case "user":
+		s.user = kv.Value
+
+	case "group":
+		s.group = kv.Value
+
+	case "processgroup":
+		s.processGroup, err = strutil.ParseBool(kv.Value)
+
+	case "limitcpu":
+		s.limits.CpuSec, err = strutil.ParseInt(kv.Value)
+		if err == nil && s.limits.CpuSec < 0 {
+			err = exception.NewException("Negative value")
+		}
+
+	case "limitmemory":
+		s.limits.MemoryBytes, err = strutil.ParseSize(kv.Value)
+		if err == nil && s.limits.MemoryBytes < 0 {
+			err = exception.NewException("Negative value")
+		}
+
+	case "limitfiles":
+		s.limits.OpenFiles, err = strutil.ParseInt(kv.Value)
+		if err == nil && s.limits.OpenFiles < 0 {
+			err = exception.NewException("Negative value")
+		}
+
+	case "limitoutput":
+		s.limits.FileBytes, err = strutil.ParseSize(kv.Value)
+		if err == nil && s.limits.FileBytes < 0 {
+			err = exception.NewException("Negative value")
+		}
+	}
+
+	if err != nil {
+		return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+	}
+	return true, nil
+}
+
+func (s *ProcessSettings) User() string {
+	return s.user
+}
+
+// OutputLimit returns max bytes of output the process can write (0 means unlimited)
+func (s *ProcessSettings) OutputLimit() int {
+	return s.limits.FileBytes
+}
+
+// Prepare applies user, process group and resource limit settings to the command before it starts.
+// If ownerFile is specified, the command runs as the owner of the file instead of the user.
+func (s *ProcessSettings) Prepare(cmd *exec.Cmd, ownerFile string) exception.IOException {
+	if ownerFile != "" {
+		uid, gid, ioerr := sysutil.GetFileOwner(ownerFile)
+		if ioerr != nil {
+			return ioerr
+		}
+		sysutil.SetProcessCredential(cmd, uid, gid)
+
+	} else if s.user != "" {
+		ioerr := sysutil.SetProcessUser(cmd, s.user)
+		if ioerr != nil {
+			return ioerr
+		}
+	}
+
+	if s.group != "" {
+		ioerr := sysutil.SetProcessGroup(cmd, s.group)
+		if ioerr != nil {
+			return ioerr
+		}
+	}
+
+	if s.processGroup {
+		sysutil.SetNewProcessGroup(cmd)
+	}
+
+	if !s.limits.Empty() {
+		ioerr := sysutil.SetProcessLimits(cmd, &s.limits)
+		if ioerr != nil {
+			return ioerr
+		}
+	}
+	return nil
+}
+
+// Kill kills the process (and its children if process group is enabled)
+func (s *ProcessSettings) Kill(cmd *exec.Cmd) exception.IOException {
+	if s.processGroup {
+		return sysutil.KillProcessGroup(cmd.Process)
+	}
+
+	err := cmd.Process.Kill()
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+	return nil
+}
+
+// Terminate asks the process to exit
+func (s *ProcessSettings) Terminate(cmd *exec.Cmd) exception.IOException {
+	return sysutil.TerminateProcess(cmd.Process)
+}
@@ -0,0 +1,308 @@
+package tracing
+
+import (
+	"bayserver-core/baykit/bayserver/common/metrics"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const DEFAULT_OTLP_ENDPOINT = "http://127.0.0.1:4318/v1/traces"
+const DEFAULT_QUEUE_SIZE = 2048
+const DEFAULT_BATCH_SIZE = 512
+const DEFAULT_FLUSH_INTERVAL_SEC = 5
+const DEFAULT_EXPORT_TIMEOUT_SEC = 10
+
+const INSTRUMENTATION_SCOPE = "bayserver"
+
+/**
+ * Exports spans to a collector using OTLP/HTTP (JSON encoding).
+ * Export never blocks: spans are dropped when the queue is full (the collector is slow or down).
+ * Spans are sent in background by batch when the batch is full or the flush interval elapses.
+ */
+type OtlpExporter struct {
+	endpoint      string
+	serviceName   string
+	version       string
+	batchSize     int
+	flushInterval time.Duration
+	client        *http.Client
+	queue         chan *Span
+	stop          chan bool
+	done          chan bool
+	closeOnce     sync.Once
+}
+
+func NewOtlpExporter(endpoint string, serviceName string, version string, queueSize int, batchSize int, flushIntervalSec int, timeoutSec int) *OtlpExporter {
+	if queueSize <= 0 {
+		queueSize = DEFAULT_QUEUE_SIZE
+	}
+	if batchSize <= 0 {
+		batchSize = DEFAULT_BATCH_SIZE
+	}
+	if flushIntervalSec <= 0 {
+		flushIntervalSec = DEFAULT_FLUSH_INTERVAL_SEC
+	}
+	if timeoutSec <= 0 {
+		timeoutSec = DEFAULT_EXPORT_TIMEOUT_SEC
+	}
+	e := &OtlpExporter{
+		endpoint:      endpoint,
+		serviceName:   serviceName,
+		version:       version,
+		batchSize:     batchSize,
+		flushInterval: time.Duration(flushIntervalSec) * time.Second,
+		client:        &http.Client{Timeout: time.Duration(timeoutSec) * time.Second},
+		queue:         make(chan *Span, queueSize),
+		stop:          make(chan bool),
+		done:          make(chan bool),
+	}
+	go e.run()
+	return e
+}
+
+func (e *OtlpExporter) String() string {
+	return "OtlpExporter[" + e.endpoint + "]"
+}
+
+// Export queues the ended span. Returns false if the span is dropped.
+func (e *OtlpExporter) Export(span *Span) bool {
+	select {
+	case e.queue <- span:
+		return true
+	default:
+		metrics.TraceSpans.Inc("dropped")
+		return false
+	}
+}
+
+// Close sends the spans in the queue and stops the exporter. Spans exported after Close are not sent.
+func (e *OtlpExporter) Close() {
+	e.closeOnce.Do(func() {
+		close(e.stop)
+	})
+	<-e.done
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (e *OtlpExporter) run() {
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, e.batchSize)
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < e.batchSize {
+				continue
+			}
+
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+
+		case <-e.stop:
+			e.drain(batch)
+			close(e.done)
+			return
+		}
+
+		e.send(batch)
+		batch = batch[:0]
+	}
+}
+
+// drain sends the batch and the spans remaining in the queue
+func (e *OtlpExporter) drain(batch []*Span) {
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < e.batchSize {
+				continue
+			}
+
+		default:
+			if len(batch) > 0 {
+				e.send(batch)
+			}
+			return
+		}
+
+		e.send(batch)
+		batch = batch[:0]
+	}
+}
+
+func (e *OtlpExporter) send(batch []*Span) {
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		baylog.ErrorE(err, "%s Cannot encode spans", e)
+		metrics.TraceSpans.Add(float64(len(batch)), "failed")
+		return
+	}
+
+	res, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		baylog.Warn("%s Cannot export spans: %s", e, err)
+		metrics.TraceSpans.Add(float64(len(batch)), "failed")
+		return
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		baylog.Warn("%s Collector returned error: %s", e, res.Status)
+		metrics.TraceSpans.Add(float64(len(batch)), "failed")
+		return
+	}
+
+	baylog.Debug("%s exported %d spans", e, len(batch))
+	metrics.TraceSpans.Add(float64(len(batch)), "exported")
+}
+
+/****************************************/
+/* OTLP JSON encoding                   */
+/****************************************/
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string `json:"timeUnixNano"`
+	Name         string `json:"name"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+func (e *OtlpExporter) encode(batch []*Span) *otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		ospan := otlpSpan{
+			TraceId:           s.Context.TraceIdString(),
+			SpanId:            s.Context.SpanIdString(),
+			TraceState:        s.Context.TraceState,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: unixNano(s.StartTime),
+			EndTimeUnixNano:   unixNano(s.EndTime),
+			Attributes:        encodeAttributes(s.Attributes),
+			Status:            otlpStatus{Code: s.StatusCode, Message: s.StatusMessage},
+		}
+		if !isZero(s.ParentSpanId[:]) {
+			ospan.ParentSpanId = hex.EncodeToString(s.ParentSpanId[:])
+		}
+		for _, ev := range s.Events {
+			ospan.Events = append(ospan.Events, otlpEvent{TimeUnixNano: unixNano(ev.Time), Name: ev.Name})
+		}
+		spans[i] = ospan
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: encodeAttributes([]Attribute{
+						{"service.name", e.serviceName},
+						{"service.version", e.version},
+					}),
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: INSTRUMENTATION_SCOPE, Version: e.version},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func encodeAttributes(attrs []Attribute) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v otlpValue
+		switch val := a.Value.(type) {
+		case string:
+			v.StringValue = &val
+		case int:
+			s := strconv.Itoa(val)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(val, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &val
+		case bool:
+			v.BoolValue = &val
+		default:
+			continue
+		}
+		kvs = append(kvs, otlpKeyValue{Key: a.Key, Value: v})
+	}
+	return kvs
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"time"
+)
+
+const SPAN_KIND_SERVER = 2
+const SPAN_KIND_CLIENT = 3
+
+const SPAN_STATUS_UNSET = 0
+const SPAN_STATUS_OK = 1
+const SPAN_STATUS_ERROR = 2
+
+/****************************************/
+/*  Type Attribute                      */
+/****************************************/
+
+// Attribute is a key and a value (string, int, float64 or bool) of a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+/****************************************/
+/*  Type SpanEvent                      */
+/****************************************/
+
+type SpanEvent struct {
+	Name string
+	Time time.Time
+}
+
+/****************************************/
+/*  Type Span                           */
+/****************************************/
+
+/**
+ * Span of a trace. A span which is not sampled records nothing
+ * but its context is propagated to backend servers.
+ */
+type Span struct {
+	Context       *TraceContext
+	ParentSpanId  [8]byte
+	Name          string
+	Kind          int
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    []Attribute
+	Events        []SpanEvent
+	StatusCode    int
+	StatusMessage string
+}
+
+func NewSpan(ctx *TraceContext, parent *TraceContext, name string, kind int) *Span {
+	s := &Span{
+		Context:   ctx,
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now(),
+	}
+	if parent != nil {
+		s.ParentSpanId = parent.SpanId
+	}
+	return s
+}
+
+func (s *Span) String() string {
+	return "span[" + s.Name + " " + s.Context.String() + "]"
+}
+
+// Recording returns true if the span is sampled
+func (s *Span) Recording() bool {
+	return s.Context.Sampled()
+}
+
+func (s *Span) Ended() bool {
+	return !s.EndTime.IsZero()
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if !s.Recording() {
+		return
+	}
+	for i := range s.Attributes {
+		if s.Attributes[i].Key == key {
+			s.Attributes[i].Value = value
+			return
+		}
+	}
+	s.Attributes = append(s.Attributes, Attribute{key, value})
+}
+
+func (s *Span) AddEvent(name string, t time.Time) {
+	if !s.Recording() {
+		return
+	}
+	s.Events = append(s.Events, SpanEvent{name, t})
+}
+
+func (s *Span) SetError(msg string) {
+	s.StatusCode = SPAN_STATUS_ERROR
+	s.StatusMessage = msg
+}
+
+func (s *Span) End() {
+	if s.Ended() {
+		return
+	}
+	s.EndTime = time.Now()
+}
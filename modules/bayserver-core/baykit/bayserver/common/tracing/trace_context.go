@@ -0,0 +1,172 @@
+package tracing
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"strings"
+)
+
+/**
+ * W3C Trace Context (https://www.w3.org/TR/trace-context/)
+ */
+
+const HEADER_TRACEPARENT = "traceparent"
+const HEADER_TRACESTATE = "tracestate"
+
+const TRACE_VERSION = "00"
+const TRACE_FLAG_SAMPLED = 0x01
+
+const MAX_TRACESTATE_LEN = 512
+
+/****************************************/
+/*  Type TraceContext                   */
+/****************************************/
+
+type TraceContext struct {
+	TraceId    [16]byte
+	SpanId     [8]byte
+	Flags      byte
+	TraceState string
+}
+
+// NewChildContext returns the context of a new span in the same trace
+func (c *TraceContext) NewChildContext() *TraceContext {
+	return &TraceContext{
+		TraceId:    c.TraceId,
+		SpanId:     NewSpanId(),
+		Flags:      c.Flags,
+		TraceState: c.TraceState,
+	}
+}
+
+func (c *TraceContext) Sampled() bool {
+	return c.Flags&TRACE_FLAG_SAMPLED != 0
+}
+
+func (c *TraceContext) SetSampled(sampled bool) {
+	if sampled {
+		c.Flags |= TRACE_FLAG_SAMPLED
+	} else {
+		c.Flags &^= TRACE_FLAG_SAMPLED
+	}
+}
+
+func (c *TraceContext) TraceIdString() string {
+	return hex.EncodeToString(c.TraceId[:])
+}
+
+func (c *TraceContext) SpanIdString() string {
+	return hex.EncodeToString(c.SpanId[:])
+}
+
+// TraceParent returns the value of traceparent header
+func (c *TraceContext) TraceParent() string {
+	return TRACE_VERSION + "-" + c.TraceIdString() + "-" + c.SpanIdString() + "-" + hex.EncodeToString([]byte{c.Flags})
+}
+
+func (c *TraceContext) String() string {
+	return c.TraceParent()
+}
+
+/****************************************/
+/*  Static functions                    */
+/****************************************/
+
+// ParseTraceParent parses the value of traceparent header and returns nil if the value is invalid
+func ParseTraceParent(value string) *TraceContext {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return nil
+	}
+
+	ver, err := hex.DecodeString(value[0:2])
+	if err != nil || ver[0] == 0xff || value[0:2] != strings.ToLower(value[0:2]) {
+		return nil
+	}
+	if value[0:2] == TRACE_VERSION && len(value) != 55 {
+		return nil
+	}
+	if len(value) > 55 && value[55] != '-' {
+		// Future versions may append fields
+		return nil
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return nil
+	}
+
+	c := &TraceContext{}
+	if !decodeLowerHex(value[3:35], c.TraceId[:]) || isZero(c.TraceId[:]) {
+		return nil
+	}
+	if !decodeLowerHex(value[36:52], c.SpanId[:]) || isZero(c.SpanId[:]) {
+		return nil
+	}
+	flags := make([]byte, 1)
+	if !decodeLowerHex(value[53:55], flags) {
+		return nil
+	}
+	c.Flags = flags[0]
+	return c
+}
+
+// NewRootContext returns the context of a new trace
+func NewRootContext(sampled bool) *TraceContext {
+	c := &TraceContext{
+		TraceId: NewTraceId(),
+		SpanId:  NewSpanId(),
+	}
+	c.SetSampled(sampled)
+	return c
+}
+
+// ValidTraceState checks the value of tracestate header roughly (Length and characters)
+func ValidTraceState(value string) bool {
+	if len(value) > MAX_TRACESTATE_LEN {
+		return false
+	}
+	for _, c := range value {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func NewTraceId() [16]byte {
+	var id [16]byte
+	for isZero(id[:]) {
+		binary.BigEndian.PutUint64(id[0:8], rand.Uint64())
+		binary.BigEndian.PutUint64(id[8:16], rand.Uint64())
+	}
+	return id
+}
+
+func NewSpanId() [8]byte {
+	var id [8]byte
+	for isZero(id[:]) {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
+
+/****************************************/
+/*  Private functions                   */
+/****************************************/
+
+func decodeLowerHex(s string, dst []byte) bool {
+	if s != strings.ToLower(s) {
+		return false
+	}
+	n, err := hex.Decode(dst, []byte(s))
+	return err == nil && n == len(dst)
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -26,5 +26,9 @@ type Transporter interface {
 	CheckTimeout(rd rudder.Rudder, durationSec int) bool
 	GetReadBufferSize() int
 
+	// Number of bytes read from the rudder and requested to write to the rudder
+	BytesRead() int64
+	BytesWritten() int64
+
 	PrintUsage(indent int)
 }
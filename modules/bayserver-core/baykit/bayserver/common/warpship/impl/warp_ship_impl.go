@@ -4,6 +4,7 @@ import (
 	"bayserver-core/baykit/bayserver/bayserver"
 	"bayserver-core/baykit/bayserver/common"
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/metrics"
 	"bayserver-core/baykit/bayserver/common/warpship"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/protocol"
@@ -99,7 +100,9 @@ func (sip *WarpShipImpl) NotifyConnect() (common.NextSocketAction, exception.IOE
 	for _, pir := range sip.tourMap {
 		tur := pir.B
 		tur.CheckTourId(pir.A)
-		ioerr := warpship.WarpDataGet(tur).Start()
+		wdat := warpship.WarpDataGet(tur)
+		wdat.OnConnect()
+		ioerr := wdat.Start()
 		if ioerr != nil {
 			return -1, ioerr
 		}
@@ -123,8 +126,13 @@ func (sip *WarpShipImpl) NotifyEof() common.NextSocketAction {
 		tur := pir.B
 		tur.CheckTourId(pir.A)
 
+		if sip.retryTour(tur) {
+			continue
+		}
+
 		var ioerr exception.IOException = nil
 		if !tur.Res().HeaderSent() {
+			sip.endWarpSpan(tur, "server closed on reading headers")
 			baylog.Debug("%s Send ServiceUnavailable: tur=%s", sip, tur)
 			ioerr = tur.Res().SendError(impl.TOUR_ID_NOCHECK, httpstatus.SERVICE_UNAVAILABLE, "Server closed on reading headers", nil)
 
@@ -146,6 +154,9 @@ func (sip *WarpShipImpl) NotifyEof() common.NextSocketAction {
 
 func (sip *WarpShipImpl) NotifyError(e exception.Exception) {
 	baylog.DebugE(e, "%s Error notified", sip)
+	if !sip.connected && sip.docker != nil {
+		metrics.WarpConnectErrors.Inc(warpship.Destination(sip.docker))
+	}
 }
 
 func (sip *WarpShipImpl) NotifyProtocolError(e exception2.ProtocolException) (bool, exception.IOException) {
@@ -239,7 +250,12 @@ func (sip *WarpShipImpl) StartWarpTour(tur tour.Tour) exception.IOException {
 		warpId := wHnd.NextWarpId()
 		wdat := wHnd.NewWarpData(warpId)
 		baylog.Debug("%s new warp tour related to %s", wdat, tur)
+		if old, ok := tur.Req().GetReqContentHandler().(*warpship.WarpData); ok {
+			// Retried through another warp ship
+			old.EndSpan(tur, "retried")
+		}
 		tur.Req().SetReqContentHandler(wdat)
+		wdat.StartSpan(tur, sip.connected)
 
 		baylog.Debug("%s start: warpId=%d", wdat, warpId)
 		if _, exists := sip.tourMap[warpId]; exists {
@@ -269,13 +285,19 @@ func (sip *WarpShipImpl) StartWarpTour(tur tour.Tour) exception.IOException {
 func (sip *WarpShipImpl) EndWarpTour(tur tour.Tour, keep bool) {
 	wdat := warpship.WarpDataGet(tur)
 	baylog.Debug("%s %s end: started=%t ended=%t keep=%t", sip, tur, wdat.Started, wdat.Ended, keep)
+	wdat.EndSpan(tur, "")
 	delete(sip.tourMap, wdat.WarpId)
-	if keep {
+	if keep && len(sip.tourMap) == 0 {
 		baylog.Debug("%s keep warp ship", sip)
 		sip.docker.Keep(sip)
 	}
 }
 
+// TourCount returns the number of tours running on the ship (More than one if multiplexed)
+func (sip *WarpShipImpl) TourCount() int {
+	return len(sip.tourMap)
+}
+
 func (sip *WarpShipImpl) Post(cmd protocol.Command, lis common.DataConsumeListener) exception.IOException {
 	baylog.Debug("%s post: cmd=%s", sip, cmd)
 	if !sip.connected {
@@ -309,8 +331,13 @@ func (sip *WarpShipImpl) notifyErrorToOwnerTour(status int, msg string) {
 
 	for _, pir := range sip.tourMap {
 		tur := pir.B
+		if sip.retryTour(tur) {
+			continue
+		}
 		baylog.Debug("%s send error to owner: %s running=%t", sip, tur, tur.IsRunning())
 
+		sip.endWarpSpan(tur, msg)
+
 		var ioerr exception.IOException = nil
 		if tur.IsRunning() || tur.IsReading() {
 			ioerr = tur.Res().SendError(impl.TOUR_ID_NOCHECK, status, msg, nil)
@@ -327,6 +354,22 @@ func (sip *WarpShipImpl) notifyErrorToOwnerTour(status int, msg string) {
 	clear(sip.tourMap)
 }
 
+// Returns true if the handler sends the tour through another warp ship
+func (sip *WarpShipImpl) retryTour(tur tour.Tour) bool {
+	if sip.protocolHandler == nil {
+		return false
+	}
+	rh, ok := sip.warpHandler().(warpship.WarpRetryHandler)
+	return ok && rh.RetryTour(tur)
+}
+
+// Ends the client span of the tour on error
+func (sip *WarpShipImpl) endWarpSpan(tur tour.Tour, msg string) {
+	if wdat, ok := tur.Req().GetReqContentHandler().(*warpship.WarpData); ok {
+		wdat.EndSpan(tur, msg)
+	}
+}
+
 func (sip *WarpShipImpl) endShip() {
 	sip.docker.OnEndShip(sip)
 }
@@ -2,13 +2,16 @@ package warpship
 
 import (
 	"bayserver-core/baykit/bayserver/agent"
+	"bayserver-core/baykit/bayserver/bayserver"
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/tracing"
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/arrayutil"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/headers"
 	"strconv"
+	"time"
 )
 
 type WarpData struct {
@@ -19,6 +22,10 @@ type WarpData struct {
 	ResHeaders *headers.Headers
 	Started    bool
 	Ended      bool
+
+	/** Client span of the warp tour (nil if tracing is disabled) */
+	span       *tracing.Span
+	resStarted bool
 }
 
 func NewWarpData(wsip WarpShip, wid int) *WarpData {
@@ -110,6 +117,85 @@ func (w *WarpData) Start() exception.IOException {
 	return nil
 }
 
+// StartSpan starts the client span of the warp tour. The trace context is set to the request headers
+// so that it is propagated to the backend server.
+func (w *WarpData) StartSpan(tur tour.Tour, reused bool) {
+	parent := tur.Span()
+	if parent == nil {
+		return
+	}
+
+	ctx := parent.Context.NewChildContext()
+	w.span = tracing.NewSpan(ctx, parent.Context, tur.Req().Method(), tracing.SPAN_KIND_CLIENT)
+	if w.span.Recording() {
+		dkr := w.warpShip.Docker()
+		w.span.SetAttribute("http.request.method", tur.Req().Method())
+		if dkr.Host() != "" {
+			w.span.SetAttribute("server.address", dkr.Host())
+		} else {
+			w.span.SetAttribute("server.address", Destination(dkr))
+		}
+		if dkr.Port() > 0 {
+			w.span.SetAttribute("server.port", dkr.Port())
+		}
+		w.span.SetAttribute("network.protocol.name", w.warpShip.ProtocolHandler().Protocol())
+		w.span.SetAttribute("bayserver.warp.reused", reused)
+	}
+
+	tur.Req().Headers().Set(tracing.HEADER_TRACEPARENT, ctx.TraceParent())
+}
+
+// OnConnect records the time to connect to the backend server
+func (w *WarpData) OnConnect() {
+	if w.span == nil || !w.span.Recording() {
+		return
+	}
+	now := time.Now()
+	w.span.AddEvent("connected", now)
+	w.span.SetAttribute("bayserver.warp.connect_ms", millis(now.Sub(w.span.StartTime)))
+}
+
+// OnResponseStart records the time to the first response from the backend server
+func (w *WarpData) OnResponseStart() {
+	if w.span == nil || w.resStarted {
+		return
+	}
+	w.resStarted = true
+	if w.span.Recording() {
+		now := time.Now()
+		w.span.AddEvent("first_byte", now)
+		w.span.SetAttribute("bayserver.warp.ttfb_ms", millis(now.Sub(w.span.StartTime)))
+	}
+}
+
+// EndSpan ends the client span and exports it
+func (w *WarpData) EndSpan(tur tour.Tour, errMsg string) {
+	span := w.span
+	if span == nil {
+		return
+	}
+	w.span = nil
+
+	if !span.Recording() {
+		return
+	}
+
+	if w.resStarted {
+		status := tur.Res().Headers().Status()
+		span.SetAttribute("http.response.status_code", status)
+		if status >= 400 {
+			span.SetError("")
+		}
+	}
+	if errMsg != "" {
+		span.SetError(errMsg)
+	} else if !w.resStarted {
+		span.SetError("no response")
+	}
+	span.End()
+	bayserver.Harbor().Tracer().Export(span)
+}
+
 /****************************************/
 /* Static functions                     */
 /****************************************/
@@ -117,3 +203,7 @@ func (w *WarpData) Start() exception.IOException {
 func WarpDataGet(tur tour.Tour) *WarpData {
 	return tur.Req().GetReqContentHandler().(*WarpData)
 }
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
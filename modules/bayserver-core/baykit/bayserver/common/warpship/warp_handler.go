@@ -16,3 +16,10 @@ type WarpHandler interface {
 	 */
 	VerifyProtocol(protocol string) exception.IOException
 }
+
+// WarpRetryHandler is implemented by warp handlers which can send the tour through another warp ship
+// when the connection is lost before the server receives the request
+type WarpRetryHandler interface {
+	// RetryTour returns true if the tour is sent again
+	RetryTour(tur tour.Tour) bool
+}
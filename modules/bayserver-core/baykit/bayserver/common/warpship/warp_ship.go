@@ -10,6 +10,7 @@ import (
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"fmt"
+	"strconv"
 )
 
 type WarpShip interface {
@@ -23,6 +24,7 @@ type WarpShip interface {
 	Initialized() bool
 
 	EndWarpTour(tur tour.Tour, keep bool)
+	TourCount() int
 	GetTour(warpId int, must bool) (tour.Tour, exception2.ProtocolException)
 	Docker() docker.Warp
 	WarpHandler() WarpHandler
@@ -30,3 +32,15 @@ type WarpShip interface {
 	Post(cmd protocol.Command, lis common.DataConsumeListener) exception.IOException
 	Flush() exception.IOException
 }
+
+// Destination returns the backend server name of the warp docker for display
+func Destination(dkr docker.Warp) string {
+	if dkr.Host() == "" {
+		// Backend processes managed by the docker
+		return "local"
+
+	} else if dkr.Port() <= 0 {
+		return dkr.Host()
+	}
+	return dkr.Host() + ":" + strconv.Itoa(dkr.Port())
+}
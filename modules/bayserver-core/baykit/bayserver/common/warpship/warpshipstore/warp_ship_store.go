@@ -64,6 +64,22 @@ func (st *WarpShipStore) Rent() warpship.WarpShip {
 	return wsip
 }
 
+/**
+ * Rent busy ship which can carry more tours (for multiplexed connection)
+ */
+
+func (st *WarpShipStore) RentShared(maxTours int) warpship.WarpShip {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	for _, wsip := range st.busyList {
+		if wsip.Initialized() && wsip.TourCount() > 0 && wsip.TourCount() < maxTours {
+			return wsip
+		}
+	}
+	return nil
+}
+
 /**
  * Keep ship which connection is alive
  */
@@ -80,6 +96,22 @@ func (st *WarpShipStore) Keep(wsip warpship.WarpShip) {
 	st.keepList = append(st.keepList, wsip)
 }
 
+/**
+ * Take out kept ship so that it is not rented any more (Returns false if it is not kept)
+ */
+
+func (st *WarpShipStore) Unkeep(wsip warpship.WarpShip) bool {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	var removed bool
+	st.keepList, removed = arrayutil.RemoveObject(st.keepList, wsip)
+	if removed {
+		st.busyList = append(st.busyList, wsip)
+	}
+	return removed
+}
+
 /**
  * Return ship which connection is closed
  */
@@ -98,6 +130,27 @@ func (st *WarpShipStore) Return(wsip warpship.WarpShip) {
 	st.ObjectStore.Return(wsip, true)
 }
 
+/**
+ * Returns ships which are kept or busy
+ */
+
+func (st *WarpShipStore) Ships() []warpship.WarpShip {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	ships := make([]warpship.WarpShip, 0, st.count())
+	ships = append(ships, st.keepList...)
+	return append(ships, st.busyList...)
+}
+
+// Counts returns the number of kept (idle) ships and busy ships
+func (st *WarpShipStore) Counts() (int, int) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	return len(st.keepList), len(st.busyList)
+}
+
 func (st *WarpShipStore) PrintUsage(indent int) {
 	baylog.Info("%sWarpShipStore Usage:", strutil.Indent(indent))
 	baylog.Info("%skeepList: %d", strutil.Indent(indent+1), len(st.keepList))
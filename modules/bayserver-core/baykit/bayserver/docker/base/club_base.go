@@ -6,8 +6,10 @@ import (
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/httpstatus"
 	"bayserver-core/baykit/bayserver/util/strutil"
 	"strings"
 )
@@ -18,6 +20,7 @@ type ClubBase struct {
 	extension      string
 	charset        string
 	decodePathInfo bool
+	earlyHints     []string
 }
 
 func NewClubBase(parent DockerInitializer) *ClubBase {
@@ -78,6 +81,9 @@ func (c *ClubBase) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.ConfigExcepti
 		if kv.Value != "" {
 			c.charset = kv.Value
 		}
+
+	case "earlyhint", "earlyhints":
+		c.earlyHints = append(c.earlyHints, kv.Value)
 	}
 	return true, nil
 }
@@ -102,6 +108,10 @@ func (c *ClubBase) DecodePathInfo() bool {
 	return c.decodePathInfo
 }
 
+func (c *ClubBase) EarlyHints() []string {
+	return c.earlyHints
+}
+
 /****************************************/
 /* Custom functions                     */
 /****************************************/
@@ -141,3 +151,33 @@ func (c *ClubBase) Matches(fname string) bool {
 		}
 	}
 }
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+// SendText sends whole response of the text
+func SendText(tur tour.Tour, contentType string, text string) exception.IOException {
+	body := []byte(text)
+	tourId := tur.TourId()
+
+	tur.Res().Headers().SetStatus(httpstatus.OK)
+	tur.Res().Headers().SetContentType(contentType)
+	tur.Res().Headers().SetContentLength(len(body))
+	tur.Res().SetConsumeListener(tour.DevNullContentConsumeListener)
+
+	ioerr := tur.Res().SendHeaders(tourId)
+	if ioerr != nil {
+		return ioerr
+	}
+
+	if tur.Req().Method() != "HEAD" {
+		_, ioerr = tur.Res().SendResContent(tourId, body, 0, len(body))
+		if ioerr != nil {
+			baylog.DebugE(ioerr, "%s Cannot send content", tur)
+			return ioerr
+		}
+	}
+
+	return tur.Res().EndResContent(tourId)
+}
@@ -9,6 +9,7 @@ import (
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
 	inboundship "bayserver-core/baykit/bayserver/common/inboundship/impl"
 	"bayserver-core/baykit/bayserver/common/inboundship/inboundshipstore"
+	"bayserver-core/baykit/bayserver/common/metrics"
 	"bayserver-core/baykit/bayserver/docker"
 	common2 "bayserver-core/baykit/bayserver/docker/common"
 	"bayserver-core/baykit/bayserver/protocol"
@@ -222,7 +223,11 @@ func (p *PortBase) FindCity(name string) docker.City {
 }
 
 func (p *PortBase) GetSecureConn(conn net.Conn) (net.Conn, exception.IOException) {
-	return p.SecureDocker.GetSecureConn(conn)
+	sslConn, ioerr := p.SecureDocker.GetSecureConn(conn)
+	if ioerr != nil {
+		metrics.TlsHandshakeFailures.Inc(strconv.Itoa(p.port))
+	}
+	return sslConn, ioerr
 }
 
 func (p *PortBase) OnConnected(agentId int, rd rudder.Rudder) exception2.HttpException {
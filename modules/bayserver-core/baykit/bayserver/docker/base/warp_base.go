@@ -7,6 +7,7 @@ import (
 	"bayserver-core/baykit/bayserver/common"
 	"bayserver-core/baykit/bayserver/common/baymessage"
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/metrics"
 	"bayserver-core/baykit/bayserver/common/warpship"
 	"bayserver-core/baykit/bayserver/common/warpship/warpshipstore"
 	"bayserver-core/baykit/bayserver/docker"
@@ -20,6 +21,7 @@ import (
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
@@ -47,13 +49,27 @@ func NewWarpBase_LifeCycleListener(base *WarpBase) *WarpBase_LifeCycleListener {
 }
 
 func (l *WarpBase_LifeCycleListener) Add(agentId int) {
-	l.base.stores[agentId] = warpshipstore.NewWarpShipStore(l.base.maxShips)
+	st := warpshipstore.NewWarpShipStore(l.base.maxShips)
+	l.base.stores[agentId] = st
+
+	dest := warpship.Destination(l.base)
+	agtId := strconv.Itoa(agentId)
+	metrics.WarpShips.SetCollector(l.metricsKey(agentId), func(emit metrics.Emitter) {
+		keep, busy := st.Counts()
+		emit(float64(keep), dest, agtId, "idle")
+		emit(float64(busy), dest, agtId, "busy")
+	})
 }
 
 func (l *WarpBase_LifeCycleListener) Remove(agentId int) {
+	metrics.WarpShips.RemoveCollector(l.metricsKey(agentId))
 	delete(l.base.stores, agentId)
 }
 
+func (l *WarpBase_LifeCycleListener) metricsKey(agentId int) string {
+	return fmt.Sprintf("%p/%d", l.base, agentId)
+}
+
 /****************************************/
 /*  Type MemUsage_LifeCycleListener     */
 /****************************************/
@@ -64,6 +80,18 @@ type WarpSub interface {
 	NewTransporter(agt agent.GrandAgent, rd rudder.Rudder, sip ship.Ship) (*multiplexer.PlainTransporter, exception.IOException)
 }
 
+// WarpDestinationSub is implemented by warp dockers which select destination for each connection
+type WarpDestinationSub interface {
+	// NextDestination returns nil if there is no destination available
+	NextDestination(wsip ship.Ship) (net.Addr, exception.IOException)
+}
+
+// WarpMultiplexSub is implemented by warp dockers which can send concurrent tours through a connection
+type WarpMultiplexSub interface {
+	// MaxWarpTours returns the number of tours carried by a warp ship at a time
+	MaxWarpTours() int
+}
+
 type WarpBase struct {
 	*ClubBase
 
@@ -113,8 +141,12 @@ func (h *WarpBase) Init(elm *bcf.BcfElement, parent docker.Docker) exception2.Co
 	var ioerr exception.IOException = nil
 	for { // try catch
 		if h.host != "" && strings.HasPrefix(h.host, ":unix:") {
-			//sktPath := h.host[6:]
-			//h.hostAddr = sysutil.GetUnixDomainSocketAddress(sktPath)
+			var err error
+			h.hostAddr, err = net.ResolveUnixAddr("unix", h.host[6:])
+			if err != nil {
+				ioerr = exception.NewIOExceptionFromError(err)
+				break
+			}
 			h.port = -1
 
 		} else {
@@ -191,7 +223,13 @@ func (h *WarpBase) Arrive(tur tour.Tour) exception2.HttpException {
 	agt := agent.Get(tur.Ship().(ship.Ship).AgentId())
 	sto := h.GetShipStore(agt.AgentId())
 
-	wsip := sto.Rent()
+	var wsip warpship.WarpShip = nil
+	if ms, ok := h.sub.(WarpMultiplexSub); ok && ms.MaxWarpTours() > 1 {
+		wsip = sto.RentShared(ms.MaxWarpTours())
+	}
+	if wsip == nil {
+		wsip = sto.Rent()
+	}
 	if wsip == nil {
 		return exception2.NewHttpException(httpstatus.SERVICE_UNAVAILABLE, "WarpDocker busy")
 	}
@@ -200,8 +238,19 @@ func (h *WarpBase) Arrive(tur tour.Tour) exception2.HttpException {
 	for { // try catch
 		needConnect := false
 		var tp common.Transporter = nil
+		addr := h.hostAddr
 
 		if !wsip.Initialized() {
+			if ds, ok := h.sub.(WarpDestinationSub); ok {
+				addr, ioerr = ds.NextDestination(wsip)
+				if ioerr != nil {
+					break
+				}
+				if addr == nil {
+					sto.Return(wsip)
+					return exception2.NewHttpException(httpstatus.SERVICE_UNAVAILABLE, "WarpDocker busy")
+				}
+			}
 
 			rd := impl.NewTcpConnRudderUnconnected()
 
@@ -228,7 +277,7 @@ func (h *WarpBase) Arrive(tur tour.Tour) exception2.HttpException {
 
 		if needConnect {
 			agt.NetMultiplexer().AddRudderState(wsip.Rudder(), common.NewRudderState(wsip.Rudder(), tp))
-			ioerr = agt.NetMultiplexer().GetTransporter(wsip.Rudder()).ReqConnect(wsip.Rudder(), h.hostAddr)
+			ioerr = agt.NetMultiplexer().GetTransporter(wsip.Rudder()).ReqConnect(wsip.Rudder(), addr)
 			if ioerr != nil {
 				break
 			}
@@ -278,6 +327,20 @@ func (h *WarpBase) OnEndShip(wsip ship.Ship) {
 /* Custom Functions                     */
 /****************************************/
 
+// CloseKept closes the connection of kept ship. Must be called in the agent of the ship
+func (h *WarpBase) CloseKept(wsip ship.Ship) bool {
+	if !h.GetShipStore(wsip.AgentId()).Unkeep(wsip.(warpship.WarpShip)) {
+		return false
+	}
+	baylog.Debug("%s close kept warp ship: %s", h, wsip)
+	wsip.PostClose(wsip.ShipId())
+	return true
+}
+
+func (h *WarpBase) HostAddr() net.Addr {
+	return h.hostAddr
+}
+
 func (h *WarpBase) GetShipStore(agtId int) *warpshipstore.WarpShipStore {
 	return h.stores[agtId]
 }
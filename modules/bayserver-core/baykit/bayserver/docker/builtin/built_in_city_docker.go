@@ -12,6 +12,7 @@ import (
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/headers"
 	"bayserver-core/baykit/bayserver/util/httpstatus"
 	"bayserver-core/baykit/bayserver/util/sysutil"
 	"fmt"
@@ -51,6 +52,11 @@ type matchInfo struct {
 	rewrittenURI string
 }
 
+// Club which has its own early hints (optional)
+type earlyHintClub interface {
+	EarlyHints() []string
+}
+
 func NewBuiltInCityDocker() docker.City {
 	c := BuiltInCityDocker{}
 	c.DockerBase = base.NewDockerBase(&c)
@@ -154,70 +160,16 @@ func (c *BuiltInCityDocker) Enter(tur tour.Tour) exception2.HttpException {
 	baylog.Debug("%s City[%s] Request URI: %s", tur, c.name, tur.Req().Uri())
 	tur.SetCity(c)
 
-	var herr exception2.HttpException = nil
-catch:
-	for { // try catch
-		for _, p := range c.permissionList {
-			herr = p.TourAdmitted(tur)
-			if herr != nil {
-				break catch
-			}
-		}
-
+	return checkPermissions(tur, c.permissionList, func() exception2.HttpException {
 		mInfo := c.getTownAndClub(tur.Req().Uri())
 		if mInfo == nil {
-			herr = exception2.NewHttpException(httpstatus.NOT_FOUND, tur.Req().Uri())
-			break
-		}
-
-		herr = mInfo.town.CheckAdmitted(tur)
-		if herr != nil {
-			break
-		}
-
-		if mInfo.redirectURI != "" {
-			herr = exception2.NewMovedTemporarily(mInfo.redirectURI)
-
-		} else {
-			baylog.Debug("%s Town[%s] Club[%s]", tur, mInfo.town.Name(), mInfo.clubMatch.club)
-			tur.Req().SetQueryString(mInfo.queryString)
-			tur.Req().SetScriptName(mInfo.clubMatch.scriptName)
-
-			if mInfo.clubMatch.club.Charset() != "" {
-				tur.Req().SetCharset(mInfo.clubMatch.club.Charset())
-				tur.Res().SetCharset(mInfo.clubMatch.club.Charset())
-
-			} else {
-				tur.Req().SetCharset(bayserver.Harbor().Charset())
-				tur.Res().SetCharset(bayserver.Harbor().Charset())
-			}
-
-			tur.Req().SetPathInfo(mInfo.clubMatch.pathInfo)
-			if tur.Req().PathInfo() != "" && mInfo.clubMatch.club.DecodePathInfo() {
-				decodedStr, err := url.QueryUnescape(tur.Req().PathInfo())
-				if err != nil {
-					baylog.ErrorE(exception.NewExceptionFromError(err), "")
-				}
-				tur.Req().SetPathInfo(decodedStr)
-			}
-
-			if mInfo.rewrittenURI != "" {
-				tur.Req().SetRewrittenUri(mInfo.rewrittenURI)
-			}
-
-			club := mInfo.clubMatch.club
-			tur.SetTown(mInfo.town)
-			tur.SetClub(club)
-			herr = club.Arrive(tur)
-			if herr != nil {
-				break
-			}
+			return exception2.NewHttpException(httpstatus.NOT_FOUND, tur.Req().Uri())
 		}
 
-		break
-	}
-
-	return herr
+		return mInfo.town.CheckAdmitted(tur, func() exception2.HttpException {
+			return c.arrive(tur, mInfo)
+		})
+	})
 }
 
 /**
@@ -231,13 +183,101 @@ func (c *BuiltInCityDocker) GetTrouble() docker.Trouble {
  * Logging
  */
 func (c *BuiltInCityDocker) Log(tur tour.Tour) {
-
+	for _, dkr := range c.logList {
+		ioerr := dkr.Log(tur)
+		if ioerr != nil {
+			baylog.ErrorE(ioerr, "%s Cannot write access log", tur)
+		}
+	}
 }
 
 /****************************************/
 /* Private methods                      */
 /****************************************/
 
+// Sends the tour to the club (Permissions are already checked)
+func (c *BuiltInCityDocker) arrive(tur tour.Tour, mInfo *matchInfo) exception2.HttpException {
+	if mInfo.redirectURI != "" {
+		return exception2.NewMovedTemporarily(mInfo.redirectURI)
+	}
+
+	baylog.Debug("%s Town[%s] Club[%s]", tur, mInfo.town.Name(), mInfo.clubMatch.club)
+	tur.Req().SetQueryString(mInfo.queryString)
+	tur.Req().SetScriptName(mInfo.clubMatch.scriptName)
+
+	if mInfo.clubMatch.club.Charset() != "" {
+		tur.Req().SetCharset(mInfo.clubMatch.club.Charset())
+		tur.Res().SetCharset(mInfo.clubMatch.club.Charset())
+
+	} else {
+		tur.Req().SetCharset(bayserver.Harbor().Charset())
+		tur.Res().SetCharset(bayserver.Harbor().Charset())
+	}
+
+	tur.Req().SetPathInfo(mInfo.clubMatch.pathInfo)
+	if tur.Req().PathInfo() != "" && mInfo.clubMatch.club.DecodePathInfo() {
+		decodedStr, err := url.QueryUnescape(tur.Req().PathInfo())
+		if err != nil {
+			baylog.ErrorE(exception.NewExceptionFromError(err), "")
+		}
+		tur.Req().SetPathInfo(decodedStr)
+	}
+
+	if mInfo.rewrittenURI != "" {
+		tur.Req().SetRewrittenUri(mInfo.rewrittenURI)
+	}
+
+	club := mInfo.clubMatch.club
+	tur.SetTown(mInfo.town)
+	tur.SetClub(club)
+	tourId := tur.TourId()
+	c.sendEarlyHints(tur, mInfo.town, club)
+	herr := club.Arrive(tur)
+	if herr != nil {
+		return herr
+	}
+
+	c.sendContinue(tur, tourId)
+	return nil
+}
+
+// Sends early hints of the town and the club which the tour arrives at (Permissions are already checked)
+func (c *BuiltInCityDocker) sendEarlyHints(tur tour.Tour, town docker.Town, club docker.Club) {
+	hdrs := headers.NewHeaders()
+	for _, link := range town.EarlyHints() {
+		hdrs.Add(headers.LINK, link)
+	}
+	if hc, ok := club.(earlyHintClub); ok {
+		for _, link := range hc.EarlyHints() {
+			hdrs.Add(headers.LINK, link)
+		}
+	}
+	if !hdrs.Contains(headers.LINK) {
+		return
+	}
+
+	ioerr := tur.Res().SendInformational(tur.TourId(), httpstatus.EARLY_HINTS, hdrs)
+	if ioerr != nil {
+		baylog.ErrorE(ioerr, "%s Cannot send early hints", tur)
+	}
+}
+
+// Lets the client send the request content after the tour is admitted (Expect: 100-continue)
+func (c *BuiltInCityDocker) sendContinue(tur tour.Tour, tourId int) {
+	if tur.TourId() != tourId || !tur.IsReading() {
+		// Tour is already ended or has no content to read
+		return
+	}
+	if !tur.Req().ExpectsContinue() || tur.Req().ContinueRelayed() {
+		return
+	}
+
+	ioerr := tur.Res().SendInformational(tourId, httpstatus.CONTINUE, headers.NewHeaders())
+	if ioerr != nil {
+		baylog.ErrorE(ioerr, "%s Cannot send 100 Continue", tur)
+	}
+}
+
 func (c *BuiltInCityDocker) clubMatches(clubs []docker.Club, relUri string, townName string) *clubMatchInfo {
 	mi := clubMatchInfo{}
 	var anyd docker.Club = nil
@@ -369,3 +409,67 @@ func (c *BuiltInCityDocker) getTownAndClub(reqUri string) *matchInfo {
 
 	return nil
 }
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+// Checks permissions in order and calls next if the tour is admitted
+func checkPermissions(tur tour.Tour, perms []docker.Permission, next func() exception2.HttpException) exception2.HttpException {
+	for i, p := range perms {
+		if ap, ok := p.(docker.AsyncPermission); ok {
+			waitPermission(tur, ap, perms[i+1:], next)
+			return nil
+		}
+
+		herr := p.TourAdmitted(tur)
+		if herr != nil {
+			return herr
+		}
+	}
+	return next()
+}
+
+// Holds the request content until the permission is decided and then resumes the tour
+func waitPermission(tur tour.Tour, p docker.AsyncPermission, rest []docker.Permission, next func() exception2.HttpException) {
+	tourId := tur.TourId()
+	pending := tour.NewPendingContentHandler()
+	tur.Req().SetReqContentHandler(pending)
+
+	baylog.Debug("%s wait permission: %s", tur, p)
+	p.TourAdmittedAsync(tur, func(herr exception2.HttpException) {
+		if !tur.IsInitialized() || tur.TourId() != tourId || tur.IsAborted() {
+			baylog.Debug("%s tour is gone while waiting permission: id=%d", tur, tourId)
+			return
+		}
+
+		tur.Req().ResetReqContentHandler()
+
+		var ioerr exception.IOException = nil
+		for { // try catch
+			if herr != nil {
+				break
+			}
+
+			herr = checkPermissions(tur, rest, next)
+			if herr != nil {
+				break
+			}
+
+			if !tur.IsInitialized() || tur.TourId() != tourId {
+				// Tour is already ended
+				return
+			}
+			ioerr, herr = pending.Replay(tur)
+			break
+		}
+
+		if herr != nil {
+			ioerr = tur.Res().SendHttpException(tourId, herr)
+		}
+
+		if ioerr != nil {
+			baylog.ErrorE(ioerr, "%s Cannot resume tour", tur)
+		}
+	})
+}
@@ -12,7 +12,9 @@ import (
 	"bayserver-core/baykit/bayserver/util"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/logsink"
 	"bayserver-core/baykit/bayserver/util/strutil"
+	"net"
 	"strings"
 )
 
@@ -50,6 +52,8 @@ const DEFAULT_GZIP_COMP = false
 
 const DEFAULT_PID_FILE = "bayserver.pid"
 
+const DEFAULT_REQUEST_ID_HEADER = "X-Request-Id"
+
 type BuiltInHarborDocker struct {
 	*base.DockerBase
 
@@ -64,7 +68,9 @@ type BuiltInHarborDocker struct {
 	tourBufferSize   int
 	traceHeader      bool
 	trouble          docker.Trouble
+	tracer           docker.Tracer
 	redirectFile     string
+	logSink          string
 	gzipComp         bool
 	controlPort      int
 	multiCore        bool
@@ -74,6 +80,8 @@ type BuiltInHarborDocker struct {
 	cgiMultiplexer   int
 	recipient        int
 	pidFile          string
+	requestIdHeader  string
+	requestIdTrusted []*util.IpMatcher
 }
 
 func NewBuiltInHarborDocker() docker.Harbor {
@@ -90,7 +98,9 @@ func NewBuiltInHarborDocker() docker.Harbor {
 	h.tourBufferSize = DEFAULT_TOUR_BUFFER_SIZE
 	h.traceHeader = false
 	h.trouble = nil
+	h.tracer = nil
 	h.redirectFile = ""
+	h.logSink = ""
 	h.gzipComp = DEFAULT_GZIP_COMP
 	h.controlPort = DEFAULT_CONTROL_PORT
 	h.multiCore = DEFAULT_MULTI_CORE
@@ -100,6 +110,8 @@ func NewBuiltInHarborDocker() docker.Harbor {
 	h.cgiMultiplexer = DEFAULT_CGI_MULTIPLEXER
 	h.recipient = DEFAULT_RECIPIENT
 	h.pidFile = DEFAULT_PID_FILE
+	h.requestIdHeader = DEFAULT_REQUEST_ID_HEADER
+	h.requestIdTrusted = []*util.IpMatcher{}
 
 	return h
 }
@@ -199,6 +211,9 @@ func (d *BuiltInHarborDocker) InitDocker(dkr docker.Docker) (bool, exception.Con
 	if t, ok := dkr.(docker.Trouble); ok {
 		d.trouble = t
 		return true, nil
+	} else if t, ok := dkr.(docker.Tracer); ok {
+		d.tracer = t
+		return true, nil
 	} else {
 		return d.DefaultInitDocker()
 	}
@@ -211,7 +226,10 @@ func (d *BuiltInHarborDocker) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.Con
 		return d.DefaultInitKeyVal(kv)
 
 	case "loglevel":
-		baylog.SetLogLevel(kv.Value)
+		err = baylog.SetBaseLogLevels(kv.Value)
+
+	case "logformat":
+		err = baylog.SetLogFormat(kv.Value)
 
 	case "charset":
 		charset := strutil.ParseCharset(kv.Value)
@@ -262,6 +280,39 @@ func (d *BuiltInHarborDocker) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.Con
 	case "redirectfile":
 		d.redirectFile = kv.Value
 
+	case "logsink":
+		if !logsink.IsSinkTarget(kv.Value) {
+			return false, exception.NewConfigException(
+				kv.FileName,
+				kv.LineNo,
+				baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+		d.logSink = kv.Value
+
+	case "requestidheader":
+		d.requestIdHeader = kv.Value
+
+	case "trustrequestid":
+		for _, adr := range strings.Fields(kv.Value) {
+			if adr != "*" && !strings.Contains(adr, "/") {
+				// Single address
+				if strings.Contains(adr, ":") {
+					adr += "/128"
+				} else {
+					adr += "/32"
+				}
+			}
+			m, ioerr := util.NewIpMatcher(adr)
+			if ioerr != nil {
+				baylog.ErrorE(ioerr, "")
+				return false, exception.NewConfigException(
+					kv.FileName,
+					kv.LineNo,
+					baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+			}
+			d.requestIdTrusted = append(d.requestIdTrusted, m)
+		}
+
 	case "controlport":
 		d.controlPort, err = strutil.ParseInt(kv.Value)
 
@@ -368,6 +419,10 @@ func (d *BuiltInHarborDocker) Trouble() docker.Trouble {
 	return d.trouble
 }
 
+func (d *BuiltInHarborDocker) Tracer() docker.Tracer {
+	return d.tracer
+}
+
 func (d *BuiltInHarborDocker) SocketTimeoutSec() int {
 	return d.socketTimeoutSec
 }
@@ -388,6 +443,27 @@ func (d *BuiltInHarborDocker) RedirectFile() string {
 	return d.redirectFile
 }
 
+func (d *BuiltInHarborDocker) LogSink() string {
+	return d.logSink
+}
+
+func (d *BuiltInHarborDocker) RequestIdHeader() string {
+	return d.requestIdHeader
+}
+
+func (d *BuiltInHarborDocker) RequestIdTrusted(remoteAddress string) bool {
+	ip := net.ParseIP(remoteAddress)
+	if ip == nil {
+		return false
+	}
+	for _, m := range d.requestIdTrusted {
+		if m.Match(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *BuiltInHarborDocker) ControlPort() int {
 	return d.controlPort
 }
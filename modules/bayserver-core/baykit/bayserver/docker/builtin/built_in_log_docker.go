@@ -1,91 +1,24 @@
 package builtin
 
 import (
-	"bayserver-core/baykit/bayserver/agent"
-	"bayserver-core/baykit/bayserver/bayserver"
 	"bayserver-core/baykit/bayserver/bcf"
-	"bayserver-core/baykit/bayserver/common"
 	"bayserver-core/baykit/bayserver/common/baymessage"
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/logfile"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/docker/base"
 	"bayserver-core/baykit/bayserver/docker/builtin/logitems"
-	"bayserver-core/baykit/bayserver/rudder"
-	"bayserver-core/baykit/bayserver/rudder/impl"
 	"bayserver-core/baykit/bayserver/ship"
 	"bayserver-core/baykit/bayserver/symbol"
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
-	"bayserver-core/baykit/bayserver/util/sysutil"
-	"os"
-	"path"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"math/rand"
 	"strconv"
 	"strings"
 )
 
-/****************************************/
-/* type LogAgentListener                */
-/****************************************/
-
-type LogAgentListener struct {
-	docker *BuiltInLogDocker
-}
-
-func NewLogAgentListener(dkr *BuiltInLogDocker) common.LifecycleListener {
-	return &LogAgentListener{
-		docker: dkr,
-	}
-}
-
-func (lis *LogAgentListener) Add(agentId int) {
-	fileName := lis.docker.filePrefix + "_" + strconv.Itoa(agentId) + "." + lis.docker.fileExt
-	var ioerr exception.IOException = nil
-	for { // try-catch
-		agt := agent.Get(agentId)
-		var rd rudder.Rudder
-		var mpx common.Multiplexer
-		switch bayserver.Harbor().LogMultiplexer() {
-		case docker.MULTI_PLEXER_TYPE_TAXI:
-			{
-
-			}
-		case docker.MULTI_PLEXER_TYPE_JOB:
-			{
-				file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE, 0755)
-				if err != nil {
-					ioerr = exception.NewIOExceptionFromError(err)
-					break
-				}
-				rd = impl.NewFileRudder(file)
-				mpx = agt.JobMultiplexer()
-			}
-
-		default:
-			bayserver.FatalError(exception.NewSink("Multiplexer type not supported: %s", bayserver.Harbor().LogMultiplexer()))
-		}
-
-		st := common.NewRudderState(rd, nil)
-		st.BytesWrote = -1
-		mpx.AddRudderState(rd, st)
-		lis.docker.multiplexers = append(lis.docker.multiplexers, mpx)
-		lis.docker.rudders = append(lis.docker.rudders, rd)
-		break
-	}
-
-	if ioerr != nil {
-		baylog.Fatal(baymessage.Get(symbol.INT_CANNOT_OPEN_LOG_FILE, fileName))
-		baylog.FatalE(ioerr, "")
-	}
-}
-
-func (lis *LogAgentListener) Remove(agentId int) {
-	rd := lis.docker.rudders[agentId-1]
-	lis.docker.multiplexers[agentId-1].ReqClose(rd)
-	lis.docker.multiplexers[agentId-1] = nil
-	lis.docker.rudders[agentId-1] = nil
-}
-
 /****************************************/
 /* type BuildInLogDocker                */
 /****************************************/
@@ -93,60 +26,134 @@ func (lis *LogAgentListener) Remove(agentId int) {
 var logItemFactoryMap = map[string]LogItemFactory{
 	"a":  func() logitems.LogItem { return logitems.NewRemoteIpItem() },
 	"A":  func() logitems.LogItem { return logitems.NewServerIpItem() },
-	"b":  func() logitems.LogItem { return logitems.NewRequestBytesItem2() },
-	"B":  func() logitems.LogItem { return logitems.NewRequestBytesItem1() },
+	"b":  func() logitems.LogItem { return logitems.NewResponseBytesItem2() },
+	"B":  func() logitems.LogItem { return logitems.NewResponseBytesItem1() },
 	"c":  func() logitems.LogItem { return logitems.NewConnectionStatusItem() },
-	"e":  func() logitems.LogItem { return logitems.NewNullItem() },
+	"C":  func() logitems.LogItem { return logitems.NewCookieItem() },
+	"D":  func() logitems.LogItem { return logitems.NewDurationItem() },
+	"e":  func() logitems.LogItem { return logitems.NewEnvItem() },
 	"h":  func() logitems.LogItem { return logitems.NewRemoteHostItem() },
 	"i":  func() logitems.LogItem { return logitems.NewRequestHeaderItem() },
+	"I":  func() logitems.LogItem { return logitems.NewBytesReceivedItem() },
 	"l":  func() logitems.LogItem { return logitems.NewRemoteLogItem() },
+	"L":  func() logitems.LogItem { return logitems.NewLogIdItem() },
 	"m":  func() logitems.LogItem { return logitems.NewMethodItem() },
-	"n":  func() logitems.LogItem { return logitems.NewNullItem() },
+	"n":  func() logitems.LogItem { return logitems.NewNoteItem() },
 	"o":  func() logitems.LogItem { return logitems.NewResponseHeaderItem() },
+	"O":  func() logitems.LogItem { return logitems.NewBytesSentItem() },
 	"p":  func() logitems.LogItem { return logitems.NewPortItem() },
-	"P":  func() logitems.LogItem { return logitems.NewNullItem() },
+	"P":  func() logitems.LogItem { return logitems.NewProcessIdItem() },
 	"q":  func() logitems.LogItem { return logitems.NewQueryStringItem() },
 	"r":  func() logitems.LogItem { return logitems.NewStartLineItem() },
 	"s":  func() logitems.LogItem { return logitems.NewStatusItem() },
 	">s": func() logitems.LogItem { return logitems.NewStatusItem() },
+	"S":  func() logitems.LogItem { return logitems.NewBytesTransferredItem() },
 	"t":  func() logitems.LogItem { return logitems.NewTimeItem() },
 	"T":  func() logitems.LogItem { return logitems.NewIntervalItem() },
 	"u":  func() logitems.LogItem { return logitems.NewRemoteUserItem() },
 	"U":  func() logitems.LogItem { return logitems.NewRequestUrlItem() },
 	"v":  func() logitems.LogItem { return logitems.NewServerNameItem() },
-	"V":  func() logitems.LogItem { return logitems.NewNullItem() },
+	"V":  func() logitems.LogItem { return logitems.NewCanonicalServerNameItem() },
+}
+
+var logItemNameMap = map[string]string{
+	"a":  "remote_addr",
+	"A":  "server_addr",
+	"b":  "bytes_clf",
+	"B":  "bytes",
+	"c":  "connection_status",
+	"C":  "cookie",
+	"D":  "duration_us",
+	"e":  "env",
+	"h":  "remote_host",
+	"i":  "req",
+	"I":  "bytes_received",
+	"l":  "remote_logname",
+	"L":  "request_id",
+	"m":  "method",
+	"n":  "note",
+	"o":  "res",
+	"O":  "bytes_sent",
+	"p":  "port",
+	"P":  "pid",
+	"q":  "query",
+	"r":  "request",
+	"s":  "status",
+	">s": "status",
+	"S":  "bytes_transferred",
+	"t":  "time",
+	"T":  "duration",
+	"u":  "remote_user",
+	"U":  "path",
+	"v":  "server_name",
+	"V":  "canonical_server_name",
+}
+
+const LOG_MODE_TEXT = "text"
+const LOG_MODE_JSON = "json"
+const LOG_MODE_LTSV = "ltsv"
+
+/**
+ * Compiled log format
+ */
+type logFormat struct {
+	format string
+	items  []logitems.LogItem
+
+	/** Field name of each item (Empty for text) */
+	names []string
+}
+
+/**
+ * Named field in json/ltsv mode
+ */
+type logField struct {
+	name  string
+	items []logitems.LogItem
 }
 
 type BuiltInLogDocker struct {
 	*base.DockerBase
 
-	/** Log file name parts */
-	filePrefix string
-	fileExt    string
+	/** Log file (written by each agent) */
+	file *logfile.LogFile
 
 	/** Log format */
 	format string
 
+	/** Output mode (text, json or ltsv) */
+	mode string
+
+	/** Custom field definitions (name and format) */
+	fieldDefs []*bcf.BcfKeyVal
+
 	/** Log items */
 	logItems []logitems.LogItem
 
-	rudders []rudder.Rudder
+	/** Fields (json/ltsv mode) */
+	fields []logField
+
+	/** Conditions: tour is logged if it matches all of ifConditions and none of unlessConditions */
+	ifConditions     []LogCondition
+	unlessConditions []LogCondition
 
-	/** Multiplexer to write to file */
-	multiplexers []common.Multiplexer
+	/** Sampling rate (0.0 - 1.0) */
+	sampleRate float64
 }
 
 func NewBuiltInLogDocker() docker.Log {
 	dkr := &BuiltInLogDocker{}
 	dkr.DockerBase = base.NewDockerBase(dkr)
+	dkr.mode = LOG_MODE_TEXT
 	dkr.logItems = make([]logitems.LogItem, 0)
-	dkr.rudders = make([]rudder.Rudder, 0)
-	dkr.multiplexers = make([]common.Multiplexer, 0)
+	dkr.fields = make([]logField, 0)
+	dkr.sampleRate = 1.0
+	dkr.file = logfile.NewLogFile()
 	return dkr
 }
 
 func (d *BuiltInLogDocker) String() string {
-	return "BuiltInLogDocker(" + d.filePrefix + "." + d.fileExt + ")"
+	return "BuiltInLogDocker(" + d.file.FilePrefix() + "." + d.file.FileExt() + ")"
 }
 
 /****************************************/
@@ -159,45 +166,48 @@ func (d *BuiltInLogDocker) Init(elm *bcf.BcfElement, parent docker.Docker) excep
 		return err
 	}
 
-	p := strings.Index(elm.Arg, ".")
-	if p == -1 {
-		d.filePrefix = elm.Arg
-		d.fileExt = ""
-	} else {
-		d.filePrefix = elm.Arg[:p]
-		d.fileExt = elm.Arg[p+1:]
-	}
+	d.file.SetFileName(elm.Arg)
 
-	if d.format == "" {
+	if d.format == "" && (d.mode == LOG_MODE_TEXT || len(d.fieldDefs) == 0) {
 		return exception2.NewConfigException(
 			elm.FileName,
 			elm.LineNo,
 			baymessage.Get(symbol.CFG_INVALID_LOG_FORMAT, ""))
 	}
 
-	if !path.IsAbs(d.filePrefix) {
-		d.filePrefix = path.Join(bayserver.BservHome(), d.filePrefix)
+	// Parse format
+	lf := &logFormat{format: d.format}
+	if d.format != "" {
+		err = d.compile(d.format, lf, elm.FileName, elm.LineNo)
+		if err != nil {
+			return err
+		}
 	}
+	d.logItems = lf.items
 
-	logDir := path.Dir(d.filePrefix)
-	if !sysutil.IsDirectory(logDir) {
-		err := os.MkdirAll(logDir, 0755)
-		if err != nil {
-			baylog.ErrorE(exception.NewExceptionFromError(err), "")
-			return exception2.NewConfigException(
-				elm.FileName,
-				elm.LineNo,
-				"Cannot create directory")
+	if d.mode != LOG_MODE_TEXT {
+		for i, item := range lf.items {
+			if lf.names[i] != "" {
+				d.addField(lf.names[i], []logitems.LogItem{item})
+			}
 		}
 	}
 
-	// Parse format
-	d.logItems, err = d.compile(d.format, []logitems.LogItem{}, elm.FileName, elm.LineNo)
-	if err != nil {
-		return err
+	for _, kv := range d.fieldDefs {
+		err = d.compileField(kv)
+		if err != nil {
+			return err
+		}
 	}
 
-	agent.AddLifeCycleListener(NewLogAgentListener(d))
+	ferr := d.file.Open()
+	if ferr != nil {
+		baylog.ErrorE(ferr, "")
+		return exception2.NewConfigException(
+			elm.FileName,
+			elm.LineNo,
+			"Cannot create directory")
+	}
 
 	return nil
 }
@@ -213,10 +223,42 @@ func (d *BuiltInLogDocker) InitDocker(dkr docker.Docker) (bool, exception2.Confi
 func (d *BuiltInLogDocker) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.ConfigException) {
 	switch strings.ToLower(kv.Key) {
 	default:
+		if ok, err := d.file.InitKeyVal(kv); ok || err != nil {
+			return ok, err
+		}
 		return d.DockerBase.DefaultInitKeyVal(kv)
 
 	case "format":
 		d.format = kv.Value
+
+	case "mode":
+		switch strings.ToLower(kv.Value) {
+		case LOG_MODE_TEXT, LOG_MODE_JSON, LOG_MODE_LTSV:
+			d.mode = strings.ToLower(kv.Value)
+		default:
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "field":
+		d.fieldDefs = append(d.fieldDefs, kv)
+
+	case "logif", "logunless":
+		cond, err := parseLogCondition(kv)
+		if err != nil {
+			return false, err
+		}
+		if strings.ToLower(kv.Key) == "logif" {
+			d.ifConditions = append(d.ifConditions, cond)
+		} else {
+			d.unlessConditions = append(d.unlessConditions, cond)
+		}
+
+	case "sample":
+		rate, ok := parseSampleRate(kv.Value)
+		if !ok {
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+		d.sampleRate = rate
 	}
 	return true, nil
 }
@@ -226,7 +268,57 @@ func (d *BuiltInLogDocker) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.Confi
 /****************************************/
 
 func (d *BuiltInLogDocker) Log(tur tour.Tour) exception.IOException {
+	if !d.shouldLog(tur) {
+		return nil
+	}
+
+	var line string
+	switch d.mode {
+	case LOG_MODE_JSON:
+		line = d.jsonLine(tur)
+
+	case LOG_MODE_LTSV:
+		line = d.ltsvLine(tur)
+
+	default:
+		line = d.textLine(tur)
+	}
+
+	// If threre are message to write, write it
+	if len(line) > 0 {
+		bytes := []byte(line + "\n")
+		return d.file.Write(tur.Ship().(ship.Ship).AgentId(), bytes)
+	}
+
+	return nil
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
 
+// shouldLog checks conditions and sampling rate before rendering log items
+func (d *BuiltInLogDocker) shouldLog(tur tour.Tour) bool {
+	for _, c := range d.ifConditions {
+		if !c.match(tur) {
+			return false
+		}
+	}
+
+	for _, c := range d.unlessConditions {
+		if c.match(tur) {
+			return false
+		}
+	}
+
+	if d.sampleRate < 1.0 && rand.Float64() >= d.sampleRate {
+		return false
+	}
+
+	return true
+}
+
+func (d *BuiltInLogDocker) textLine(tur tour.Tour) string {
 	line := ""
 	for _, logItem := range d.logItems {
 		item := logItem.GetItem(tur)
@@ -236,58 +328,148 @@ func (d *BuiltInLogDocker) Log(tur tour.Tour) exception.IOException {
 			line += item
 		}
 	}
+	return line
+}
 
-	// If threre are message to write, write it
-	if len(line) > 0 {
-		bytes := []byte(line)
-		return d.multiplexers[tur.Ship().(ship.Ship).AgentId()-1].ReqWrite(
-			d.rudders[tur.Ship().(ship.Ship).AgentId()-1], bytes, nil, "log", nil)
+func (d *BuiltInLogDocker) jsonLine(tur tour.Tour) string {
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, f := range d.fields {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strutil.JsonQuote(f.name))
+		sb.WriteString(":")
+
+		value, isNumber := fieldValue(tur, f)
+		if value == "" || (len(f.items) == 1 && value == "-") {
+			sb.WriteString("null")
+		} else if isNumber {
+			sb.WriteString(value)
+		} else {
+			sb.WriteString(strutil.JsonQuote(value))
+		}
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (d *BuiltInLogDocker) ltsvLine(tur tour.Tour) string {
+	var sb strings.Builder
+	for i, f := range d.fields {
+		if i > 0 {
+			sb.WriteString("\t")
+		}
+		sb.WriteString(f.name)
+		sb.WriteString(":")
+
+		value, _ := fieldValue(tur, f)
+		if value == "" {
+			sb.WriteString("-")
+		} else {
+			sb.WriteString(ltsvEscape(value))
+		}
+	}
+	return sb.String()
+}
+
+/**
+ * Compile custom field definition: "field <name> <format>"
+ */
+func (d *BuiltInLogDocker) compileField(kv *bcf.BcfKeyVal) exception2.ConfigException {
+	if d.mode == LOG_MODE_TEXT {
+		return exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Key+" (only json or ltsv mode)"))
+	}
+
+	value := strings.TrimSpace(kv.Value)
+	pos := strings.IndexAny(value, " \t")
+	if pos <= 0 || !isValidFieldName(value[:pos]) {
+		return exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+	}
+
+	name := value[:pos]
+	format := strings.TrimSpace(value[pos+1:])
+	if len(format) >= 2 && format[0] == '"' && format[len(format)-1] == '"' {
+		format = format[1 : len(format)-1]
+	}
+	lf := &logFormat{format: format}
+	err := d.compile(format, lf, kv.FileName, kv.LineNo)
+	if err != nil {
+		return err
+	}
+
+	// Drop empty text items
+	items := make([]logitems.LogItem, 0, len(lf.items))
+	for i, item := range lf.items {
+		if lf.names[i] != "" || item.GetItem(nil) != "" {
+			items = append(items, item)
+		}
 	}
 
+	// Custom field overrides the field which has same name
+	for i := range d.fields {
+		if d.fields[i].name == name {
+			d.fields[i].items = items
+			return nil
+		}
+	}
+	d.fields = append(d.fields, logField{name, items})
 	return nil
 }
 
-/****************************************/
-/* Private functions                    */
-/****************************************/
+func (d *BuiltInLogDocker) addField(name string, items []logitems.LogItem) {
+	fieldName := name
+	for n := 2; ; n++ {
+		found := false
+		for _, f := range d.fields {
+			if f.name == fieldName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		fieldName = name + "_" + strconv.Itoa(n)
+	}
+	d.fields = append(d.fields, logField{fieldName, items})
+}
 
 /**
  * Compile format pattern
  */
-func (d *BuiltInLogDocker) compile(str string, items []logitems.LogItem, fileName string, lineNo int) ([]logitems.LogItem, exception2.ConfigException) {
+func (d *BuiltInLogDocker) compile(str string, lf *logFormat, fileName string, lineNo int) exception2.ConfigException {
 
 	// Find control code
 	pos := strings.Index(str, "%")
 
 	if pos != -1 {
 		text := str[0:pos]
-		items = append(items, logitems.NewTextItem(text))
-		var err exception2.ConfigException
-		items, err = d.compileCtl(str[pos+1:], items, fileName, lineNo)
-		if err != nil {
-			return nil, err
-		}
+		lf.items = append(lf.items, logitems.NewTextItem(text))
+		lf.names = append(lf.names, "")
+		return d.compileCtl(str[pos+1:], lf, fileName, lineNo)
 
 	} else {
-		items = append(items, logitems.NewTextItem(str))
+		lf.items = append(lf.items, logitems.NewTextItem(str))
+		lf.names = append(lf.names, "")
 	}
 
-	return items, nil
+	return nil
 }
 
 /**
  * Compile format pattern(Control code)
  */
-func (d *BuiltInLogDocker) compileCtl(str string, items []logitems.LogItem, fileName string, lineNo int) ([]logitems.LogItem, exception2.ConfigException) {
+func (d *BuiltInLogDocker) compileCtl(str string, lf *logFormat, fileName string, lineNo int) exception2.ConfigException {
 
 	param := ""
 
 	// if exists param
-	if str[0] == '{' {
+	if len(str) > 0 && str[0] == '{' {
 		// find close bracket
 		pos := strings.Index(str, "}")
 		if pos == -1 {
-			return nil, exception2.NewConfigException(fileName, lineNo, baymessage.Get(symbol.CFG_INVALID_LOG_FORMAT, d.format))
+			return exception2.NewConfigException(fileName, lineNo, baymessage.Get(symbol.CFG_INVALID_LOG_FORMAT, lf.format))
 		}
 		param = str[1:pos]
 		str = str[pos+1:]
@@ -325,14 +507,119 @@ func (d *BuiltInLogDocker) compileCtl(str string, items []logitems.LogItem, file
 	}
 
 	if hasError {
-		return nil, exception2.NewConfigException(
+		return exception2.NewConfigException(
 			fileName,
 			lineNo,
-			baymessage.Get(symbol.CFG_INVALID_LOG_FORMAT, d.format+" (unknown control code: '%"+ctlChar+"')"))
+			baymessage.Get(symbol.CFG_INVALID_LOG_FORMAT, lf.format+" (unknown control code: '%"+ctlChar+"')"))
 	}
 
 	item := fct()
 	item.Init(param)
-	items = append(items, item)
-	return d.compile(str, items, fileName, lineNo)
+	lf.items = append(lf.items, item)
+	lf.names = append(lf.names, itemName(ctlChar, param))
+	return d.compile(str, lf, fileName, lineNo)
+}
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+/**
+ * Default field name of the control code (Header names are added to the name. e.g. req_user_agent)
+ */
+func itemName(ctlChar string, param string) string {
+	name := logItemNameMap[ctlChar]
+	if name == "" {
+		name = ctlChar
+	}
+
+	if param != "" && ctlChar != "t" {
+		var sb strings.Builder
+		for _, c := range strings.ToLower(param) {
+			if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' {
+				sb.WriteRune(c)
+			} else {
+				sb.WriteRune('_')
+			}
+		}
+		name += "_" + sb.String()
+	}
+	return name
+}
+
+func isValidFieldName(name string) bool {
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '.' || c == '-') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+/**
+ * Returns the value of field and whether it is number
+ */
+func fieldValue(tur tour.Tour, f logField) (string, bool) {
+	if len(f.items) == 1 {
+		value := f.items[0].GetItem(tur)
+		if nitem, ok := f.items[0].(logitems.NumberItem); ok && nitem.IsNumber() {
+			_, err := strconv.ParseInt(value, 10, 64)
+			return value, err == nil
+		}
+		return value, false
+	}
+
+	var sb strings.Builder
+	for _, item := range f.items {
+		value := item.GetItem(tur)
+		if value == "" {
+			if _, isText := item.(*logitems.TextItem); !isText {
+				value = "-"
+			}
+		}
+		sb.WriteString(value)
+	}
+	return sb.String(), false
+}
+
+// parseSampleRate parses "0.1" or "10%"
+func parseSampleRate(str string) (float64, bool) {
+	str = strings.TrimSpace(str)
+	div := 1.0
+	if strings.HasSuffix(str, "%") {
+		str = str[:len(str)-1]
+		div = 100.0
+	}
+
+	rate, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false
+	}
+	rate /= div
+	if rate < 0 || rate > 1 {
+		return 0, false
+	}
+	return rate, true
+}
+
+func ltsvEscape(str string) string {
+	if !strings.ContainsAny(str, "\t\r\n\\") {
+		return str
+	}
+	var sb strings.Builder
+	for _, c := range str {
+		switch c {
+		case '\t':
+			sb.WriteString("\\t")
+		case '\r':
+			sb.WriteString("\\r")
+		case '\n':
+			sb.WriteString("\\n")
+		case '\\':
+			sb.WriteString("\\\\")
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
 }
@@ -223,9 +223,15 @@ func (t *BuiltInSecureDocker) initSSL() exception.IOException {
 		}
 	}
 
+	clientAuth := tls.NoClientCert
+	if t.clientAuth {
+		// Client certificate is required so that it can be passed to the backend (e.g. AJP ssl_cert)
+		clientAuth = tls.RequireAnyClientCert
+	}
+
 	t.config = &tls.Config{
 		Certificates:       []tls.Certificate{cert},
-		ClientAuth:         tls.NoClientCert,
+		ClientAuth:         clientAuth,
 		InsecureSkipVerify: true,
 	}
 
@@ -0,0 +1,386 @@
+package builtin
+
+import (
+	"bayserver-core/baykit/bayserver"
+	"bayserver-core/baykit/bayserver/agent"
+	bayserver2 "bayserver-core/baykit/bayserver/bayserver"
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/docker/base"
+	"bayserver-core/baykit/bayserver/ship"
+	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/tour"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	"bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/headers"
+	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const STATUS_FORMAT_HTML = "html"
+const STATUS_FORMAT_JSON = "json"
+
+// Agents which do not answer in this time are omitted from the status
+const SNAPSHOT_TIMEOUT_SEC = 5
+
+/**
+ * Club which shows the state of grand agents (like mod_status of Apache)
+ *   [town /server-status/]
+ *       [permission]
+ *           admit ip 127.0.0.1/32
+ *           refuse ip 0.0.0.0/0
+ *       [club *]
+ *           docker status
+ *           format html
+ *
+ * The format can be selected by client with "?format=json" or "Accept: application/json".
+ */
+type BuiltInStatusDocker struct {
+	*base.ClubBase
+
+	format string
+}
+
+func NewBuiltInStatusDocker() docker.Club {
+	d := &BuiltInStatusDocker{}
+	d.ClubBase = base.NewClubBase(d)
+	d.format = STATUS_FORMAT_HTML
+
+	var _ docker.Club = d // implement check
+	return d
+}
+
+func (d *BuiltInStatusDocker) String() string {
+	return "BuiltInStatusDocker"
+}
+
+/****************************************/
+/* Implements Docker                    */
+/****************************************/
+
+func (d *BuiltInStatusDocker) Init(elm *bcf.BcfElement, parent docker.Docker) exception2.ConfigException {
+	return d.ClubBase.Init(elm, parent)
+}
+
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (d *BuiltInStatusDocker) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.ConfigException) {
+	switch strings.ToLower(kv.Key) {
+	case "format":
+		switch strings.ToLower(kv.Value) {
+		case STATUS_FORMAT_HTML, STATUS_FORMAT_JSON:
+			d.format = strings.ToLower(kv.Value)
+
+		default:
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	default:
+		ok, cerr := d.ClubBase.InitKeyVal(kv)
+		if cerr != nil || ok {
+			return ok, cerr
+		}
+		return d.DefaultInitKeyVal(kv)
+	}
+	return true, nil
+}
+
+/****************************************/
+/* Implements Club                      */
+/****************************************/
+
+func (d *BuiltInStatusDocker) Arrive(tur tour.Tour) exception2.HttpException {
+	method := tur.Req().Method()
+	if method != "GET" && method != "HEAD" {
+		return exception2.NewHttpException(httpstatus.METHOD_NOT_ALLOWED, "%s", tur.Req().Uri())
+	}
+
+	tur.Req().SetReqContentHandler(NewStatusContentHandler(d))
+	return nil
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+// formatOf returns the format which client requests
+func (d *BuiltInStatusDocker) formatOf(tur tour.Tour) string {
+	if tur.Req().QueryString() != "" {
+		query, err := url.ParseQuery(tur.Req().QueryString())
+		if err == nil {
+			switch strings.ToLower(query.Get("format")) {
+			case STATUS_FORMAT_HTML:
+				return STATUS_FORMAT_HTML
+			case STATUS_FORMAT_JSON:
+				return STATUS_FORMAT_JSON
+			}
+		}
+	}
+
+	if strings.Contains(tur.Req().Headers().Get(headers.ACCEPT), "application/json") {
+		return STATUS_FORMAT_JSON
+	}
+	return d.format
+}
+
+// takeSnapshots waits for snapshots of all agents. (Must not be called in an agent loop)
+func (d *BuiltInStatusDocker) takeSnapshots() []*agent.AgentSnapshot {
+	chs := []<-chan *agent.AgentSnapshot{}
+	for _, agt := range agent.Agents() {
+		chs = append(chs, agt.Snapshot())
+	}
+
+	snaps := []*agent.AgentSnapshot{}
+	timeout := time.After(SNAPSHOT_TIMEOUT_SEC * time.Second)
+	for _, ch := range chs {
+		select {
+		case snap := <-ch:
+			snaps = append(snaps, snap)
+		case <-timeout:
+			baylog.Warn("%s Cannot get snapshot of agent (timed out)", d)
+			return snaps
+		}
+	}
+	return snaps
+}
+
+func (d *BuiltInStatusDocker) renderHtml(snaps []*agent.AgentSnapshot) string {
+	var sb strings.Builder
+	now := time.Now()
+	startTime := bayserver2.StartTime()
+
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>BayServer Status</title>\n")
+	sb.WriteString("<style>table{border-collapse:collapse}th,td{border:1px solid #999;padding:2px 6px;text-align:left}</style>\n")
+	sb.WriteString("</head>\n<body>\n<h1>BayServer Status</h1>\n")
+	sb.WriteString("<dl>\n")
+	sb.WriteString("<dt>Version</dt><dd>" + html.EscapeString(bayserver.VERSION) + "</dd>\n")
+	sb.WriteString("<dt>Current time</dt><dd>" + now.Format(time.RFC1123) + "</dd>\n")
+	sb.WriteString("<dt>Started</dt><dd>" + startTime.Format(time.RFC1123) + "</dd>\n")
+	sb.WriteString("<dt>Uptime</dt><dd>" + now.Sub(startTime).Truncate(time.Second).String() + "</dd>\n")
+	sb.WriteString("<dt>Grand agents</dt><dd>" + strconv.Itoa(len(snaps)) + "</dd>\n")
+	sb.WriteString("</dl>\n")
+
+	for _, snap := range snaps {
+		sb.WriteString(fmt.Sprintf("<h2>agt#%d</h2>\n", snap.AgentId))
+		sb.WriteString(fmt.Sprintf("<p>%d ships, %d keep-alive</p>\n", len(snap.Ships), snap.KeepAliveShips))
+
+		sb.WriteString("<table>\n<tr><th>Ship</th><th>Remote address</th><th>Protocol</th><th>State</th><th>Tours</th><th>Method</th><th>URI</th><th>Elapsed</th></tr>\n")
+		for _, sip := range snap.Ships {
+			state := "active"
+			if len(sip.Tours) == 0 {
+				if sip.Keeping {
+					state = "keep-alive"
+				} else {
+					state = "reading"
+				}
+			}
+			cols := fmt.Sprintf("<td>%d</td><td>%s</td><td>%s</td><td>%s</td><td>%d</td>",
+				sip.ShipId, html.EscapeString(sip.RemoteAddress), html.EscapeString(sip.Protocol), state, sip.TourCount)
+			if len(sip.Tours) == 0 {
+				sb.WriteString("<tr>" + cols + "<td></td><td></td><td></td></tr>\n")
+			}
+			for _, tur := range sip.Tours {
+				sb.WriteString(fmt.Sprintf("<tr>%s<td>%s</td><td>%s</td><td>%.3fs</td></tr>\n",
+					cols, html.EscapeString(tur.Method), html.EscapeString(tur.Uri), tur.Elapsed.Seconds()))
+			}
+		}
+		sb.WriteString("</table>\n")
+
+		sb.WriteString("<h3>Object stores</h3>\n<table>\n<tr><th>Store</th><th>Active</th><th>Free</th></tr>\n")
+		for _, st := range snap.Stores {
+			sb.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%d</td><td>%d</td></tr>\n", html.EscapeString(st.Name), st.Active, st.Free))
+		}
+		sb.WriteString("</table>\n")
+
+		sb.WriteString("<h3>Warp pools</h3>\n<table>\n<tr><th>Destination</th><th>Clubs</th><th>Idle</th><th>Busy</th></tr>\n")
+		for _, pool := range warpPools(snap) {
+			sb.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%d</td><td>%d</td></tr>\n",
+				html.EscapeString(pool.destination), html.EscapeString(strings.Join(pool.clubs, ", ")), pool.idle, pool.busy))
+		}
+		sb.WriteString("</table>\n")
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+func (d *BuiltInStatusDocker) renderJson(snaps []*agent.AgentSnapshot) string {
+	var sb strings.Builder
+	now := time.Now()
+	startTime := bayserver2.StartTime()
+
+	sb.WriteString("{\"version\":" + strutil.JsonQuote(bayserver.VERSION))
+	sb.WriteString(",\"currentTime\":" + strutil.JsonQuote(now.Format(time.RFC3339)))
+	sb.WriteString(",\"startTime\":" + strutil.JsonQuote(startTime.Format(time.RFC3339)))
+	sb.WriteString(",\"uptimeSeconds\":" + strconv.FormatInt(int64(now.Sub(startTime).Seconds()), 10))
+	sb.WriteString(",\"agents\":[")
+	for i, snap := range snaps {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("{\"agentId\":" + strconv.Itoa(snap.AgentId))
+		sb.WriteString(",\"keepAliveShips\":" + strconv.Itoa(snap.KeepAliveShips))
+
+		sb.WriteString(",\"ships\":[")
+		for j, sip := range snap.Ships {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("{\"shipId\":" + strconv.Itoa(sip.ShipId))
+			sb.WriteString(",\"remoteAddress\":" + strutil.JsonQuote(sip.RemoteAddress))
+			sb.WriteString(",\"protocol\":" + strutil.JsonQuote(sip.Protocol))
+			sb.WriteString(",\"keeping\":" + strconv.FormatBool(sip.Keeping))
+			sb.WriteString(",\"tourCount\":" + strconv.Itoa(sip.TourCount))
+			sb.WriteString(",\"tours\":[")
+			for k, tur := range sip.Tours {
+				if k > 0 {
+					sb.WriteString(",")
+				}
+				sb.WriteString("{\"tourId\":" + strconv.Itoa(tur.TourId))
+				sb.WriteString(",\"method\":" + strutil.JsonQuote(tur.Method))
+				sb.WriteString(",\"uri\":" + strutil.JsonQuote(tur.Uri))
+				sb.WriteString(",\"elapsedSeconds\":" + strconv.FormatFloat(tur.Elapsed.Seconds(), 'f', 3, 64) + "}")
+			}
+			sb.WriteString("]}")
+		}
+		sb.WriteString("]")
+
+		sb.WriteString(",\"stores\":[")
+		for j, st := range snap.Stores {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(fmt.Sprintf("{\"name\":%s,\"active\":%d,\"free\":%d}", strutil.JsonQuote(st.Name), st.Active, st.Free))
+		}
+		sb.WriteString("]")
+
+		sb.WriteString(",\"warpPools\":[")
+		for j, pool := range warpPools(snap) {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			clubs := make([]string, len(pool.clubs))
+			for k, club := range pool.clubs {
+				clubs[k] = strutil.JsonQuote(club)
+			}
+			sb.WriteString(fmt.Sprintf("{\"destination\":%s,\"clubs\":[%s],\"idle\":%d,\"busy\":%d}",
+				strutil.JsonQuote(pool.destination), strings.Join(clubs, ","), pool.idle, pool.busy))
+		}
+		sb.WriteString("]}")
+	}
+	sb.WriteString("]}\n")
+	return sb.String()
+}
+
+/****************************************/
+/* type warpPool                        */
+/****************************************/
+
+// warpPool is the sum of warp ships which connect to the same destination
+type warpPool struct {
+	destination string
+	clubs       []string
+	idle        int
+	busy        int
+}
+
+func warpPools(snap *agent.AgentSnapshot) []*warpPool {
+	pools := []*warpPool{}
+	poolMap := map[string]*warpPool{}
+	for _, wu := range snap.Warps {
+		pool, ok := poolMap[wu.Destination]
+		if !ok {
+			pool = &warpPool{destination: wu.Destination}
+			poolMap[wu.Destination] = pool
+			pools = append(pools, pool)
+		}
+		pool.clubs = append(pool.clubs, wu.Club)
+		pool.idle += wu.Idle
+		pool.busy += wu.Busy
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].destination < pools[j].destination })
+	return pools
+}
+
+/****************************************/
+/* type StatusContentHandler            */
+/****************************************/
+
+type StatusContentHandler struct {
+	docker *BuiltInStatusDocker
+}
+
+func NewStatusContentHandler(dkr *BuiltInStatusDocker) *StatusContentHandler {
+	h := &StatusContentHandler{
+		docker: dkr,
+	}
+
+	var _ tour.ReqContentHandler = h // implement check
+	return h
+}
+
+/****************************************/
+/* Implements ReqContentHandler         */
+/****************************************/
+
+func (h *StatusContentHandler) OnReadReqContent(tur tour.Tour, buf []byte, start int, length int, lis tour.ContentConsumeListener) exception.IOException {
+	tur.Req().Consumed(tur.TourId(), length, lis)
+	return nil
+}
+
+func (h *StatusContentHandler) OnEndReqContent(tur tour.Tour) (exception.IOException, exception2.HttpException) {
+	agt := agent.Get(tur.Ship().(ship.Ship).AgentId())
+	tourId := tur.TourId()
+
+	// Agents take snapshots in their own loops. Wait for them in another goroutine
+	// so as not to block this agent, and send the response in the agent of the tour.
+	go func() {
+		defer func() {
+			bayserver2.BDefer()
+		}()
+
+		snaps := h.docker.takeSnapshots()
+		agt.SendRunLetter(func() {
+			if !tur.IsInitialized() || tur.TourId() != tourId || tur.IsAborted() {
+				baylog.Debug("%s tour is gone while taking snapshots: id=%d", tur, tourId)
+				return
+			}
+
+			ioerr := h.sendStatus(tur, snaps)
+			if ioerr != nil {
+				baylog.DebugE(ioerr, "%s Cannot send status", tur)
+			}
+		}, true)
+	}()
+	return nil, nil
+}
+
+func (h *StatusContentHandler) OnAbortReq(tur tour.Tour) bool {
+	return false
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (h *StatusContentHandler) sendStatus(tur tour.Tour, snaps []*agent.AgentSnapshot) exception.IOException {
+	tur.Res().Headers().Set("Cache-Control", "no-cache")
+
+	if h.docker.formatOf(tur) == STATUS_FORMAT_JSON {
+		return base.SendText(tur, "application/json; charset=utf-8", h.docker.renderJson(snaps))
+
+	} else {
+		return base.SendText(tur, "text/html; charset=utf-8", h.docker.renderHtml(snaps))
+	}
+}
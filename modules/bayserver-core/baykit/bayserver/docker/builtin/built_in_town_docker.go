@@ -24,6 +24,7 @@ type BuiltInTownDocker struct {
 	rerouteList    []docker.Reroute
 	city           docker.City
 	name           string
+	earlyHints     []string
 }
 
 func NewBuiltInTownDocker() docker.Town {
@@ -36,6 +37,7 @@ func NewBuiltInTownDocker() docker.Town {
 	t.rerouteList = []docker.Reroute{}
 	t.city = nil
 	t.name = ""
+	t.earlyHints = []string{}
 	return &t
 }
 
@@ -101,6 +103,9 @@ func (t *BuiltInTownDocker) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.Conf
 
 	case "index", "welcome":
 		t.welcome = kv.Value
+
+	case "earlyhint", "earlyhints":
+		t.earlyHints = append(t.earlyHints, kv.Value)
 	}
 	return true, nil
 }
@@ -137,6 +142,10 @@ func (t *BuiltInTownDocker) Reroute(uri string) string {
 	return uri
 }
 
+func (t *BuiltInTownDocker) EarlyHints() []string {
+	return t.earlyHints
+}
+
 func (t *BuiltInTownDocker) Matches(uri string) int {
 	if strings.HasPrefix(uri, t.name) {
 		return docker.MATCH_TYPE_MATCHED
@@ -147,12 +156,6 @@ func (t *BuiltInTownDocker) Matches(uri string) int {
 	}
 }
 
-func (t *BuiltInTownDocker) CheckAdmitted(tur tour.Tour) exception2.HttpException {
-	for _, p := range t.permissionList {
-		hterr := p.TourAdmitted(tur)
-		if hterr != nil {
-			return hterr
-		}
-	}
-	return nil
+func (t *BuiltInTownDocker) CheckAdmitted(tur tour.Tour, next func() exception2.HttpException) exception2.HttpException {
+	return checkPermissions(tur, t.permissionList, next)
 }
@@ -0,0 +1,163 @@
+package builtin
+
+import (
+	bayserver2 "bayserver-core/baykit/bayserver"
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/tracing"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/docker/base"
+	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const DEFAULT_SERVICE_NAME = "bayserver"
+
+/**
+ * Tracer which exports spans to OpenTelemetry collector (OTLP/HTTP)
+ *   [harbor]
+ *       [tracer]
+ *           endpoint http://127.0.0.1:4318/v1/traces
+ *           sampleRate 0.1
+ */
+type BuiltInTracerDocker struct {
+	*base.DockerBase
+
+	endpoint         string
+	serviceName      string
+	sampleRate       float64
+	parentBased      bool
+	queueSize        int
+	batchSize        int
+	flushIntervalSec int
+	timeoutSec       int
+
+	exporter     *tracing.OtlpExporter
+	exporterOnce sync.Once
+}
+
+func NewBuiltInTracerDocker() docker.Tracer {
+	t := &BuiltInTracerDocker{
+		endpoint:    tracing.DEFAULT_OTLP_ENDPOINT,
+		serviceName: DEFAULT_SERVICE_NAME,
+		sampleRate:  1.0,
+		parentBased: true,
+	}
+	t.DockerBase = base.NewDockerBase(t)
+
+	// interface check
+	var _ docker.Docker = t
+	var _ docker.Tracer = t
+	return t
+}
+
+func (t *BuiltInTracerDocker) String() string {
+	return "BuiltInTracerDocker"
+}
+
+/****************************************/
+/* Implements Docker                    */
+/****************************************/
+
+func (t *BuiltInTracerDocker) Init(elm *bcf.BcfElement, parent docker.Docker) exception2.ConfigException {
+	return t.DockerBase.Init(elm, parent)
+}
+
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (t *BuiltInTracerDocker) InitDocker(dkr docker.Docker) (bool, exception2.ConfigException) {
+	return t.DockerBase.DefaultInitDocker()
+}
+
+func (t *BuiltInTracerDocker) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.ConfigException) {
+	var err error = nil
+	switch strings.ToLower(kv.Key) {
+	default:
+		return t.DefaultInitKeyVal(kv)
+
+	case "endpoint":
+		t.endpoint = kv.Value
+
+	case "servicename":
+		t.serviceName = kv.Value
+
+	case "samplerate":
+		t.sampleRate, err = strconv.ParseFloat(kv.Value, 64)
+		if err == nil && (t.sampleRate < 0 || t.sampleRate > 1) {
+			err = strconv.ErrRange
+		}
+
+	case "parentbased":
+		t.parentBased, err = strutil.ParseBool(kv.Value)
+
+	case "queuesize":
+		t.queueSize, err = strconv.Atoi(kv.Value)
+
+	case "batchsize":
+		t.batchSize, err = strconv.Atoi(kv.Value)
+
+	case "flushinterval":
+		t.flushIntervalSec, err = strconv.Atoi(kv.Value)
+
+	case "timeout":
+		t.timeoutSec, err = strconv.Atoi(kv.Value)
+	}
+
+	if err != nil {
+		return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+	}
+	return true, nil
+}
+
+/****************************************/
+/* Implements Tracer                    */
+/****************************************/
+
+func (t *BuiltInTracerDocker) Sample(traceId [16]byte, parent *tracing.TraceContext) bool {
+	if parent != nil && t.parentBased {
+		return parent.Sampled()
+	}
+
+	if t.sampleRate >= 1 {
+		return true
+	} else if t.sampleRate <= 0 {
+		return false
+	}
+
+	// Decide by trace ID so that all servers in the trace make the same decision
+	return float64(binary.BigEndian.Uint64(traceId[8:16])>>11) < t.sampleRate*(1<<53)
+}
+
+func (t *BuiltInTracerDocker) Export(span *tracing.Span) {
+	t.exporterOnce.Do(func() {
+		// Started lazily not to run the exporter in processes which serve no requests
+		t.exporter = tracing.NewOtlpExporter(
+			t.endpoint,
+			t.serviceName,
+			bayserver2.VERSION,
+			t.queueSize,
+			t.batchSize,
+			t.flushIntervalSec,
+			t.timeoutSec)
+	})
+	if t.exporter == nil {
+		// Already closed
+		return
+	}
+	t.exporter.Export(span)
+}
+
+func (t *BuiltInTracerDocker) Close() {
+	// Prevent the exporter from being started after this
+	t.exporterOnce.Do(func() {})
+	if t.exporter != nil {
+		t.exporter.Close()
+	}
+}
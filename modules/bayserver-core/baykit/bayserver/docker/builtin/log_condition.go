@@ -0,0 +1,221 @@
+package builtin
+
+import (
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/tour"
+	"bayserver-core/baykit/bayserver/util"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"net"
+	"regexp"
+	"strings"
+)
+
+/****************************************/
+/* type LogCondition                    */
+/****************************************/
+
+/**
+ * Condition to select tours to be logged
+ *   logIf <type> <value> ...      Log only if the tour matches one of values
+ *   logUnless <type> <value> ...  Do not log if the tour matches one of values
+ * Types:
+ *   status 200 3xx 400-499
+ *   method GET HEAD
+ *   uri <regexp> ...
+ *   header <request header name> ...
+ *   ip 127.0.0.1 192.168.0.0/16
+ */
+type LogCondition interface {
+	match(tur tour.Tour) bool
+}
+
+/****************************************/
+/* type StatusLogCondition              */
+/****************************************/
+
+type statusRange struct {
+	from int
+	to   int
+}
+
+type StatusLogCondition struct {
+	ranges []statusRange
+}
+
+func (c *StatusLogCondition) match(tur tour.Tour) bool {
+	status := tur.Res().Headers().Status()
+	for _, r := range c.ranges {
+		if status >= r.from && status <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+/****************************************/
+/* type MethodLogCondition              */
+/****************************************/
+
+type MethodLogCondition struct {
+	methods []string
+}
+
+func (c *MethodLogCondition) match(tur tour.Tour) bool {
+	for _, m := range c.methods {
+		if strings.EqualFold(m, tur.Req().Method()) {
+			return true
+		}
+	}
+	return false
+}
+
+/****************************************/
+/* type UriLogCondition                 */
+/****************************************/
+
+type UriLogCondition struct {
+	patterns []*regexp.Regexp
+}
+
+func (c *UriLogCondition) match(tur tour.Tour) bool {
+	for _, p := range c.patterns {
+		if p.MatchString(tur.Req().Uri()) {
+			return true
+		}
+	}
+	return false
+}
+
+/****************************************/
+/* type HeaderLogCondition              */
+/****************************************/
+
+type HeaderLogCondition struct {
+	names []string
+}
+
+func (c *HeaderLogCondition) match(tur tour.Tour) bool {
+	for _, name := range c.names {
+		if tur.Req().Headers().Contains(name) {
+			return true
+		}
+	}
+	return false
+}
+
+/****************************************/
+/* type IpLogCondition                  */
+/****************************************/
+
+type IpLogCondition struct {
+	matchers []*util.IpMatcher
+}
+
+func (c *IpLogCondition) match(tur tour.Tour) bool {
+	ip := net.ParseIP(tur.Req().RemoteAddress())
+	if ip == nil {
+		return false
+	}
+	for _, m := range c.matchers {
+		if m.Match(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func parseLogCondition(kv *bcf.BcfKeyVal) (LogCondition, exception2.ConfigException) {
+	invalid := exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+
+	tokens := strings.Fields(kv.Value)
+	if len(tokens) < 2 {
+		return nil, invalid
+	}
+	values := tokens[1:]
+
+	switch strings.ToLower(tokens[0]) {
+	case "status":
+		c := &StatusLogCondition{}
+		for _, v := range values {
+			r, ok := parseStatusRange(v)
+			if !ok {
+				return nil, invalid
+			}
+			c.ranges = append(c.ranges, r)
+		}
+		return c, nil
+
+	case "method":
+		return &MethodLogCondition{methods: values}, nil
+
+	case "uri":
+		c := &UriLogCondition{}
+		for _, v := range values {
+			p, err := regexp.Compile(v)
+			if err != nil {
+				return nil, invalid
+			}
+			c.patterns = append(c.patterns, p)
+		}
+		return c, nil
+
+	case "header":
+		return &HeaderLogCondition{names: values}, nil
+
+	case "ip":
+		c := &IpLogCondition{}
+		for _, v := range values {
+			if v != "*" && !strings.Contains(v, "/") {
+				// Single address
+				if strings.Contains(v, ":") {
+					v += "/128"
+				} else {
+					v += "/32"
+				}
+			}
+			m, ioerr := util.NewIpMatcher(v)
+			if ioerr != nil {
+				return nil, invalid
+			}
+			c.matchers = append(c.matchers, m)
+		}
+		return c, nil
+
+	default:
+		return nil, invalid
+	}
+}
+
+// parseStatusRange parses "404", "4xx" or "400-499"
+func parseStatusRange(str string) (statusRange, bool) {
+	lower := strings.ToLower(str)
+	if len(lower) == 3 && strings.HasSuffix(lower, "xx") && lower[0] >= '1' && lower[0] <= '5' {
+		from := int(lower[0]-'0') * 100
+		return statusRange{from, from + 99}, true
+	}
+
+	var fromStr, toStr string
+	pos := strings.Index(lower, "-")
+	if pos < 0 {
+		fromStr, toStr = lower, lower
+	} else {
+		fromStr, toStr = lower[:pos], lower[pos+1:]
+	}
+
+	from, err := strutil.ParseInt(fromStr)
+	if err != nil {
+		return statusRange{}, false
+	}
+	to, err := strutil.ParseInt(toStr)
+	if err != nil || to < from {
+		return statusRange{}, false
+	}
+	return statusRange{from, to}, true
+}
@@ -16,3 +16,10 @@ type LogItem interface {
 
 	GetItem(tour tour.Tour) string
 }
+
+/**
+ * Log item which prints number (Printed as number in json mode)
+ */
+type NumberItem interface {
+	IsNumber() bool
+}
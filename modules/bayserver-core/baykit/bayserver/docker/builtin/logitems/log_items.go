@@ -1,7 +1,13 @@
 package logitems
 
 import (
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/ship"
 	"bayserver-core/baykit/bayserver/tour"
+	"bayserver-core/baykit/bayserver/util/headers"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -99,63 +105,147 @@ func (s ServerIpItem) GetItem(tour tour.Tour) string {
 }
 
 /****************************************/
-/* RequestBytesItem1                    */
+/* ResponseBytesItem1                   */
 /****************************************/
 
 /**
- * Return number of bytes that is sent from clients (Except HTTP headers)
+ * Return number of bytes that is sent to clients (Except HTTP headers)
  * (%B)
  */
 
-type RequestBytesItem1 struct {
+type ResponseBytesItem1 struct {
 }
 
-func NewRequestBytesItem1() LogItem {
-	return &RequestBytesItem1{}
+func NewResponseBytesItem1() LogItem {
+	return &ResponseBytesItem1{}
 }
 
-func (r RequestBytesItem1) Init(param string) {
+func (r ResponseBytesItem1) Init(param string) {
 
 }
 
-func (r RequestBytesItem1) GetItem(tour tour.Tour) string {
-	bytes := tour.Req().Headers().ContentLength()
-	if bytes < 0 {
-		bytes = 0
-	}
+func (r ResponseBytesItem1) IsNumber() bool {
+	return true
+}
 
-	return strconv.Itoa(bytes)
+func (r ResponseBytesItem1) GetItem(tour tour.Tour) string {
+	return strconv.Itoa(tour.Res().ContentBytesSent())
 }
 
 /****************************************/
-/* RequestBytesItem2                    */
+/* ResponseBytesItem2                   */
 /****************************************/
 
 /**
- * Return number of bytes that is sent from clients in CLF format (Except
+ * Return number of bytes that is sent to clients in CLF format (Except
  * HTTP headers) (%b)
  */
 
-type RequestBytesItem2 struct {
+type ResponseBytesItem2 struct {
 }
 
-func NewRequestBytesItem2() LogItem {
-	return &RequestBytesItem2{}
+func NewResponseBytesItem2() LogItem {
+	return &ResponseBytesItem2{}
 }
 
-func (r RequestBytesItem2) Init(param string) {
+func (r ResponseBytesItem2) Init(param string) {
 
 }
 
-func (r RequestBytesItem2) GetItem(tour tour.Tour) string {
-	bytes := tour.Req().Headers().ContentLength()
-	if bytes < 0 {
+func (r ResponseBytesItem2) IsNumber() bool {
+	return true
+}
+
+func (r ResponseBytesItem2) GetItem(tour tour.Tour) string {
+	bytes := tour.Res().ContentBytesSent()
+	if bytes == 0 {
 		return "-"
 	}
 
 	return strconv.Itoa(bytes)
 }
 
+/****************************************/
+/* BytesReceivedItem                    */
+/****************************************/
+
+/**
+ * Return number of bytes received from clients including headers (%I)
+ */
+
+type BytesReceivedItem struct {
+}
+
+func NewBytesReceivedItem() LogItem {
+	return &BytesReceivedItem{}
+}
+
+func (b BytesReceivedItem) Init(param string) {
+
+}
+
+func (b BytesReceivedItem) IsNumber() bool {
+	return true
+}
+
+func (b BytesReceivedItem) GetItem(tour tour.Tour) string {
+	return strconv.Itoa(tour.Req().BytesReceived())
+}
+
+/****************************************/
+/* BytesSentItem                        */
+/****************************************/
+
+/**
+ * Return number of bytes sent to clients including headers (%O)
+ */
+
+type BytesSentItem struct {
+}
+
+func NewBytesSentItem() LogItem {
+	return &BytesSentItem{}
+}
+
+func (b BytesSentItem) Init(param string) {
+
+}
+
+func (b BytesSentItem) IsNumber() bool {
+	return true
+}
+
+func (b BytesSentItem) GetItem(tour tour.Tour) string {
+	return strconv.Itoa(tour.Res().BytesSent())
+}
+
+/****************************************/
+/* BytesTransferredItem                 */
+/****************************************/
+
+/**
+ * Return number of bytes transferred (received and sent) including headers (%S)
+ */
+
+type BytesTransferredItem struct {
+}
+
+func NewBytesTransferredItem() LogItem {
+	return &BytesTransferredItem{}
+}
+
+func (b BytesTransferredItem) Init(param string) {
+
+}
+
+func (b BytesTransferredItem) IsNumber() bool {
+	return true
+}
+
+func (b BytesTransferredItem) GetItem(tour tour.Tour) string {
+	return strconv.Itoa(tour.Req().BytesReceived() + tour.Res().BytesSent())
+}
+
 /****************************************/
 /* ConnectionStatusItem                 */
 /****************************************/
@@ -280,11 +370,11 @@ func NewRequestHeaderItem() LogItem {
 	return &RequestHeaderItem{}
 }
 
-func (r RequestHeaderItem) Init(param string) {
+func (r *RequestHeaderItem) Init(param string) {
 	r.name = param
 }
 
-func (r RequestHeaderItem) GetItem(tour tour.Tour) string {
+func (r *RequestHeaderItem) GetItem(tour tour.Tour) string {
 	return tour.Req().Headers().Get(r.name)
 }
 
@@ -326,11 +416,11 @@ func NewResponseHeaderItem() LogItem {
 	return &ResponseHeaderItem{}
 }
 
-func (r ResponseHeaderItem) Init(param string) {
+func (r *ResponseHeaderItem) Init(param string) {
 	r.name = param
 }
 
-func (r ResponseHeaderItem) GetItem(tour tour.Tour) string {
+func (r *ResponseHeaderItem) GetItem(tour tour.Tour) string {
 	return tour.Res().Headers().Get(r.name)
 }
 
@@ -353,6 +443,10 @@ func (p PortItem) Init(param string) {
 
 }
 
+func (p PortItem) IsNumber() bool {
+	return true
+}
+
 func (p PortItem) GetItem(tour tour.Tour) string {
 	return strconv.Itoa(tour.Req().ServerPort())
 }
@@ -423,6 +517,10 @@ func (s StatusItem) Init(param string) {
 
 }
 
+func (s StatusItem) IsNumber() bool {
+	return true
+}
+
 func (s StatusItem) GetItem(tour tour.Tour) string {
 	return strconv.Itoa(tour.Res().Headers().Status())
 }
@@ -431,22 +529,55 @@ func (s StatusItem) GetItem(tour tour.Tour) string {
 /* TimeItem                             */
 /****************************************/
 
+/**
+ * Return the time the request was received (%t, %{format}t)
+ * The format is strftime style or one of sec, msec, usec, msec_frac and usec_frac.
+ * The format can be prefixed with "begin:" (default) or "end:".
+ */
+
 type TimeItem struct {
-	layout string
+	format string
+	end    bool
 }
 
 func NewTimeItem() LogItem {
-	return &TimeItem{
-		layout: "01/Jan/2000 23:59:59 -0900",
-	}
+	return &TimeItem{}
 }
 
-func (t TimeItem) Init(param string) {
-	t.layout = param
+func (t *TimeItem) Init(param string) {
+	if strings.HasPrefix(param, "begin:") {
+		param = param[6:]
+	} else if strings.HasPrefix(param, "end:") {
+		param = param[4:]
+		t.end = true
+	}
+	t.format = param
 }
 
-func (t TimeItem) GetItem(tour tour.Tour) string {
-	return time.Now().Format(t.layout)
+func (t *TimeItem) GetItem(tour tour.Tour) string {
+	var tm time.Time
+	if t.end || tour.StartTime().IsZero() {
+		tm = time.Now()
+	} else {
+		tm = tour.StartTime()
+	}
+
+	switch t.format {
+	case "":
+		return tm.Format("[02/Jan/2006:15:04:05 -0700]")
+	case "sec":
+		return strconv.FormatInt(tm.Unix(), 10)
+	case "msec":
+		return strconv.FormatInt(tm.UnixMilli(), 10)
+	case "usec":
+		return strconv.FormatInt(tm.UnixMicro(), 10)
+	case "msec_frac":
+		return fmt.Sprintf("%03d", tm.Nanosecond()/1000000)
+	case "usec_frac":
+		return fmt.Sprintf("%06d", tm.Nanosecond()/1000)
+	default:
+		return strutil.Strftime(t.format, tm)
+	}
 }
 
 /****************************************/
@@ -454,22 +585,63 @@ func (t TimeItem) GetItem(tour tour.Tour) string {
 /****************************************/
 
 /**
- * Return how long request took (%T)
+ * Return how long request took (%T, %{UNIT}T)
+ * UNIT is one of s (default), ms and us.
  */
 
 type IntervalItem struct {
+	unit string
 }
 
 func NewIntervalItem() LogItem {
 	return &IntervalItem{}
 }
 
-func (i IntervalItem) Init(param string) {
+func (i *IntervalItem) Init(param string) {
+	i.unit = param
+}
+
+func (i *IntervalItem) IsNumber() bool {
+	return true
+}
+
+func (i *IntervalItem) GetItem(tour tour.Tour) string {
+	dur := elapsed(tour)
+	switch i.unit {
+	case "ms":
+		return strconv.FormatInt(dur.Milliseconds(), 10)
+	case "us":
+		return strconv.FormatInt(dur.Microseconds(), 10)
+	default:
+		return strconv.FormatInt(int64(dur/time.Second), 10)
+	}
+}
+
+/****************************************/
+/* DurationItem                         */
+/****************************************/
+
+/**
+ * Return how long request took in microseconds (%D)
+ */
+
+type DurationItem struct {
+}
+
+func NewDurationItem() LogItem {
+	return &DurationItem{}
+}
 
+func (d DurationItem) Init(param string) {
+
+}
+
+func (d DurationItem) IsNumber() bool {
+	return true
 }
 
-func (i IntervalItem) GetItem(tour tour.Tour) string {
-	return strconv.Itoa(tour.Interval() / 1000)
+func (d DurationItem) GetItem(tour tour.Tour) string {
+	return strconv.FormatInt(elapsed(tour).Microseconds(), 10)
 }
 
 /****************************************/
@@ -541,3 +713,191 @@ func (s ServerNameItem) Init(param string) {
 func (s ServerNameItem) GetItem(tour tour.Tour) string {
 	return tour.Req().ServerName()
 }
+
+/****************************************/
+/* CanonicalServerNameItem              */
+/****************************************/
+
+/**
+ * Return canonical server name (%V)
+ * (Name of the city, or server name if the city is wildcard)
+ */
+
+type CanonicalServerNameItem struct {
+}
+
+func NewCanonicalServerNameItem() LogItem {
+	return &CanonicalServerNameItem{}
+}
+
+func (c CanonicalServerNameItem) Init(param string) {
+
+}
+
+func (c CanonicalServerNameItem) GetItem(tour tour.Tour) string {
+	if city, ok := tour.City().(docker.City); ok && city != nil {
+		name := city.Name()
+		if name != "" && !strings.Contains(name, "*") {
+			return name
+		}
+	}
+	return tour.Req().ServerName()
+}
+
+/****************************************/
+/* CookieItem                           */
+/****************************************/
+
+/**
+ * Return cookie value (%{Foobar}C)
+ */
+
+type CookieItem struct {
+	name string
+}
+
+func NewCookieItem() LogItem {
+	return &CookieItem{}
+}
+
+func (c *CookieItem) Init(param string) {
+	c.name = param
+}
+
+func (c *CookieItem) GetItem(tour tour.Tour) string {
+	for _, cookies := range tour.Req().Headers().HeaderValues(headers.COOKIE) {
+		for _, cookie := range strings.Split(cookies, ";") {
+			pos := strings.Index(cookie, "=")
+			if pos == -1 {
+				continue
+			}
+			if strings.TrimSpace(cookie[:pos]) == c.name {
+				return strings.TrimSpace(cookie[pos+1:])
+			}
+		}
+	}
+	return ""
+}
+
+/****************************************/
+/* EnvItem                              */
+/****************************************/
+
+/**
+ * Return environment variable (%{FOOBAR}e)
+ */
+
+type EnvItem struct {
+	name string
+}
+
+func NewEnvItem() LogItem {
+	return &EnvItem{}
+}
+
+func (e *EnvItem) Init(param string) {
+	e.name = param
+}
+
+func (e *EnvItem) GetItem(tour tour.Tour) string {
+	return os.Getenv(e.name)
+}
+
+/****************************************/
+/* NoteItem                             */
+/****************************************/
+
+/**
+ * Return request variable set by other dockers (%{Foobar}n)
+ */
+
+type NoteItem struct {
+	name string
+}
+
+func NewNoteItem() LogItem {
+	return &NoteItem{}
+}
+
+func (n *NoteItem) Init(param string) {
+	n.name = param
+}
+
+func (n *NoteItem) GetItem(tour tour.Tour) string {
+	for _, nv := range tour.Req().Variables() {
+		if len(nv) >= 2 && nv[0] == n.name {
+			return nv[1]
+		}
+	}
+	return ""
+}
+
+/****************************************/
+/* ProcessIdItem                        */
+/****************************************/
+
+/**
+ * Return process id (%P, %{pid}P) or agent id that served the request (%{tid}P)
+ */
+
+type ProcessIdItem struct {
+	agent bool
+}
+
+func NewProcessIdItem() LogItem {
+	return &ProcessIdItem{}
+}
+
+func (p *ProcessIdItem) Init(param string) {
+	p.agent = param == "tid" || param == "hextid"
+}
+
+func (p *ProcessIdItem) IsNumber() bool {
+	return true
+}
+
+func (p *ProcessIdItem) GetItem(tour tour.Tour) string {
+	if p.agent {
+		return strconv.Itoa(tour.Ship().(ship.Ship).AgentId())
+	}
+	return strconv.Itoa(os.Getpid())
+}
+
+/****************************************/
+/* LogIdItem                            */
+/****************************************/
+
+/**
+ * Return request id of the request (%L)
+ */
+
+type LogIdItem struct {
+}
+
+func NewLogIdItem() LogItem {
+	return &LogIdItem{}
+}
+
+func (l LogIdItem) Init(param string) {
+
+}
+
+func (l LogIdItem) GetItem(tour tour.Tour) string {
+	if tour.Req().RequestId() != "" {
+		return tour.Req().RequestId()
+	}
+	return strconv.FormatInt(tour.StartTime().UnixNano(), 36) + "-" +
+		strconv.Itoa(tour.Ship().(ship.Ship).AgentId()) + "-" +
+		strconv.Itoa(tour.TourId())
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func elapsed(tour tour.Tour) time.Duration {
+	if tour.StartTime().IsZero() {
+		return 0
+	}
+	return time.Since(tour.StartTime())
+}
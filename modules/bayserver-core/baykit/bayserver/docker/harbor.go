@@ -40,6 +40,9 @@ type Harbor interface {
 	/** Trouble base */
 	Trouble() Trouble
 
+	/** Tracer (nil if tracing is disabled) */
+	Tracer() Tracer
+
 	/** Socket timeout in seconds */
 	SocketTimeoutSec() int
 
@@ -55,6 +58,15 @@ type Harbor interface {
 	/** File name to redirect stdout/stderr */
 	RedirectFile() string
 
+	/** Sink of BayServer log (stdout, stderr, syslog://...) */
+	LogSink() string
+
+	/** Header name of request ID */
+	RequestIdHeader() string
+
+	/** Check if request ID sent from the address is accepted */
+	RequestIdTrusted(remoteAddress string) bool
+
 	/** Port number of signal agent */
 	ControlPort() int
 
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/metrics"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/docker/base"
+	"bayserver-core/baykit/bayserver/tour"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/httpstatus"
+)
+
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+/**
+ * Club which exposes metrics in Prometheus text format
+ *   [club metrics]
+ *       docker metrics
+ */
+type MetricsDocker struct {
+	*base.ClubBase
+}
+
+func NewMetricsDocker() docker.Club {
+	d := &MetricsDocker{}
+	d.ClubBase = base.NewClubBase(d)
+
+	var _ docker.Club = d // implement check
+	return d
+}
+
+func (d *MetricsDocker) String() string {
+	return "Metrics"
+}
+
+/****************************************/
+/* Implements Docker                    */
+/****************************************/
+
+func (d *MetricsDocker) Init(elm *bcf.BcfElement, parent docker.Docker) exception.ConfigException {
+	return d.ClubBase.Init(elm, parent)
+}
+
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (d *MetricsDocker) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.ConfigException) {
+	ok, cerr := d.ClubBase.InitKeyVal(kv)
+	if cerr != nil || ok {
+		return ok, cerr
+	}
+	return d.DefaultInitKeyVal(kv)
+}
+
+/****************************************/
+/* Implements Club                      */
+/****************************************/
+
+func (d *MetricsDocker) Arrive(tur tour.Tour) exception.HttpException {
+	method := tur.Req().Method()
+	if method != "GET" && method != "HEAD" {
+		return exception.NewHttpException(httpstatus.METHOD_NOT_ALLOWED, "%s", tur.Req().Uri())
+	}
+
+	tur.Req().SetReqContentHandler(NewMetricsContentHandler())
+	return nil
+}
+
+/****************************************/
+/* type MetricsContentHandler           */
+/****************************************/
+
+type MetricsContentHandler struct {
+}
+
+func NewMetricsContentHandler() *MetricsContentHandler {
+	h := &MetricsContentHandler{}
+
+	var _ tour.ReqContentHandler = h // implement check
+	return h
+}
+
+/****************************************/
+/* Implements ReqContentHandler         */
+/****************************************/
+
+func (h *MetricsContentHandler) OnReadReqContent(tur tour.Tour, buf []byte, start int, length int, lis tour.ContentConsumeListener) exception2.IOException {
+	tur.Req().Consumed(tur.TourId(), length, lis)
+	return nil
+}
+
+func (h *MetricsContentHandler) OnEndReqContent(tur tour.Tour) (exception2.IOException, exception.HttpException) {
+	return base.SendText(tur, CONTENT_TYPE, metrics.WriteText()), nil
+}
+
+func (h *MetricsContentHandler) OnAbortReq(tur tour.Tour) bool {
+	return false
+}
@@ -13,3 +13,16 @@ type Permission interface {
 
 	TourAdmitted(tur tour.Tour) exception.HttpException
 }
+
+// PermissionCallback is called in the agent of the tour when the permission is decided
+type PermissionCallback func(herr exception.HttpException)
+
+/**
+ * Permission which needs to wait for I/O (e.g. external authorizer) to decide.
+ *   The tour holds the request content until the callback is called.
+ */
+type AsyncPermission interface {
+	Permission
+
+	TourAdmittedAsync(tur tour.Tour, callback PermissionCallback)
+}
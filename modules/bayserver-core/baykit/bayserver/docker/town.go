@@ -45,7 +45,17 @@ type Town interface {
 	 */
 	Reroute(uri string) string
 
+	/**
+	 * Get Link header values sent in 103 Early Hints responses
+	 * @return early hint list
+	 */
+	EarlyHints() []string
+
 	Matches(uri string) int
 
-	CheckAdmitted(tur tour.Tour) exception.HttpException
+	/**
+	 * Check permissions of this town and call next if the tour is admitted
+	 * (next may be called later if the permission is checked asynchronously)
+	 */
+	CheckAdmitted(tur tour.Tour, next func() exception.HttpException) exception.HttpException
 }
@@ -0,0 +1,18 @@
+package docker
+
+import (
+	"bayserver-core/baykit/bayserver/common/tracing"
+)
+
+type Tracer interface {
+	Docker
+
+	/** Check if the trace is sampled (parent is nil if the request has no trace context) */
+	Sample(traceId [16]byte, parent *tracing.TraceContext) bool
+
+	/** Export the ended span. Never blocks */
+	Export(span *tracing.Span)
+
+	/** Export queued spans and stop exporting (Called on shutdown) */
+	Close()
+}
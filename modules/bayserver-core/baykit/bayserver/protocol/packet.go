@@ -85,7 +85,9 @@ func (p *PacketImpl) DataLen() int {
 }
 
 func (p *PacketImpl) Expand() {
-	p.buf = make([]byte, len(p.buf)*2)
+	buf := make([]byte, len(p.buf)*2)
+	copy(buf, p.buf)
+	p.buf = buf
 }
 
 func (p *PacketImpl) MaxDataLen() int {
@@ -8,6 +8,8 @@ import (
 	"bayserver-core/baykit/bayserver/util"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/strutil"
+	"sort"
+	"sync"
 )
 
 var packetProtocolMap = map[string]*PacketProtocolInfo{}
@@ -69,6 +71,7 @@ type PacketStore struct {
 	protocol string
 	storeMap map[int]*objectstore.ObjectStore
 	factory  protocol.PacketFactory
+	lock     sync.Mutex
 }
 
 func NewPacketStore(protocol string, factory protocol.PacketFactory) *PacketStore {
@@ -80,20 +83,42 @@ func NewPacketStore(protocol string, factory protocol.PacketFactory) *PacketStor
 }
 
 func (st *PacketStore) Rent(typ int) protocol.Packet {
+	st.lock.Lock()
 	store := st.storeMap[typ]
 	if store == nil {
 		store = objectstore.NewObjectStore(func() util.Reusable { return st.factory(typ) })
 		st.storeMap[typ] = store
 	}
+	st.lock.Unlock()
 	// 他の処理を追加
 	return store.Rent().(protocol.Packet) // 例: オブジェクトの取得
 }
 
 func (st *PacketStore) Return(pkt protocol.Packet) {
+	st.lock.Lock()
 	store := st.storeMap[pkt.Type()]
+	st.lock.Unlock()
 	store.Return(pkt, true)
 }
 
+func (st *PacketStore) Protocol() string {
+	return st.protocol
+}
+
+// Counts returns the number of active packets and free packets of all types
+func (st *PacketStore) Counts() (int, int) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	active := 0
+	free := 0
+	for _, store := range st.storeMap {
+		active += store.ActiveCount()
+		free += store.FreeCount()
+	}
+	return active, free
+}
+
 func (st *PacketStore) PrintUsage(indent int) {
 	baylog.Info("%sPacketStore(%s) usage nTypes=%d", strutil.Indent(indent), st.protocol, len(st.storeMap))
 	for typ := range st.storeMap {
@@ -119,6 +144,7 @@ func GetStores(agentId int) []*PacketStore {
 	for _, ifo := range packetProtocolMap {
 		storeList = append(storeList, ifo.stores[agentId])
 	}
+	sort.Slice(storeList, func(i, j int) bool { return storeList[i].protocol < storeList[j].protocol })
 	return storeList
 }
 
@@ -7,6 +7,7 @@ import (
 	"bayserver-core/baykit/bayserver/util"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/strutil"
+	"sort"
 )
 
 var protoMap = map[string]*ProtocolInfo{}
@@ -78,6 +79,11 @@ func NewProtocolHandlerStore(proto string, serverMode bool, fact ProtocolHandler
 	}
 }
 
+// Name returns protocol name with mode suffix ("-s": server mode, "-c": client mode)
+func (h *ProtocolHandlerStore) Name() string {
+	return constructProtocol(h.protocol, h.serverMode)
+}
+
 func (h *ProtocolHandlerStore) PrintUsage(indent int) {
 	var typ string
 	if h.serverMode {
@@ -107,6 +113,7 @@ func GetStores(agentId int) []*ProtocolHandlerStore {
 	for _, ifo := range protoMap {
 		storeList = append(storeList, ifo.stores[agentId])
 	}
+	sort.Slice(storeList, func(i, j int) bool { return storeList[i].Name() < storeList[j].Name() })
 	return storeList
 }
 
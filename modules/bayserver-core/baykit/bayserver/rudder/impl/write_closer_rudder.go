@@ -0,0 +1,66 @@
+package impl
+
+import (
+	"bayserver-core/baykit/bayserver/rudder"
+	"bayserver-core/baykit/bayserver/util/exception"
+	"io"
+)
+
+type WriteCloserRudder struct {
+	writeCloser io.WriteCloser
+	name        string
+}
+
+func NewWriteCloserRudder(wc io.WriteCloser, name string) *WriteCloserRudder {
+	rd := WriteCloserRudder{
+		writeCloser: wc,
+		name:        name,
+	}
+	return &rd
+}
+
+func (rd *WriteCloserRudder) String() string {
+	return "WriteCloser[" + rd.name + "]"
+}
+
+func GetWriteCloser(rd rudder.Rudder) io.WriteCloser {
+	return rd.(*WriteCloserRudder).writeCloser
+}
+
+/****************************************/
+/* Implements Rudder                    */
+/****************************************/
+
+func (rd *WriteCloserRudder) Key() interface{} {
+	return rd.writeCloser
+}
+
+func (rd *WriteCloserRudder) Fd() int {
+	return -1
+}
+
+func (rd *WriteCloserRudder) SetNonBlocking() exception.IOException {
+	return nil
+}
+
+func (rd *WriteCloserRudder) Read(buf []byte) (int, exception.IOException) {
+	return -1, exception.NewIOException("Not supported")
+}
+
+func (rd *WriteCloserRudder) Write(buf []byte) (int, exception.IOException) {
+	n, err := rd.writeCloser.Write(buf)
+	if err != nil {
+		return n, exception.NewIOExceptionFromError(err)
+	} else {
+		return n, nil
+	}
+}
+
+func (rd *WriteCloserRudder) Close() exception.IOException {
+	err := rd.writeCloser.Close()
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	} else {
+		return nil
+	}
+}
@@ -52,6 +52,7 @@ const HTP_UNSUPPORTED_PROTOCOL = "HTP_UNSUPPORTED_PROTOCOL"
 const HTP_INVALID_HEADER_FORMAT = "HTP_INVALID_HEADER_FORMAT"
 const HTP_INVALID_PREFACE = "HTP_INVALID_PREFACE"
 const HTP_READ_DATA_EXCEEDED = "HTP_READ_DATA_EXCEEDED"
+const HTP_H2_FLOOD_DETECTED = "HTP_H2_FLOOD_DETECTED"
 
 // Internal errors
 const INT_UNKNOWN_LOG_LEVEL = "INT_UNKNOWN_LOG_LEVEL"
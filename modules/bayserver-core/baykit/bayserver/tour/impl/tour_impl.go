@@ -4,6 +4,9 @@ import (
 	"bayserver-core/baykit/bayserver/bayserver"
 	"bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/common/inboundship"
+	"bayserver-core/baykit/bayserver/common/metrics"
+	"bayserver-core/baykit/bayserver/common/tracing"
+	"bayserver-core/baykit/bayserver/common/warpship"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/ship"
 	"bayserver-core/baykit/bayserver/tour"
@@ -11,7 +14,10 @@ import (
 	"bayserver-core/baykit/bayserver/util/baylog"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"github.com/google/uuid"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const STATE_UNINITIALIZED = 1
@@ -25,6 +31,8 @@ const STATE_ZOMBIE = 7
 const TOUR_ID_NOCHECK = -1
 const INVALID_TOUR_ID = 0
 
+const MAX_REQUEST_ID_LEN = 200
+
 var oidCounter = util.NewCounter()
 var idCounter = util.NewCounter()
 
@@ -42,10 +50,12 @@ type TourImpl struct {
 	req *TourReqImpl
 	res *TourResImpl
 
-	interval int
-	isSecure bool
-	state    int
-	error    exception.HttpException
+	startTime time.Time
+	interval  int
+	span      *tracing.Span
+	isSecure  bool
+	state     int
+	error     exception.HttpException
 }
 
 func NewTour() tour.Tour {
@@ -63,7 +73,11 @@ func (tur *TourImpl) String() string {
 	if tur.ship != nil {
 		shipStr = tur.ship.String()
 	}
-	return shipStr + " tour#" + strconv.Itoa(tur.tourId) + "/" + strconv.Itoa(tur.objectId) + "[key=" + strconv.Itoa(tur.req.key) + "]"
+	str := shipStr + " tour#" + strconv.Itoa(tur.tourId) + "/" + strconv.Itoa(tur.objectId) + "[key=" + strconv.Itoa(tur.req.key) + "]"
+	if tur.req.requestId != "" {
+		str += " rid=" + tur.req.requestId
+	}
+	return str
 }
 
 /****************************************/
@@ -73,6 +87,11 @@ func (tur *TourImpl) String() string {
 func (tur *TourImpl) Reset() {
 	baylog.Debug("%s Tour reset", tur)
 
+	if tur.span != nil {
+		// The tour is aborted or returned without ending response
+		tur.endSpan(true)
+	}
+
 	tur.req.Reset()
 	tur.res.Reset()
 	tur.city = nil
@@ -83,7 +102,9 @@ func (tur *TourImpl) Reset() {
 	tur.ChangeState(TOUR_ID_NOCHECK, STATE_UNINITIALIZED)
 	tur.tourId = INVALID_TOUR_ID
 
+	tur.startTime = time.Time{}
 	tur.interval = 0
+	tur.span = nil
 	tur.isSecure = false
 	tur.error = nil
 	tur.ship = nil
@@ -102,6 +123,7 @@ func (tur *TourImpl) Init(key int, sip ship.Ship) {
 	tur.ship = sip.(inboundship.InboundShip)
 	tur.shipId = sip.ShipId()
 	tur.tourId = idCounter.Next()
+	tur.startTime = time.Now()
 	tur.Req().(*TourReqImpl).key = key
 
 	tur.Req().Init(key)
@@ -143,6 +165,10 @@ func (tur *TourImpl) SetTown(town interface{}) {
 	tur.town = town.(docker.Town)
 }
 
+func (tur *TourImpl) Club() interface{} {
+	return tur.club
+}
+
 func (tur *TourImpl) SetClub(club interface{}) {
 	tur.club = club.(docker.Club)
 }
@@ -218,6 +244,9 @@ func (tur *TourImpl) CheckTourId(checkId int) {
 
 func (tur *TourImpl) Go() exception.HttpException {
 
+	tur.assignRequestId()
+	tur.startSpan()
+
 	tur.city = tur.ship.PortDocker().FindCity(tur.req.reqHost)
 	if tur.city == nil {
 		tur.city = bayserver.FindCity(tur.req.reqHost)
@@ -245,10 +274,156 @@ func (tur *TourImpl) Interval() int {
 	return tur.interval
 }
 
+func (tur *TourImpl) StartTime() time.Time {
+	return tur.startTime
+}
+
+func (tur *TourImpl) Span() *tracing.Span {
+	return tur.span
+}
+
 /****************************************/
 /* Custom functions                     */
 /****************************************/
 
+// observeMetrics counts the tour which is about to end
+func (tur *TourImpl) observeMetrics() {
+	cityName := tur.city.Name()
+	townName := ""
+	if tur.town != nil {
+		townName = tur.town.Name()
+	}
+	clubName := ""
+	if tur.club != nil {
+		clubName = tur.club.FileName()
+		if tur.club.Extension() != "" {
+			clubName += "." + tur.club.Extension()
+		}
+	}
+
+	metrics.RequestsTotal.Inc(cityName, townName, clubName, strconv.Itoa(tur.res.headers.Status()))
+	metrics.RequestDuration.Observe(time.Since(tur.startTime).Seconds(), cityName, townName, clubName)
+	metrics.ReceivedBytes.Add(float64(tur.req.BytesReceived()), cityName, townName)
+	metrics.SentBytes.Add(float64(tur.res.BytesSent()), cityName, townName)
+}
+
+// assignRequestId accepts the request ID sent from a trusted upstream or generates new one.
+// The ID is set to the request header so that it is forwarded to backend servers.
+func (tur *TourImpl) assignRequestId() {
+	if tur.req.requestId != "" {
+		return
+	}
+
+	hdr := bayserver.Harbor().RequestIdHeader()
+	id := tur.req.headers.Get(hdr)
+	if id != "" && (!isValidRequestId(id) || !bayserver.Harbor().RequestIdTrusted(tur.req.remoteAddress)) {
+		baylog.Debug("%s Ignore request id: %s", tur, id)
+		id = ""
+	}
+	if id == "" {
+		id = uuid.New().String()
+	}
+	tur.req.requestId = id
+	tur.req.headers.Set(hdr, id)
+}
+
+// startSpan starts the server span of the tour. The trace context is set to the request headers
+// so that it is propagated to backend servers and CGI.
+func (tur *TourImpl) startSpan() {
+	if tur.span != nil {
+		// Span is taken over from the original tour
+		return
+	}
+
+	trc := bayserver.Harbor().Tracer()
+	if trc == nil {
+		return
+	}
+
+	var ctx *tracing.TraceContext
+	parent := tracing.ParseTraceParent(tur.req.headers.Get(tracing.HEADER_TRACEPARENT))
+	if parent != nil {
+		ctx = parent.NewChildContext()
+		state := tur.req.headers.Get(tracing.HEADER_TRACESTATE)
+		if tracing.ValidTraceState(state) {
+			ctx.TraceState = state
+		}
+	} else {
+		ctx = tracing.NewRootContext(false)
+	}
+	ctx.SetSampled(trc.Sample(ctx.TraceId, parent))
+
+	tur.span = tracing.NewSpan(ctx, parent, tur.req.method, tracing.SPAN_KIND_SERVER)
+	tur.span.StartTime = tur.startTime
+	if tur.span.Recording() {
+		path := tur.req.uri
+		if pos := strings.Index(path, "?"); pos >= 0 {
+			path = path[:pos]
+		}
+		scheme := "http"
+		if tur.isSecure {
+			scheme = "https"
+		}
+		tur.span.SetAttribute("http.request.method", tur.req.method)
+		tur.span.SetAttribute("url.path", path)
+		tur.span.SetAttribute("url.scheme", scheme)
+		tur.span.SetAttribute("server.address", tur.req.reqHost)
+		tur.span.SetAttribute("server.port", tur.req.reqPort)
+		tur.span.SetAttribute("client.address", tur.req.remoteAddress)
+		tur.span.SetAttribute("network.protocol.version", strings.TrimPrefix(tur.req.protocol, "HTTP/"))
+		if ua := tur.req.headers.Get("User-Agent"); ua != "" {
+			tur.span.SetAttribute("user_agent.original", ua)
+		}
+		tur.span.SetAttribute("bayserver.request_id", tur.req.requestId)
+	}
+
+	tur.req.headers.Set(tracing.HEADER_TRACEPARENT, ctx.TraceParent())
+	if ctx.TraceState != "" {
+		tur.req.headers.Set(tracing.HEADER_TRACESTATE, ctx.TraceState)
+	} else {
+		tur.req.headers.Remove(tracing.HEADER_TRACESTATE)
+	}
+}
+
+// endSpan ends the server span of the tour and exports it
+func (tur *TourImpl) endSpan(aborted bool) {
+	span := tur.span
+	if span == nil {
+		return
+	}
+	tur.span = nil
+
+	if wdat, ok := tur.req.contentHandler.(*warpship.WarpData); ok {
+		// Client span which is not ended by warp ship
+		wdat.EndSpan(tur, "")
+	}
+
+	if !span.Recording() {
+		return
+	}
+
+	status := tur.res.headers.Status()
+	if tur.town != nil {
+		span.Name = tur.req.method + " " + tur.town.Name()
+		span.SetAttribute("http.route", tur.town.Name())
+	}
+	span.SetAttribute("http.response.status_code", status)
+	if aborted {
+		span.SetError("aborted")
+	} else if status >= 500 {
+		span.SetError("")
+	}
+	span.End()
+	bayserver.Harbor().Tracer().Export(span)
+}
+
+// notifyResponseStart is called when the response from the backend server arrives
+func (tur *TourImpl) notifyResponseStart() {
+	if wdat, ok := tur.req.contentHandler.(*warpship.WarpData); ok {
+		wdat.OnResponseStart()
+	}
+}
+
 func (tur *TourImpl) ChangeState(checkId int, newState int) {
 	tur.CheckTourId(checkId)
 	tur.state = newState
@@ -261,3 +436,16 @@ func (tur *TourImpl) Error() exception.HttpException {
 /****************************************/
 /* Private functions                    */
 /****************************************/
+
+// isValidRequestId checks the request ID is short and contains only safe characters
+func isValidRequestId(id string) bool {
+	if len(id) > MAX_REQUEST_ID_LEN {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:+/=@", c)) {
+			return false
+		}
+	}
+	return true
+}
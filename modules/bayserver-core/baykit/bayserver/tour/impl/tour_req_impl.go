@@ -17,8 +17,9 @@ import (
 )
 
 type TourReqImpl struct {
-	tour *TourImpl
-	key  int
+	tour      *TourImpl
+	key       int
+	requestId string
 
 	uri      string
 	method   string
@@ -35,6 +36,7 @@ type TourReqImpl struct {
 
 	remoteUser string
 	remotePass string
+	variables  [][]string
 
 	remoteAddress  string
 	remotePort     int
@@ -48,9 +50,13 @@ type TourReqImpl struct {
 	bytesPosted    int
 	bytesConsumed  int
 	bytesLimit     int
+	bytesReceived  int
+	contentBytes   int
 	contentHandler tour.ReqContentHandler
 	available      bool
 	ended          bool
+
+	continueRelayed bool
 }
 
 func NewTourReq(tur *TourImpl) *TourReqImpl {
@@ -76,12 +82,15 @@ func (req *TourReqImpl) Reset() {
 	//baylog.Info("TourReq:Reset")
 	req.headers.Clear()
 	req.key = 0
+	req.requestId = ""
 	req.uri = ""
 	req.method = ""
 	req.protocol = ""
 	req.bytesPosted = 0
 	req.bytesConsumed = 0
 	req.bytesLimit = 0
+	req.bytesReceived = 0
+	req.contentBytes = 0
 
 	req.rewrittenURI = ""
 	req.queryString = ""
@@ -91,6 +100,7 @@ func (req *TourReqImpl) Reset() {
 	req.reqPort = 0
 	req.remoteUser = ""
 	req.remotePass = ""
+	req.variables = nil
 
 	req.remoteAddress = ""
 	req.remotePort = 0
@@ -103,6 +113,7 @@ func (req *TourReqImpl) Reset() {
 	req.contentHandler = nil
 	req.available = false
 	req.ended = false
+	req.continueRelayed = false
 }
 
 /****************************************/
@@ -145,6 +156,14 @@ func (req *TourReqImpl) ReqPort() int {
 	return req.reqPort
 }
 
+func (req *TourReqImpl) RequestId() string {
+	return req.requestId
+}
+
+func (req *TourReqImpl) SetRequestId(id string) {
+	req.requestId = id
+}
+
 func (req *TourReqImpl) RemoteAddress() string {
 	return req.remoteAddress
 }
@@ -248,6 +267,18 @@ func (req *TourReqImpl) SetRewrittenUri(uri string) {
 	req.rewrittenURI = uri
 }
 
+func (req *TourReqImpl) ContinueRelayed() bool {
+	return req.continueRelayed
+}
+
+func (req *TourReqImpl) SetContinueRelayed(relayed bool) {
+	req.continueRelayed = relayed
+}
+
+func (req *TourReqImpl) ExpectsContinue() bool {
+	return strings.EqualFold(strings.TrimSpace(req.headers.Get(headers.EXPECT)), headers.EXPECT_100_CONTINUE)
+}
+
 func (req *TourReqImpl) GetReqContentHandler() tour.ReqContentHandler {
 	return req.contentHandler
 }
@@ -263,6 +294,10 @@ func (req *TourReqImpl) SetReqContentHandler(handler tour.ReqContentHandler) {
 	req.contentHandler = handler
 }
 
+func (req *TourReqImpl) ResetReqContentHandler() {
+	req.contentHandler = nil
+}
+
 /**
  * Parse AUTHORIZATION headerq
  */
@@ -358,6 +393,7 @@ func (req *TourReqImpl) PostReqContent(checkId int, data []byte, start int, len
 	}
 
 	req.bytesPosted += len
+	req.contentBytes += len
 	baylog.Debug("%s read content: len=%d posted=%d limit=%d consumed=%d available=%t",
 		req.tour, len, req.bytesPosted, req.bytesLimit, req.bytesConsumed, req.available)
 
@@ -413,10 +449,29 @@ func (req *TourReqImpl) RemotePass() string {
 	return req.remotePass
 }
 
+func (req *TourReqImpl) Variables() [][]string {
+	return req.variables
+}
+
+func (req *TourReqImpl) AddVariable(name string, value string) {
+	req.variables = append(req.variables, []string{name, value})
+}
+
 func (req *TourReqImpl) BytesPosted() int {
 	return req.bytesPosted
 }
 
+// BytesReceived returns the number of bytes read from the client for this tour including headers.
+// (Counted on the ship when the response ends)
+func (req *TourReqImpl) BytesReceived() int {
+	return req.bytesReceived
+}
+
+// ContentBytesReceived returns the number of bytes of request content received from the client
+func (req *TourReqImpl) ContentBytesReceived() int {
+	return req.contentBytes
+}
+
 func (req *TourReqImpl) BytesLimit() int {
 	return req.bytesLimit
 }
@@ -15,6 +15,7 @@ import (
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/transform"
 	"io"
+	"time"
 )
 
 type TourResImpl struct {
@@ -27,6 +28,8 @@ type TourResImpl struct {
 	bytesPosted        int
 	bytesConsumed      int
 	bytesLimit         int
+	bytesSent          int
+	contentBytesSent   int
 	resConsumeListener tour.ContentConsumeListener
 	canCompress        bool
 }
@@ -53,6 +56,8 @@ func (res *TourResImpl) Reset() {
 	res.bytesPosted = 0
 	res.bytesConsumed = 0
 	res.bytesLimit = 0
+	res.bytesSent = 0
+	res.contentBytesSent = 0
 
 	res.charset = ""
 	res.headerSent = false
@@ -99,6 +104,11 @@ func (res *TourResImpl) SendHeaders(checkId int) exception.IOException {
 		return nil
 	}
 
+	tour.notifyResponseStart()
+
+	// Request ID is needed even if the tour fails before it starts (It is taken over by the error tour)
+	tour.assignRequestId()
+
 	res.bytesLimit = res.headers.ContentLength()
 	baylog.Debug("%s content length: %d", res, res.bytesLimit)
 
@@ -121,6 +131,9 @@ catch:
 					errTour.req.serverAddress = tour.req.serverAddress
 					errTour.req.serverPort = tour.req.serverPort
 					errTour.req.serverName = tour.req.serverName
+					errTour.req.requestId = tour.req.requestId
+					errTour.span = tour.span
+					tour.span = nil
 					errTour.res.headerSent = tour.res.headerSent
 					errTour.res.SetConsumeListener(func(len int, resume bool) {})
 					tour.ChangeState(TOUR_ID_NOCHECK, STATE_ZOMBIE)
@@ -191,6 +204,34 @@ catch:
 	return nil
 }
 
+/**
+ * This method sends an informational (1xx) response to the client.
+ * It is ignored after the final response headers are sent.
+ */
+
+func (res *TourResImpl) SendInformational(checkId int, status int, hdrs *headers.Headers) exception.IOException {
+	tour := res.tour
+	tour.CheckTourId(checkId)
+	baylog.Debug("%s send informational: status=%d", res, status)
+
+	if tour.IsZombie() || tour.IsAborted() {
+		return nil
+	}
+
+	if res.headerSent {
+		return nil
+	}
+
+	tour.notifyResponseStart()
+	ioerr := tour.ship.SendInformational(tour.shipId, tour, status, hdrs)
+	if ioerr != nil {
+		tour.ChangeState(checkId, STATE_ABORTED)
+		return ioerr
+	}
+
+	return nil
+}
+
 /**
  * This method sends a part of the response content to the client.
  * Whether this process is synchronous or asynchronous is uncertain
@@ -236,6 +277,7 @@ func (res *TourResImpl) SendResContent(checkId int, buf []byte, ofs int, length
 				res.tour.ChangeState(TOUR_ID_NOCHECK, STATE_ABORTED)
 				return false, ioerr
 			}
+			res.contentBytesSent += length
 		}
 	}
 
@@ -270,8 +312,19 @@ func (res *TourResImpl) EndResContent(checkId int) exception.IOException {
 		return nil
 	}
 
+	// Interval in milliseconds
+	res.tour.interval = int(time.Since(res.tour.startTime).Milliseconds())
+
+	if !res.tour.IsZombie() {
+		res.tour.req.bytesReceived, res.bytesSent = res.tour.ship.TakeBytesCount()
+	}
+
 	if !res.tour.IsZombie() && res.tour.city != nil {
 		res.tour.city.Log(res.tour)
+		res.tour.observeMetrics()
+	}
+	if !res.tour.IsZombie() {
+		res.tour.endSpan(res.tour.IsAborted())
 	}
 
 	// send end message
@@ -401,6 +454,17 @@ func (res *TourResImpl) BytesPosted() int {
 	return res.bytesPosted
 }
 
+// BytesSent returns the number of bytes written to the client for this tour including headers.
+// (Counted on the ship when the response ends)
+func (res *TourResImpl) BytesSent() int {
+	return res.bytesSent
+}
+
+// ContentBytesSent returns the number of bytes of response content sent to the client
+func (res *TourResImpl) ContentBytesSent() int {
+	return res.contentBytesSent
+}
+
 func (res *TourResImpl) BytesLimit() int {
 	return res.bytesLimit
 }
@@ -0,0 +1,76 @@
+package tour
+
+import (
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * Content handler which holds request content while the tour waits (e.g. for permission check)
+ *   Content is passed to the handler which is set after waiting by Replay()
+ */
+
+type pendingContent struct {
+	data []byte
+	lis  ContentConsumeListener
+}
+
+type PendingContentHandler struct {
+	contents []pendingContent
+	ended    bool
+}
+
+func NewPendingContentHandler() *PendingContentHandler {
+	return &PendingContentHandler{}
+}
+
+/****************************************/
+/* Implements ReqContentHandler         */
+/****************************************/
+
+func (h *PendingContentHandler) OnReadReqContent(tur Tour, buf []byte, start int, length int, lis ContentConsumeListener) exception.IOException {
+	// Copy data because the caller may reuse buffer after return
+	data := make([]byte, length)
+	copy(data, buf[start:start+length])
+	h.contents = append(h.contents, pendingContent{data: data, lis: lis})
+	return nil
+}
+
+func (h *PendingContentHandler) OnEndReqContent(tur Tour) (exception.IOException, exception2.HttpException) {
+	h.ended = true
+	return nil, nil
+}
+
+func (h *PendingContentHandler) OnAbortReq(tur Tour) bool {
+	h.contents = nil
+	return true
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+// Replay passes held content to the current content handler of the tour
+func (h *PendingContentHandler) Replay(tur Tour) (exception.IOException, exception2.HttpException) {
+	next := tur.Req().GetReqContentHandler()
+	contents := h.contents
+	h.contents = nil
+
+	for _, c := range contents {
+		if next == nil {
+			// Content is not needed
+			tur.Req().Consumed(tur.TourId(), len(c.data), c.lis)
+			continue
+		}
+
+		ioerr := next.OnReadReqContent(tur, c.data, 0, len(c.data), c.lis)
+		if ioerr != nil {
+			return ioerr, nil
+		}
+	}
+
+	if h.ended && next != nil {
+		return next.OnEndReqContent(tur)
+	}
+	return nil, nil
+}
@@ -2,8 +2,10 @@ package tour
 
 import (
 	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/tracing"
 	"bayserver-core/baykit/bayserver/ship"
 	"bayserver-core/baykit/bayserver/util"
+	"time"
 )
 
 type Tour interface {
@@ -20,6 +22,7 @@ type Tour interface {
 	SetCity(city interface{}) // docker.City
 	Town() interface{}
 	SetTown(town interface{}) // docker.Town
+	Club() interface{}
 	SetClub(club interface{}) // docker.Club
 	State() int
 	Ship() interface{}
@@ -42,6 +45,8 @@ type Tour interface {
 
 	Go() exception.HttpException
 	Interval() int
+	StartTime() time.Time
+	Span() *tracing.Span // Server span (nil if tracing is disabled)
 	Error() exception.HttpException
 	SetErrorHandling(handling bool)
 }
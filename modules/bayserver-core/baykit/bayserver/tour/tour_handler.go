@@ -4,6 +4,7 @@ import (
 	"bayserver-core/baykit/bayserver/common"
 	"bayserver-core/baykit/bayserver/common/exception"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/headers"
 )
 
 type TourHandler interface {
@@ -11,6 +12,9 @@ type TourHandler interface {
 	// Send HTTP headers to client
 	SendHeaders(tour Tour) exception2.IOException
 
+	// Send informational (1xx) response headers to client
+	SendInformational(tour Tour, status int, hdrs *headers.Headers) exception2.IOException
+
 	// Send Contents to client
 	SendContent(tour Tour, bytes []byte, ofs int, length int, lis common.DataConsumeListener) exception2.IOException
 
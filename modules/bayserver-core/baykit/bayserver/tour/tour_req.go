@@ -31,6 +31,10 @@ type TourReq interface {
 
 	Key() int
 
+	// RequestId returns the identifier of the request which is shared with clients and backends
+	RequestId() string
+	SetRequestId(id string)
+
 	Uri() string
 	SetUri(uri string)
 	Method() string
@@ -68,18 +72,31 @@ type TourReq interface {
 	RewrittenUri() string
 	SetRewrittenUri(uri string)
 
+	// Expect: 100-continue
+	ExpectsContinue() bool
+	ContinueRelayed() bool
+	SetContinueRelayed(relayed bool)
+
 	ParseAuthorization()
 	ParseHostPort(defaultPort int)
 
 	GetReqContentHandler() ReqContentHandler
 	SetReqContentHandler(handler ReqContentHandler)
+	// ResetReqContentHandler detaches content handler so that the tour can be passed to another handler
+	ResetReqContentHandler()
 	Consumed(checkId int, length int, lis ContentConsumeListener)
 	PostReqContent(checkId int, data []byte, start int, len int, lis ContentConsumeListener) (bool, exception2.HttpException)
 	EndReqContent(checkId int) (exception.IOException, exception2.HttpException)
 	RemoteUser() string
 	RemotePass() string
 
+	// Extra CGI variables passed to the club (e.g. Variable-* of FastCGI authorizer)
+	Variables() [][]string
+	AddVariable(name string, value string)
+
 	BytesPosted() int
+	BytesReceived() int
+	ContentBytesReceived() int
 	BytesLimit() int
 	Abort() bool
 }
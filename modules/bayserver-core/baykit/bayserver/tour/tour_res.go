@@ -14,6 +14,7 @@ type TourRes interface {
 	HeaderSent() bool
 
 	SendHeaders(checkId int) exception.IOException
+	SendInformational(checkId int, status int, hdrs *headers.Headers) exception.IOException
 	SendResContent(checkId int, buf []byte, start int, length int) (bool, exception.IOException)
 	EndResContent(checkId int) exception.IOException
 	SendError(checkId int, status int, message string, err exception.Exception) exception.IOException
@@ -22,5 +23,7 @@ type TourRes interface {
 	DetachConsumeListener()
 
 	BytesPosted() int
+	BytesSent() int
+	ContentBytesSent() int
 	BytesLimit() int
 }
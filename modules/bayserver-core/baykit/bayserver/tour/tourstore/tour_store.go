@@ -4,11 +4,13 @@ import (
 	"bayserver-core/baykit/bayserver/agent"
 	"bayserver-core/baykit/bayserver/bayserver"
 	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/metrics"
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/tour/impl"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/strutil"
+	"strconv"
 	"sync"
 )
 
@@ -26,10 +28,17 @@ type TourStore_LifeCycleListener struct {
 }
 
 func (l *TourStore_LifeCycleListener) Add(agentId int) {
-	stores[agentId] = NewTourStore()
+	st := NewTourStore()
+	stores[agentId] = st
+
+	agtId := strconv.Itoa(agentId)
+	metrics.ActiveTours.SetCollector(agtId, func(emit metrics.Emitter) {
+		emit(float64(st.ActiveCount()), agtId)
+	})
 }
 
 func (*TourStore_LifeCycleListener) Remove(agentId int) {
+	metrics.ActiveTours.RemoveCollector(strconv.Itoa(agentId))
 	delete(stores, agentId)
 }
 
@@ -55,6 +64,9 @@ func (st *TourStore) Get(key int64) tour.Tour {
 }
 
 func (st *TourStore) Rent(key int64, force bool) tour.Tour {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
 	tur := st.Get(key)
 	if tur != nil {
 		bayserver.FatalError(exception.NewSink("Tour is active: %s", tur))
@@ -94,6 +106,20 @@ func (st *TourStore) Return(key int64) {
 	st.freeTours = append(st.freeTours, tur)
 }
 
+func (st *TourStore) ActiveCount() int {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	return len(st.activeTourMap)
+}
+
+func (st *TourStore) FreeCount() int {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+
+	return len(st.freeTours)
+}
+
 func (st *TourStore) PrintUsage(indent int) {
 	baylog.Info("%sTour store usage:", strutil.Indent(indent))
 	baylog.Info("%sfreeList: %d", strutil.Indent(indent+1), len(st.freeTours))
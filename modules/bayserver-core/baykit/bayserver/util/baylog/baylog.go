@@ -2,6 +2,7 @@ package baylog
 
 import (
 	"bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/logsink"
 	"fmt"
 	"runtime"
 	"strconv"
@@ -16,27 +17,69 @@ const LOG_LEVEL_WARN int = 3
 const LOG_LEVEL_ERROR int = 4
 const LOG_LEVEL_FATAL int = 5
 
-/** Log level */
-var logLevel = LOG_LEVEL_INFO
-
 var logLevelNames = []string{"TRACE", "DEBUG", "INFO ", "WARN ", "ERROR", "FATAL"}
 
+/** Output format (plain, text or json) */
+var logFormat = LOG_FORMAT_PLAIN
+
+var logSeverities = []int{
+	logsink.SEVERITY_DEBUG,
+	logsink.SEVERITY_DEBUG,
+	logsink.SEVERITY_INFO,
+	logsink.SEVERITY_WARNING,
+	logsink.SEVERITY_ERR,
+	logsink.SEVERITY_CRIT,
+}
+
+/** Writer to log sink (If nil, logs are printed to stdout) */
+var sinkWriter *logsink.AsyncWriter = nil
+
+// SetSink makes baylog write logs to the sink in background
+func SetSink(sink logsink.LogSink, queueSize int) {
+	sinkWriter = logsink.NewAsyncWriter(sink, queueSize)
+}
+
+/** Max time to wait for the log sink to write out queued records */
+const SINK_FLUSH_TIMEOUT_SEC = 3
+
+// FlushSink waits until the records queued for the log sink are written
+func FlushSink() {
+	if sinkWriter != nil {
+		sinkWriter.Flush(SINK_FLUSH_TIMEOUT_SEC * time.Second)
+	}
+}
+
+// CloseSink writes out the queued records and closes the log sink.
+// Called before the process exits.
+func CloseSink() {
+	if sinkWriter != nil {
+		sinkWriter.Close(SINK_FLUSH_TIMEOUT_SEC * time.Second)
+	}
+}
+
 func SetLogLevel(s string) {
-	for i, arg := range logLevelNames {
-		if strings.ToUpper(s) == arg {
-			logLevel = i
-			return
-		}
+	err := SetBaseLogLevels(s)
+	if err != nil {
+		WarnE(err, "Unknown LogLevel: %s", s)
+	}
+}
+
+func SetLogFormat(s string) exception.Exception {
+	switch strings.ToLower(s) {
+	case LOG_FORMAT_PLAIN, LOG_FORMAT_TEXT, LOG_FORMAT_JSON:
+		logFormat = strings.ToLower(s)
+		return nil
+	default:
+		return exception.NewException("Unknown log format: %s", s)
 	}
-	Warn("Unknown LogLevel", s)
 }
 
 func IsDebugMode() bool {
-	return logLevel <= LOG_LEVEL_DEBUG
+	return enabled(LOG_LEVEL_DEBUG, 3)
 }
 
 func IsTraceMode() bool {
-	return logLevel == LOG_LEVEL_TRACE
+	return enabled(LOG_LEVEL_TRACE, 3)
 }
 
 func Trace(fmt string, args ...interface{}) {
@@ -83,15 +126,39 @@ func FatalE(err error, fmt string, args ...interface{}) {
 	log(LOG_LEVEL_FATAL, 3, err, fmt, args...)
 }
 
-func log(lvl int, stackIdx int, err error, format string, args ...interface{}) {
+/*
+ * Structured logging: kvs are pairs of key and value
+ *   e.g. baylog.InfoKV("Connected", "agent", agentId, "remote", addr)
+ */
+
+func DebugKV(msg string, kvs ...interface{}) {
+	logKV(LOG_LEVEL_DEBUG, 3, nil, msg, kvs)
+}
+
+func InfoKV(msg string, kvs ...interface{}) {
+	logKV(LOG_LEVEL_INFO, 3, nil, msg, kvs)
+}
+
+func WarnKV(msg string, kvs ...interface{}) {
+	logKV(LOG_LEVEL_WARN, 3, nil, msg, kvs)
+}
+
+func ErrorKV(err error, msg string, kvs ...interface{}) {
+	logKV(LOG_LEVEL_ERROR, 3, err, msg, kvs)
+}
 
-	if lvl < logLevel {
+func log(lvl int, stackIdx int, err error, format string, args ...interface{}) {
+	cfg := currentConfig()
+	if lvl < cfg.min {
 		return
 	}
 
-	pos := getPos()
-
-	dateStr := time.Now().Format("2006-01-02 15:04:05 MST")
+	// Check level of the package before formatting message
+	frame := callerFrame(stackIdx)
+	pkg := packageOf(frame.Function)
+	if lvl < cfg.levelOf(pkg) {
+		return
+	}
 
 	var msg string
 	if len(args) == 0 {
@@ -99,33 +166,93 @@ func log(lvl int, stackIdx int, err error, format string, args ...interface{}) {
 	} else {
 		msg = fmt.Sprintf(format, args...)
 	}
-	fmt.Printf("[%s] %s. %s (%s)\n", dateStr, logLevelNames[lvl], msg, pos)
+	writeRecord(cfg, lvl, frame, pkg, err, msg, nil)
+}
 
-	if err != nil {
-		fmt.Printf("%s\n", err.Error())
-		if IsDebugMode() || lvl == LOG_LEVEL_FATAL {
-			if err, ok := err.(exception.Exception); ok {
-				stackTraceString := ""
-				for {
-					frame, more := err.Frames().Next()
-					stackTraceString += frame.Function + "\n\t" + frame.File + ":" + strconv.Itoa(frame.Line) + "\n"
-					if !more {
-						break
-					}
+func logKV(lvl int, stackIdx int, err error, msg string, kvs []interface{}) {
+	cfg := currentConfig()
+	if lvl < cfg.min {
+		return
+	}
+
+	frame := callerFrame(stackIdx)
+	pkg := packageOf(frame.Function)
+	if lvl < cfg.levelOf(pkg) {
+		return
+	}
+	writeRecord(cfg, lvl, frame, pkg, err, msg, kvs)
+}
+
+func writeRecord(cfg *levelConfig, lvl int, frame runtime.Frame, pkg string, err error, msg string, kvs []interface{}) {
+	rec := &record{
+		time: time.Now(),
+		lvl:  lvl,
+		pkg:  pkg,
+		msg:  msg,
+		kvs:  kvs,
+		pos:  fmt.Sprintf("%s:%d", frame.File, frame.Line),
+		err:  err,
+	}
+
+	if err != nil && (cfg.levelOf(pkg) <= LOG_LEVEL_DEBUG || lvl == LOG_LEVEL_FATAL) {
+		if err, ok := err.(exception.Exception); ok {
+			stackTraceString := ""
+			for {
+				frame, more := err.Frames().Next()
+				stackTraceString += frame.Function + "\n\t" + frame.File + ":" + strconv.Itoa(frame.Line) + "\n"
+				if !more {
+					break
 				}
-				fmt.Println(stackTraceString)
 			}
+			rec.stack = stackTraceString
 		}
 	}
+
+	var line string
+	switch logFormat {
+	case LOG_FORMAT_TEXT:
+		line = formatText(rec)
+	case LOG_FORMAT_JSON:
+		line = formatJson(rec)
+	default:
+		line = formatPlain(rec, sinkWriter == nil || !sinkWriter.Sink().Timestamped())
+	}
+
+	if sinkWriter != nil {
+		sinkWriter.Write(sinkWriter.Sink().Format(logSeverities[lvl], line))
+	} else {
+		fmt.Println(line)
+	}
+}
+
+// enabled checks if the level is enabled for the package of caller
+func enabled(lvl int, stackIdx int) bool {
+	cfg := currentConfig()
+	if lvl < cfg.min {
+		return false
+	}
+	if len(cfg.pkgs) == 0 {
+		return lvl >= cfg.global
+	}
+	return lvl >= cfg.levelOf(packageOf(callerFrame(stackIdx).Function))
+}
+
+func callerFrame(skip int) runtime.Frame {
+	pcs := make([]uintptr, 1)
+	n := runtime.Callers(skip+1, pcs)
+	if n == 0 {
+		return runtime.Frame{}
+	}
+	frame, _ := runtime.CallersFrames(pcs[:n]).Next()
+	return frame
 }
 
-func getPos() string {
-	buf := make([]uintptr, 10)
-	n := runtime.Callers(1, buf[:])
-	frames := runtime.CallersFrames(buf[:n])
-	var frame runtime.Frame
-	for i := 0; i < 4; i++ {
-		frame, _ = frames.Next()
+// packageOf returns package path of function name such as "a/b/pkg.(*Type).Method.func1"
+func packageOf(funcName string) string {
+	slash := strings.LastIndex(funcName, "/")
+	dot := strings.Index(funcName[slash+1:], ".")
+	if dot < 0 {
+		return funcName
 	}
-	return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+	return funcName[:slash+1+dot]
 }
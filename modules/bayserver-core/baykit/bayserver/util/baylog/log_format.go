@@ -0,0 +1,136 @@
+package baylog
+
+import (
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const LOG_FORMAT_PLAIN = "plain"
+const LOG_FORMAT_TEXT = "text"
+const LOG_FORMAT_JSON = "json"
+
+/**
+ * Log record
+ */
+type record struct {
+	time  time.Time
+	lvl   int
+	pkg   string
+	msg   string
+	kvs   []interface{}
+	pos   string
+	err   error
+	stack string
+}
+
+// formatPlain makes line of traditional style: [date] LEVEL. message k=v (pos)
+func formatPlain(rec *record, withTime bool) string {
+	var sb strings.Builder
+	if withTime {
+		sb.WriteString("[" + rec.time.Format("2006-01-02 15:04:05 MST") + "] ")
+	}
+	sb.WriteString(logLevelNames[rec.lvl])
+	sb.WriteString(". ")
+	sb.WriteString(rec.msg)
+	for i := 0; i < len(rec.kvs); i += 2 {
+		sb.WriteString(" ")
+		sb.WriteString(kvKey(rec.kvs, i))
+		sb.WriteString("=")
+		sb.WriteString(textQuote(fmt.Sprint(kvValue(rec.kvs, i))))
+	}
+	sb.WriteString(" (" + rec.pos + ")")
+
+	if rec.err != nil {
+		sb.WriteString("\n" + rec.err.Error())
+		if rec.stack != "" {
+			sb.WriteString("\n" + rec.stack)
+		}
+	}
+	return sb.String()
+}
+
+// formatText makes line of key=value pairs (logfmt)
+func formatText(rec *record) string {
+	var sb strings.Builder
+	sb.WriteString("time=" + rec.time.Format(time.RFC3339Nano))
+	sb.WriteString(" level=" + strings.ToLower(strings.TrimSpace(logLevelNames[rec.lvl])))
+	sb.WriteString(" pkg=" + shortPackage(rec.pkg))
+	sb.WriteString(" msg=" + textQuote(rec.msg))
+	for i := 0; i < len(rec.kvs); i += 2 {
+		sb.WriteString(" " + kvKey(rec.kvs, i) + "=" + textQuote(fmt.Sprint(kvValue(rec.kvs, i))))
+	}
+	sb.WriteString(" pos=" + textQuote(rec.pos))
+	if rec.err != nil {
+		sb.WriteString(" error=" + textQuote(rec.err.Error()))
+		if rec.stack != "" {
+			sb.WriteString(" stack=" + textQuote(rec.stack))
+		}
+	}
+	return sb.String()
+}
+
+// formatJson makes line of JSON object
+func formatJson(rec *record) string {
+	var sb strings.Builder
+	sb.WriteString("{\"time\":" + strutil.JsonQuote(rec.time.Format(time.RFC3339Nano)))
+	sb.WriteString(",\"level\":" + strutil.JsonQuote(strings.ToLower(strings.TrimSpace(logLevelNames[rec.lvl]))))
+	sb.WriteString(",\"pkg\":" + strutil.JsonQuote(shortPackage(rec.pkg)))
+	sb.WriteString(",\"msg\":" + strutil.JsonQuote(rec.msg))
+	for i := 0; i < len(rec.kvs); i += 2 {
+		sb.WriteString("," + strutil.JsonQuote(kvKey(rec.kvs, i)) + ":" + jsonValue(kvValue(rec.kvs, i)))
+	}
+	sb.WriteString(",\"pos\":" + strutil.JsonQuote(rec.pos))
+	if rec.err != nil {
+		sb.WriteString(",\"error\":" + strutil.JsonQuote(rec.err.Error()))
+		if rec.stack != "" {
+			sb.WriteString(",\"stack\":" + strutil.JsonQuote(rec.stack))
+		}
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func kvKey(kvs []interface{}, i int) string {
+	if key, ok := kvs[i].(string); ok && key != "" {
+		return key
+	}
+	return "arg" + strconv.Itoa(i/2)
+}
+
+func kvValue(kvs []interface{}, i int) interface{} {
+	if i+1 < len(kvs) {
+		return kvs[i+1]
+	}
+	return nil
+}
+
+// shortPackage returns the last part of package path
+func shortPackage(pkgPath string) string {
+	return pkgPath[strings.LastIndex(pkgPath, "/")+1:]
+}
+
+func textQuote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=\\") || !utf8.ValidString(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func jsonValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(val)
+	case float32, float64:
+		return fmt.Sprint(val)
+	default:
+		return strutil.JsonQuote(fmt.Sprint(val))
+	}
+}
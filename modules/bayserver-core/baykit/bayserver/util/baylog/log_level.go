@@ -0,0 +1,159 @@
+package baylog
+
+import (
+	"bayserver-core/baykit/bayserver/util/exception"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/**
+ * Log levels of the server and each subsystem (package)
+ *
+ * Spec format: "<level> <package>=<level> ..."  (Separated by spaces or commas)
+ *   e.g. "info h2=debug warpship=trace"
+ * Package is matched with the end of package path or any directory of it
+ * (e.g. "h2", "http/h2" or "warpship").
+ */
+type levelConfig struct {
+	spec   string
+	global int
+	pkgs   map[string]int
+
+	/** Lowest level in the config (Messages under this level are never written) */
+	min int
+
+	/** Resolved level of each package path */
+	cache sync.Map
+}
+
+var config atomic.Value
+
+/** Level set by configuration (Used by ToggleDebug) */
+var baseSpec = ""
+
+func init() {
+	config.Store(newLevelConfig("", LOG_LEVEL_INFO, map[string]int{}))
+}
+
+func newLevelConfig(spec string, global int, pkgs map[string]int) *levelConfig {
+	cfg := &levelConfig{
+		spec:   spec,
+		global: global,
+		pkgs:   pkgs,
+		min:    global,
+	}
+	for _, lvl := range pkgs {
+		if lvl < cfg.min {
+			cfg.min = lvl
+		}
+	}
+	return cfg
+}
+
+func currentConfig() *levelConfig {
+	return config.Load().(*levelConfig)
+}
+
+// SetLogLevels sets levels by spec. Current levels are kept if the spec is invalid.
+func SetLogLevels(spec string) exception.Exception {
+	cur := currentConfig()
+	global := cur.global
+	pkgs := map[string]int{}
+
+	tokens := strings.FieldsFunc(spec, func(c rune) bool {
+		return c == ' ' || c == '\t' || c == ','
+	})
+	if len(tokens) == 0 {
+		return exception.NewException("Log level not specified")
+	}
+
+	for _, tk := range tokens {
+		pos := strings.Index(tk, "=")
+		if pos < 0 {
+			lvl, ok := parseLevel(tk)
+			if !ok {
+				return exception.NewException("Unknown log level: %s", tk)
+			}
+			global = lvl
+
+		} else {
+			pkg := strings.Trim(tk[:pos], "/")
+			lvl, ok := parseLevel(tk[pos+1:])
+			if pkg == "" || !ok {
+				return exception.NewException("Invalid log level: %s", tk)
+			}
+			pkgs[pkg] = lvl
+		}
+	}
+
+	config.Store(newLevelConfig(strings.Join(tokens, " "), global, pkgs))
+	return nil
+}
+
+// SetBaseLogLevels sets levels at startup. They are restored by ToggleDebug.
+func SetBaseLogLevels(spec string) exception.Exception {
+	err := SetLogLevels(spec)
+	if err == nil {
+		baseSpec = currentConfig().spec
+	}
+	return err
+}
+
+// LogLevels returns current level spec
+func LogLevels() string {
+	cfg := currentConfig()
+	spec := strings.TrimSpace(logLevelNames[cfg.global])
+	for pkg, lvl := range cfg.pkgs {
+		spec += " " + pkg + "=" + strings.TrimSpace(logLevelNames[lvl])
+	}
+	return strings.ToLower(spec)
+}
+
+// ToggleDebug switches the server level between debug and the configured levels
+func ToggleDebug() {
+	if currentConfig().global <= LOG_LEVEL_DEBUG {
+		spec := baseSpec
+		if spec == "" {
+			spec = "info"
+		}
+		SetLogLevels(spec)
+	} else {
+		SetLogLevels("debug")
+	}
+}
+
+func parseLevel(name string) (int, bool) {
+	for i, lvlName := range logLevelNames {
+		if strings.EqualFold(strings.TrimSpace(lvlName), name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// levelOf returns log level of the package (Package path is like "bayserver-core/baykit/bayserver/common/warpship")
+func (cfg *levelConfig) levelOf(pkgPath string) int {
+	if len(cfg.pkgs) == 0 {
+		return cfg.global
+	}
+
+	if lvl, ok := cfg.cache.Load(pkgPath); ok {
+		return lvl.(int)
+	}
+
+	lvl := cfg.global
+	dirs := strings.Split(pkgPath, "/")
+search:
+	for end := len(dirs); end > 0; end-- {
+		for start := end - 1; start >= 0; start-- {
+			if l, ok := cfg.pkgs[strings.Join(dirs[start:end], "/")]; ok {
+				lvl = l
+				break search
+			}
+		}
+	}
+
+	cfg.cache.Store(pkgPath, lvl)
+	return lvl
+}
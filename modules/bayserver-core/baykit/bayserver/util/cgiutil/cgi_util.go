@@ -2,6 +2,7 @@ package cgiutil
 
 import (
 	"bayserver-core/baykit/bayserver/bayserver"
+	"bayserver-core/baykit/bayserver/common/tracing"
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/headers"
 	"os"
@@ -47,6 +48,9 @@ const SERVER_ADMIN = "SERVER_ADMIN"
 const REQUEST_TIME_FLOAT = "REQUEST_TIME_FLOAT"
 const REQUEST_TIME = "REQUEST_TIME"
 const UNIQUE_ID = "UNIQUE_ID"
+const REQUEST_ID = "REQUEST_ID"
+const TRACEPARENT = "TRACEPARENT"
+const TRACESTATE = "TRACESTATE"
 
 type CallBack func(name string, value string)
 
@@ -145,6 +149,22 @@ func GetEnv2(path string, docRoot string, scriptBase string, tur tour.Tour, cb C
 		addEnv(cb, PATH, os.Getenv("PATH"))
 	}
 
+	if tur.Req().RequestId() != "" {
+		addEnv(cb, REQUEST_ID, tur.Req().RequestId())
+	}
+
+	if tur.Span() != nil {
+		// Trace context for OpenTelemetry SDKs
+		addEnv(cb, TRACEPARENT, reqHeaders.Get(tracing.HEADER_TRACEPARENT))
+		if state := reqHeaders.Get(tracing.HEADER_TRACESTATE); state != "" {
+			addEnv(cb, TRACESTATE, state)
+		}
+	}
+
+	for _, nv := range tur.Req().Variables() {
+		addEnv(cb, nv[0], nv[1])
+	}
+
 }
 
 func addEnv(cb CallBack, key string, value string) {
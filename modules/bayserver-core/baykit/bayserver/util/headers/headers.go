@@ -24,6 +24,9 @@ const AUTHORIZATION = "Authorization"
 const WWW_AUTHENTICATE = "WWW-Authenticate"
 const STATUS = "Status"
 const LOCATION = "Location"
+const LINK = "Link"
+const EXPECT = "Expect"
+const PRIORITY = "Priority"
 const HOST = "Host"
 const COOKIE = "Cookie"
 const USER_AGENT = "User-Agent"
@@ -37,6 +40,8 @@ const X_FORWARDED_FOR = "X-Forwarded-For"
 const X_FORWARDED_PROTO = "X-Forwarded-Proto"
 const X_FORWARDED_PORT = "X-Forwarded-Port"
 
+const EXPECT_100_CONTINUE = "100-continue"
+
 const CONNECTION_TYPE_CLOSE = 1
 const CONNECTION_TYPE_KEEP_ALIVE = 2
 const CONNECTION_TYPE_UPGRADE = 3
@@ -4,7 +4,11 @@ import (
 	"strconv"
 )
 
+const CONTINUE int = 100
+const SWITCHING_PROTOCOLS int = 101
+const EARLY_HINTS int = 103
 const OK int = 200
+const NO_CONTENT int = 204
 const MOVED_PERMANENTLY int = 301
 const MOVED_TEMPORARILY int = 302
 const NOT_MODIFIED int = 304
@@ -12,7 +16,11 @@ const BAD_REQUEST int = 400
 const UNAUTHORIZED int = 401
 const FORBIDDEN int = 403
 const NOT_FOUND int = 404
+const METHOD_NOT_ALLOWED int = 405
+const REQUEST_ENTITY_TOO_LARGE int = 413
+const EXPECTATION_FAILED int = 417
 const UPGRADE_REQUIRED int = 426
+const REQUEST_HEADER_FIELDS_TOO_LARGE int = 431
 const INTERNAL_SERVER_ERROR int = 500
 const SERVICE_UNAVAILABLE int = 503
 const GATEWAY_TIMEOUT int = 504
@@ -0,0 +1,156 @@
+package logsink
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DEFAULT_QUEUE_SIZE = 1000
+const RETRY_INTERVAL_SEC = 5
+const FLUSH_POLL_INTERVAL_MSEC = 10
+
+/**
+ * Writes records to the sink in background. Write never blocks:
+ * records are dropped when the queue is full (the sink is slow or down).
+ */
+type AsyncWriter struct {
+	sink      LogSink
+	queue     chan []byte
+	dropped   int64
+	pending   int64
+	closed    int32
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func NewAsyncWriter(sink LogSink, queueSize int) *AsyncWriter {
+	if queueSize <= 0 {
+		queueSize = DEFAULT_QUEUE_SIZE
+	}
+	w := &AsyncWriter{
+		sink:    sink,
+		queue:   make(chan []byte, queueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) Sink() LogSink {
+	return w.sink
+}
+
+// Write queues the record. Returns false if the record is dropped.
+func (w *AsyncWriter) Write(rec []byte) bool {
+	if atomic.LoadInt32(&w.closed) != 0 {
+		atomic.AddInt64(&w.dropped, 1)
+		return false
+	}
+
+	atomic.AddInt64(&w.pending, 1)
+	select {
+	case w.queue <- rec:
+		return true
+	default:
+		atomic.AddInt64(&w.pending, -1)
+		atomic.AddInt64(&w.dropped, 1)
+		return false
+	}
+}
+
+// Flush waits until the queued records are written or the timeout expires.
+// Returns false on timeout.
+func (w *AsyncWriter) Flush(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&w.pending) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(FLUSH_POLL_INTERVAL_MSEC * time.Millisecond)
+	}
+	return true
+}
+
+// Close flushes the queued records and closes the sink, waiting up to the timeout.
+// Records written after Close are dropped. Returns false on timeout.
+func (w *AsyncWriter) Close(timeout time.Duration) bool {
+	start := time.Now()
+	flushed := w.Flush(timeout)
+
+	w.closeOnce.Do(func() {
+		atomic.StoreInt32(&w.closed, 1)
+		close(w.done)
+	})
+
+	remain := timeout - time.Since(start)
+	if remain <= 0 {
+		return false
+	}
+	select {
+	case <-w.stopped:
+		return flushed
+	case <-time.After(remain):
+		return false
+	}
+}
+
+// Dropped returns the number of records dropped so far
+func (w *AsyncWriter) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+func (w *AsyncWriter) run() {
+	var out io.WriteCloser
+	var reported int64 = 0
+
+	defer func() {
+		if out != nil {
+			out.Close()
+		}
+		close(w.stopped)
+	}()
+
+	for {
+		var rec []byte
+		select {
+		case rec = <-w.queue:
+		case <-w.done:
+			return
+		}
+
+		for out == nil {
+			var ioerr error
+			out, ioerr = w.sink.Open()
+			if ioerr != nil {
+				// Cannot use baylog here (baylog writes to this writer)
+				fmt.Fprintf(os.Stderr, "Cannot open log sink: %s: %s\n", w.sink, ioerr)
+				out = nil
+				select {
+				case <-time.After(RETRY_INTERVAL_SEC * time.Second):
+				case <-w.done:
+					return
+				}
+			}
+		}
+
+		if dropped := w.Dropped(); dropped != reported {
+			msg := w.sink.Format(SEVERITY_WARNING, fmt.Sprintf("%d log records dropped", dropped-reported))
+			reported = dropped
+			out.Write(msg)
+		}
+
+		_, err := out.Write(rec)
+		atomic.AddInt64(&w.pending, -1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot write to log sink: %s: %s\n", w.sink, err)
+			out.Close()
+			out = nil
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package logsink
+
+import (
+	"bayserver-core/baykit/bayserver/util/exception"
+	"io"
+	"net/url"
+	"strings"
+)
+
+/** Severities (RFC 5424) */
+const SEVERITY_EMERG = 0
+const SEVERITY_ALERT = 1
+const SEVERITY_CRIT = 2
+const SEVERITY_ERR = 3
+const SEVERITY_WARNING = 4
+const SEVERITY_NOTICE = 5
+const SEVERITY_INFO = 6
+const SEVERITY_DEBUG = 7
+
+const TARGET_STDOUT = "stdout"
+const TARGET_STDERR = "stderr"
+const TARGET_SYSLOG = "syslog"
+
+/**
+ * Destination of log messages other than local files
+ *
+ * Targets:
+ *   stdout, stderr
+ *   syslog://host:port            (UDP)
+ *   syslog+udp://host:port
+ *   syslog+tcp://host:port
+ *   syslog+unix:///dev/log
+ * Syslog targets accept "facility" and "tag" query parameters.
+ *  e.g. syslog+tcp://127.0.0.1:601?facility=local0&tag=bayserver
+ */
+type LogSink interface {
+	String() string
+
+	// Open opens connection to the destination
+	Open() (io.WriteCloser, exception.IOException)
+
+	// Format makes a record from a message (which does not contain newline)
+	Format(severity int, msg string) []byte
+
+	// Timestamped returns true if the sink adds timestamp to records
+	Timestamped() bool
+}
+
+// IsSinkTarget returns true if the target is not a local file
+func IsSinkTarget(target string) bool {
+	lower := strings.ToLower(target)
+	return lower == TARGET_STDOUT || lower == TARGET_STDERR || strings.HasPrefix(lower, TARGET_SYSLOG+":") || strings.HasPrefix(lower, TARGET_SYSLOG+"+")
+}
+
+// NewLogSink creates sink of the target. defaultTag is used as APP-NAME of syslog if tag is not specified.
+func NewLogSink(target string, defaultTag string) (LogSink, exception.Exception) {
+	switch strings.ToLower(target) {
+	case TARGET_STDOUT:
+		return NewStdSink(false), nil
+
+	case TARGET_STDERR:
+		return NewStdSink(true), nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, exception.NewExceptionFromError(err)
+	}
+
+	var network string
+	var address string
+	switch strings.ToLower(u.Scheme) {
+	case TARGET_SYSLOG, TARGET_SYSLOG + "+udp":
+		network = "udp"
+		address = u.Host
+		if u.Port() == "" {
+			address += ":514"
+		}
+
+	case TARGET_SYSLOG + "+tcp":
+		network = "tcp"
+		address = u.Host
+		if u.Port() == "" {
+			address += ":601"
+		}
+
+	case TARGET_SYSLOG + "+unix":
+		network = "unix"
+		address = u.Path
+
+	default:
+		return nil, exception.NewException("Unknown log target: %s", target)
+	}
+
+	if address == "" {
+		return nil, exception.NewException("Address not specified: %s", target)
+	}
+
+	facility := FACILITY_USER
+	if name := u.Query().Get("facility"); name != "" {
+		var ok bool
+		facility, ok = facilityMap[strings.ToLower(name)]
+		if !ok {
+			return nil, exception.NewException("Unknown syslog facility: %s", name)
+		}
+	}
+
+	tag := u.Query().Get("tag")
+	if tag == "" {
+		tag = defaultTag
+	}
+
+	return NewSyslogSink(network, address, facility, tag), nil
+}
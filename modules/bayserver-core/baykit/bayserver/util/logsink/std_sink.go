@@ -0,0 +1,70 @@
+package logsink
+
+import (
+	"bayserver-core/baykit/bayserver/util/exception"
+	"io"
+	"os"
+)
+
+/**
+ * Writes log records to stdout or stderr (For containers)
+ */
+type StdSink struct {
+	stderr bool
+}
+
+func NewStdSink(stderr bool) *StdSink {
+	s := &StdSink{
+		stderr: stderr,
+	}
+
+	var _ LogSink = s // implement check
+	return s
+}
+
+func (s *StdSink) String() string {
+	if s.stderr {
+		return TARGET_STDERR
+	} else {
+		return TARGET_STDOUT
+	}
+}
+
+/****************************************/
+/* Implements LogSink                   */
+/****************************************/
+
+func (s *StdSink) Open() (io.WriteCloser, exception.IOException) {
+	if s.stderr {
+		return &stdWriter{os.Stderr}, nil
+	} else {
+		return &stdWriter{os.Stdout}, nil
+	}
+}
+
+func (s *StdSink) Format(severity int, msg string) []byte {
+	return []byte(msg + "\n")
+}
+
+func (s *StdSink) Timestamped() bool {
+	return false
+}
+
+/****************************************/
+/* type stdWriter                       */
+/****************************************/
+
+/**
+ * Writer which does not close stdout/stderr
+ */
+type stdWriter struct {
+	file *os.File
+}
+
+func (w *stdWriter) Write(buf []byte) (int, error) {
+	return w.file.Write(buf)
+}
+
+func (w *stdWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,157 @@
+package logsink
+
+import (
+	"bayserver-core/baykit/bayserver/util/exception"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+/** Facilities (RFC 5424) */
+const FACILITY_KERN = 0
+const FACILITY_USER = 1
+const FACILITY_DAEMON = 3
+const FACILITY_AUTH = 4
+const FACILITY_SYSLOG = 5
+const FACILITY_LOCAL0 = 16
+
+var facilityMap = map[string]int{
+	"kern":     FACILITY_KERN,
+	"user":     FACILITY_USER,
+	"mail":     2,
+	"daemon":   FACILITY_DAEMON,
+	"auth":     FACILITY_AUTH,
+	"syslog":   FACILITY_SYSLOG,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   FACILITY_LOCAL0,
+	"local1":   FACILITY_LOCAL0 + 1,
+	"local2":   FACILITY_LOCAL0 + 2,
+	"local3":   FACILITY_LOCAL0 + 3,
+	"local4":   FACILITY_LOCAL0 + 4,
+	"local5":   FACILITY_LOCAL0 + 5,
+	"local6":   FACILITY_LOCAL0 + 6,
+	"local7":   FACILITY_LOCAL0 + 7,
+}
+
+const DIAL_TIMEOUT_SEC = 5
+
+/**
+ * Sends log records to syslog server in RFC 5424 format
+ * (TCP and unix stream socket use octet counting framing of RFC 6587)
+ *
+ * The sink is not changed after it is created, because Format is called from
+ * any goroutine. Framing depends on the connection and is done by the writer.
+ */
+type SyslogSink struct {
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostName string
+	pid      string
+}
+
+func NewSyslogSink(network string, address string, facility int, tag string) *SyslogSink {
+	hostName, err := os.Hostname()
+	if err != nil || hostName == "" {
+		hostName = "-"
+	}
+	if tag == "" {
+		tag = "-"
+	}
+
+	s := &SyslogSink{
+		network:  network,
+		address:  address,
+		facility: facility,
+		tag:      tag,
+		hostName: hostName,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+
+	var _ LogSink = s // implement check
+	return s
+}
+
+func (s *SyslogSink) String() string {
+	return TARGET_SYSLOG + "+" + s.network + "://" + s.address
+}
+
+/****************************************/
+/* Implements LogSink                   */
+/****************************************/
+
+func (s *SyslogSink) Open() (io.WriteCloser, exception.IOException) {
+	if s.network == "unix" {
+		// Syslog daemons usually listen on datagram socket
+		conn, err := net.DialTimeout("unixgram", s.address, DIAL_TIMEOUT_SEC*time.Second)
+		if err == nil {
+			return conn, nil
+		}
+		conn, err = net.DialTimeout("unix", s.address, DIAL_TIMEOUT_SEC*time.Second)
+		if err != nil {
+			return nil, exception.NewIOExceptionFromError(err)
+		}
+		return &streamWriter{conn}, nil
+	}
+
+	conn, err := net.DialTimeout(s.network, s.address, DIAL_TIMEOUT_SEC*time.Second)
+	if err != nil {
+		return nil, exception.NewIOExceptionFromError(err)
+	}
+	if s.network == "tcp" {
+		return &streamWriter{conn}, nil
+	}
+	return conn, nil
+}
+
+// Format makes RFC 5424 record: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+func (s *SyslogSink) Format(severity int, msg string) []byte {
+	pri := s.facility*8 + severity
+	rec := "<" + strconv.Itoa(pri) + ">1 " +
+		time.Now().Format("2006-01-02T15:04:05.000000Z07:00") + " " +
+		s.hostName + " " +
+		s.tag + " " +
+		s.pid + " - - " +
+		msg
+
+	return []byte(rec)
+}
+
+func (s *SyslogSink) Timestamped() bool {
+	return true
+}
+
+/****************************************/
+/* type streamWriter                    */
+/****************************************/
+
+/**
+ * Writer which adds octet counting frame (RFC 6587) to each record
+ */
+type streamWriter struct {
+	conn net.Conn
+}
+
+func (w *streamWriter) Write(rec []byte) (int, error) {
+	buf := make([]byte, 0, len(rec)+8)
+	buf = strconv.AppendInt(buf, int64(len(rec)), 10)
+	buf = append(buf, ' ')
+	buf = append(buf, rec...)
+	_, err := w.conn.Write(buf)
+	if err != nil {
+		return 0, err
+	}
+	return len(rec), nil
+}
+
+func (w *streamWriter) Close() error {
+	return w.conn.Close()
+}
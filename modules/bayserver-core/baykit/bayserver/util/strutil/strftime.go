@@ -0,0 +1,95 @@
+package strutil
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var strftimeLayouts = map[byte]string{
+	'a': "Mon",
+	'A': "Monday",
+	'b': "Jan",
+	'B': "January",
+	'c': "Mon Jan _2 15:04:05 2006",
+	'd': "02",
+	'D': "01/02/06",
+	'e': "_2",
+	'F': "2006-01-02",
+	'h': "Jan",
+	'H': "15",
+	'I': "03",
+	'j': "002",
+	'm': "01",
+	'M': "04",
+	'p': "PM",
+	'r': "03:04:05 PM",
+	'R': "15:04",
+	'S': "05",
+	'T': "15:04:05",
+	'x': "01/02/06",
+	'X': "15:04:05",
+	'y': "06",
+	'Y': "2006",
+	'z': "-0700",
+	'Z': "MST",
+}
+
+/**
+ * Formats time in strftime(3) format. (Unknown conversions are printed as they are)
+ */
+func Strftime(format string, t time.Time) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' || i == len(format)-1 {
+			sb.WriteByte(c)
+			continue
+		}
+
+		i++
+		c = format[i]
+		if layout, ok := strftimeLayouts[c]; ok {
+			sb.WriteString(t.Format(layout))
+			continue
+		}
+
+		switch c {
+		case 'k':
+			sb.WriteString(padLeft(strconv.Itoa(t.Hour()), 2))
+		case 'l':
+			hour := t.Hour() % 12
+			if hour == 0 {
+				hour = 12
+			}
+			sb.WriteString(padLeft(strconv.Itoa(hour), 2))
+		case 's':
+			sb.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'u':
+			wday := int(t.Weekday())
+			if wday == 0 {
+				wday = 7
+			}
+			sb.WriteString(strconv.Itoa(wday))
+		case 'w':
+			sb.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case '%':
+			sb.WriteByte('%')
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func padLeft(s string, width int) string {
+	for len(s) < width {
+		s = " " + s
+	}
+	return s
+}
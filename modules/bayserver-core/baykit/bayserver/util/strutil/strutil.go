@@ -2,6 +2,7 @@ package strutil
 
 import (
 	"bayserver-core/baykit/bayserver/util/exception"
+	"fmt"
 	"golang.org/x/text/encoding/htmlindex"
 	"slices"
 	"strconv"
@@ -80,3 +81,31 @@ func EndsWith(str, end string) bool {
 func Indent(count int) string {
 	return strings.Repeat(" ", count)
 }
+
+// JsonQuote makes JSON string literal (Control characters are escaped)
+func JsonQuote(str string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range str {
+		switch c {
+		case '"':
+			sb.WriteString("\\\"")
+		case '\\':
+			sb.WriteString("\\\\")
+		case '\n':
+			sb.WriteString("\\n")
+		case '\r':
+			sb.WriteString("\\r")
+		case '\t':
+			sb.WriteString("\\t")
+		default:
+			if c < 0x20 || c == 0x7f {
+				sb.WriteString(fmt.Sprintf("\\u%04x", c))
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
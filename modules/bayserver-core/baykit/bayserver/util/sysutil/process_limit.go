@@ -0,0 +1,13 @@
+package sysutil
+
+// ProcessLimits holds resource limits of child process. Zero means unlimited.
+type ProcessLimits struct {
+	CpuSec      int // RLIMIT_CPU
+	MemoryBytes int // RLIMIT_AS
+	OpenFiles   int // RLIMIT_NOFILE
+	FileBytes   int // RLIMIT_FSIZE
+}
+
+func (l *ProcessLimits) Empty() bool {
+	return l.CpuSec <= 0 && l.MemoryBytes <= 0 && l.OpenFiles <= 0 && l.FileBytes <= 0
+}
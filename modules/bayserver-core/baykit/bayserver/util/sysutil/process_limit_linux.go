@@ -0,0 +1,102 @@
+//go:build linux
+
+package sysutil
+
+import (
+	"bayserver-core/baykit/bayserver/util/exception"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Environment variable which makes this executable run as the wrapper of a child process.
+// Value format: [cpu]:[memory]:[files]:[output]:[path]
+const ENV_PROCESS_LIMITS = "BSERV_PROCESS_LIMITS"
+
+func init() {
+	execWithLimits()
+}
+
+// SetProcessLimits makes the command set resource limits before the program is executed.
+// The command is changed to run this executable as a wrapper, which sets the limits
+// to itself and then executes the program (See execWithLimits).
+func SetProcessLimits(cmd *exec.Cmd, limits *ProcessLimits) exception.IOException {
+	if cmd.Err != nil {
+		// Start will fail
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return exception.NewIOException("Cannot get executable path: %s", err)
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d:%d:%d:%d:%s",
+		ENV_PROCESS_LIMITS, limits.CpuSec, limits.MemoryBytes, limits.OpenFiles, limits.FileBytes, cmd.Path))
+	cmd.Path = self
+	return nil
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+// execWithLimits sets resource limits and executes the program if this process is started as the wrapper.
+// It is called before main so that nothing else runs in the wrapper.
+func execWithLimits() {
+	value, ok := os.LookupEnv(ENV_PROCESS_LIMITS)
+	if !ok {
+		return
+	}
+
+	items := strings.SplitN(value, ":", 5)
+	if len(items) != 5 {
+		execFailed("Invalid %s: %s", ENV_PROCESS_LIMITS, value)
+	}
+
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, ENV_PROCESS_LIMITS+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	for _, lim := range []struct {
+		resource int
+		value    string
+	}{
+		{syscall.RLIMIT_CPU, items[0]},
+		{syscall.RLIMIT_NOFILE, items[2]},
+		{syscall.RLIMIT_FSIZE, items[3]},
+		// RLIMIT_AS is set at last to keep memory available for this process as long as possible
+		{syscall.RLIMIT_AS, items[1]},
+	} {
+		value, err := strconv.Atoi(lim.value)
+		if err != nil {
+			execFailed("Invalid %s: %s", ENV_PROCESS_LIMITS, lim.value)
+		}
+		if value <= 0 {
+			continue
+		}
+
+		rlim := syscall.Rlimit{Cur: uint64(value), Max: uint64(value)}
+		err = syscall.Setrlimit(lim.resource, &rlim)
+		if err != nil {
+			execFailed("Cannot set resource limit: resource=%d (%s)", lim.resource, err)
+		}
+	}
+
+	err := syscall.Exec(items[4], os.Args, env)
+	execFailed("Cannot execute %s: %s", items[4], err)
+}
+
+func execFailed(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(127)
+}
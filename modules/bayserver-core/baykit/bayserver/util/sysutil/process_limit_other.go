@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sysutil
+
+import (
+	"bayserver-core/baykit/bayserver/util/exception"
+	"os/exec"
+)
+
+// SetProcessLimits makes the command set resource limits before the program is executed
+func SetProcessLimits(cmd *exec.Cmd, limits *ProcessLimits) exception.IOException {
+	return exception.NewIOException("Setting resource limits of process is not supported")
+}
@@ -0,0 +1,105 @@
+//go:build unix
+
+package sysutil
+
+import (
+	"bayserver-core/baykit/bayserver/util/exception"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// SetProcessUser makes the command run as the user (name or numeric id)
+func SetProcessUser(cmd *exec.Cmd, userName string) exception.IOException {
+	u, err := user.Lookup(userName)
+	if err != nil {
+		u, err = user.LookupId(userName)
+		if err != nil {
+			return exception.NewIOException("Unknown user: %s", userName)
+		}
+	}
+
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+
+	SetProcessCredential(cmd, uid, gid)
+	return nil
+}
+
+// SetProcessGroup makes the command run with the group (name or numeric id)
+func SetProcessGroup(cmd *exec.Cmd, groupName string) exception.IOException {
+	g, err := user.LookupGroup(groupName)
+	if err != nil {
+		g, err = user.LookupGroupId(groupName)
+		if err != nil {
+			return exception.NewIOException("Unknown group: %s", groupName)
+		}
+	}
+
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+
+	uid := os.Getuid()
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Credential != nil {
+		uid = int(cmd.SysProcAttr.Credential.Uid)
+	}
+	SetProcessCredential(cmd, uid, gid)
+	return nil
+}
+
+// SetProcessCredential makes the command run as the uid and gid
+func SetProcessCredential(cmd *exec.Cmd, uid int, gid int) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+}
+
+// GetFileOwner returns uid and gid of the file
+func GetFileOwner(path string) (int, int, exception.IOException) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1, -1, exception.NewIOExceptionFromError(err)
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, exception.NewIOException("Cannot get file owner: %s", path)
+	}
+	return int(st.Uid), int(st.Gid), nil
+}
+
+// SetNewProcessGroup makes the command run in a new process group
+func SetNewProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// KillProcessGroup kills all processes in the process group led by the process
+func KillProcessGroup(proc *os.Process) exception.IOException {
+	err := syscall.Kill(-proc.Pid, syscall.SIGKILL)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+	return nil
+}
+
+// TerminateProcess asks the process to exit
+func TerminateProcess(proc *os.Process) exception.IOException {
+	err := proc.Signal(syscall.SIGTERM)
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+	return nil
+}
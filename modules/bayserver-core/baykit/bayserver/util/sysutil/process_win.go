@@ -0,0 +1,46 @@
+//go:build windows
+
+package sysutil
+
+import (
+	"bayserver-core/baykit/bayserver/util/exception"
+	"os"
+	"os/exec"
+)
+
+// SetProcessUser makes the command run as the user (name or numeric id)
+func SetProcessUser(cmd *exec.Cmd, userName string) exception.IOException {
+	return exception.NewIOException("Changing process user is not supported: %s", userName)
+}
+
+// SetProcessGroup makes the command run with the group (name or numeric id)
+func SetProcessGroup(cmd *exec.Cmd, groupName string) exception.IOException {
+	return exception.NewIOException("Changing process group is not supported: %s", groupName)
+}
+
+// SetProcessCredential makes the command run as the uid and gid
+func SetProcessCredential(cmd *exec.Cmd, uid int, gid int) {
+}
+
+// GetFileOwner returns uid and gid of the file
+func GetFileOwner(path string) (int, int, exception.IOException) {
+	return -1, -1, exception.NewIOException("Getting file owner is not supported: %s", path)
+}
+
+// SetNewProcessGroup makes the command run in a new process group
+func SetNewProcessGroup(cmd *exec.Cmd) {
+}
+
+// KillProcessGroup kills all processes in the process group led by the process
+func KillProcessGroup(proc *os.Process) exception.IOException {
+	return TerminateProcess(proc)
+}
+
+// TerminateProcess asks the process to exit
+func TerminateProcess(proc *os.Process) exception.IOException {
+	err := proc.Kill()
+	if err != nil {
+		return exception.NewIOExceptionFromError(err)
+	}
+	return nil
+}
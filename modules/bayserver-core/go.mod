@@ -2,8 +2,9 @@ module bayserver-core
 
 go 1.21
 
+require github.com/google/uuid v1.6.0
+
 require (
-	github.com/google/uuid v1.6.0 // indirect
 	golang.org/x/crypto v0.24.0 // indirect
 	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sys v0.21.0 // indirect
@@ -15,5 +15,7 @@ type AjpCommandHandler interface {
 	HandleSendHeaders(cmd *CmdSendHeaders) (common.NextSocketAction, exception.IOException)
 	HandleShutdown(cmd *CmdShutdown) (common.NextSocketAction, exception.IOException)
 	HandleGetBodyChunk(cmd *CmdGetBodyChunk) (common.NextSocketAction, exception.IOException)
+	HandleCPing(cmd *CmdCPing) (common.NextSocketAction, exception.IOException)
+	HandleCPong(cmd *CmdCPong) (common.NextSocketAction, exception.IOException)
 	NeedData() bool
 }
@@ -51,6 +51,12 @@ func (cu *AjpCommandUnpacker) PacketReceived(pkt protocol.Packet) (common.NextSo
 	case AJP_TYPE_GET_BODY_CHUNK:
 		cmd = NewCmdGetBodyChunk()
 
+	case AJP_TYPE_CPING:
+		cmd = NewCmdCPing()
+
+	case AJP_TYPE_CPONG:
+		cmd = NewCmdCPong()
+
 	default:
 		baylog.FatalE(exception2.NewSink("Illegal State"), "")
 		panic("Error")
@@ -13,14 +13,25 @@ import (
 	"bayserver-core/baykit/bayserver/tour/impl"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/headers"
 	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"crypto/subtle"
 )
 
 const COMMAND_STATE_READ_FORWARD_REQUEST = 1
 const COMMAND_STATE_READ_DATA = 2
+const COMMAND_STATE_REJECTED = 3
 
 const AJP_DUMMY_KEY = 1
 
+// SSL attributes and the CGI variable names (same as mod_ssl) to which they are passed
+var sslVariables = [][]string{
+	{"?ssl_cipher", "SSL_CIPHER"},
+	{"?ssl_key_size", "SSL_CIPHER_USEKEYSIZE"},
+	{"?ssl_session", "SSL_SESSION_ID"},
+	{"?ssl_cert", "SSL_CLIENT_CERT"},
+}
+
 type AjpInboundHandler struct {
 	protocolHandler *AjpProtocolHandlerImpl
 	curTourId       int
@@ -86,6 +97,11 @@ func (h *AjpInboundHandler) SendHeaders(tur tour.Tour) exception2.IOException {
 	return h.protocolHandler.Post(cmd, nil)
 }
 
+func (h *AjpInboundHandler) SendInformational(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	// AJP has no way to send interim responses
+	return nil
+}
+
 func (h *AjpInboundHandler) SendContent(tur tour.Tour, bytes []byte, ofs int, length int, lis common.DataConsumeListener) exception2.IOException {
 	cmd := NewCmdSendBodyChunk(bytes, ofs, length)
 	return h.protocolHandler.Post(cmd, lis)
@@ -135,6 +151,20 @@ func (h *AjpInboundHandler) HandleForwardRequest(cmd *CmdForwardRequest) (common
 
 	h.keeping = false
 	h.reqCommand = cmd
+
+	secret := sip.PortDocker().(AjpPortDocker).Secret()
+	if secret != "" && subtle.ConstantTimeCompare([]byte(cmd.Attributes["?secret"]), []byte(secret)) != 1 {
+		baylog.Warn("%s AJP secret mismatch: remote=%s", sip, cmd.RemoteAddr)
+		tur := sip.GetErrorTour()
+		ioerr := tur.Res().SendError(impl.TOUR_ID_NOCHECK, httpstatus.FORBIDDEN, "Invalid secret", nil)
+		if ioerr != nil {
+			return -1, ioerr
+		}
+		// Connection is closed after the error is sent. Discards request content until then
+		h.changeState(COMMAND_STATE_REJECTED)
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+
 	tur := sip.GetTour(AJP_DUMMY_KEY, false, true)
 
 	if tur == nil {
@@ -225,6 +255,10 @@ func (h *AjpInboundHandler) HandleData(cmd *CmdData) (common.NextSocketAction, e
 	sip := h.Ship()
 	baylog.Debug("%s handleData len=%d", sip, cmd.Length)
 
+	if h.state == COMMAND_STATE_REJECTED {
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+
 	if h.state != COMMAND_STATE_READ_DATA {
 		return -1, exception.NewProtocolException("Invalid Ajp Command: %d", cmd.Type())
 	}
@@ -328,6 +362,24 @@ func (h *AjpInboundHandler) HandleGetBodyChunk(cmd *CmdGetBodyChunk) (common.Nex
 	return -1, exception.NewProtocolException("Invalid Ajp Command: %d", cmd.Type())
 }
 
+func (h *AjpInboundHandler) HandleCPing(cmd *CmdCPing) (common.NextSocketAction, exception2.IOException) {
+	baylog.Debug("%s handleCPing", h.Ship())
+
+	if h.state != COMMAND_STATE_READ_FORWARD_REQUEST {
+		return -1, exception.NewProtocolException("Ajpi: Invalid command: %d state=%d", cmd.Type(), h.state)
+	}
+
+	ioerr := h.protocolHandler.Post(NewCmdCPong(), nil)
+	if ioerr != nil {
+		return -1, ioerr
+	}
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+func (h *AjpInboundHandler) HandleCPong(cmd *CmdCPong) (common.NextSocketAction, exception2.IOException) {
+	return -1, exception.NewProtocolException("Invalid Ajp Command: %d", cmd.Type())
+}
+
 func (h *AjpInboundHandler) NeedData() bool {
 	return h.state == COMMAND_STATE_READ_DATA
 }
@@ -359,6 +411,7 @@ func (h *AjpInboundHandler) endReqContent(tur tour.Tour) (exception2.IOException
 
 func (h *AjpInboundHandler) startTour(tur tour.Tour) exception.HttpException {
 	req := tur.Req()
+
 	if h.reqCommand.IsSsl {
 		req.ParseHostPort(443)
 	} else {
@@ -375,5 +428,12 @@ func (h *AjpInboundHandler) startTour(tur tour.Tour) exception.HttpException {
 	req.SetServerName(h.reqCommand.ServerName)
 	tur.SetSecure(h.reqCommand.IsSsl)
 
+	for _, nv := range sslVariables {
+		value, ok := h.reqCommand.Attributes[nv[0]]
+		if ok {
+			req.AddVariable(nv[1], value)
+		}
+	}
+
 	return tur.Go()
 }
@@ -1,4 +1,6 @@
 package ajp
 
 type AjpPortDocker interface {
+	// Secret returns the shared secret which must be sent by the web server (Empty if not required)
+	Secret() string
 }
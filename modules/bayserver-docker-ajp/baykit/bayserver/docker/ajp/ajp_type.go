@@ -12,4 +12,5 @@ const AJP_TYPE_END_RESPONSE = 5
 const AJP_TYPE_GET_BODY_CHUNK = 6
 const AJP_TYPE_SHUTDOWN = 7
 const AJP_TYPE_PING = 8
+const AJP_TYPE_CPONG = 9
 const AJP_TYPE_CPING = 10
@@ -1,4 +1,19 @@
 package ajp
 
+const DEFAULT_PING_TIMEOUT_SEC = 10
+const DEFAULT_PING_INTERVAL_SEC = 60
+const DEFAULT_PING_ON_REUSE = true
+
 type AjpWarpDocker interface {
+	// Secret returns the shared secret which is sent to the backend server (Empty if not sent)
+	Secret() string
+
+	// PingTimeoutSec returns how long to wait for CPong before the connection is regarded as dead (0 means CPing is not used)
+	PingTimeoutSec() int
+
+	// PingIntervalSec returns the interval of CPing sent through idle connections (0 means no probe)
+	PingIntervalSec() int
+
+	// PingOnReuse returns true if idle connection is validated by CPing before it is reused
+	PingOnReuse() bool
 }
@@ -6,16 +6,27 @@ import (
 	"bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/common/warpship"
 	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/rudder/impl"
+	"bayserver-core/baykit/bayserver/ship"
 	"bayserver-core/baykit/bayserver/tour"
 	tourimpl "bayserver-core/baykit/bayserver/tour/impl"
 	"bayserver-core/baykit/bayserver/util"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/headers"
+	"bayserver-core/baykit/bayserver/util/sysutil"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/pem"
+	"strconv"
 	"strings"
 )
 
 const FIXED_WARP_ID = 1
 
+const REQUEST_ID_ATTRIBUTE = "REQUEST_ID"
+
 const STATE_READ_HEADER = 1
 const STATE_READ_CONTENT = 2
 
@@ -23,16 +34,27 @@ type AjpWarpHandler struct {
 	protocolHandler *AjpProtocolHandlerImpl
 	state           int
 	contReadLen     int
+
+	// CPing is sent and CPong is not received yet
+	pinging  bool
+	pingTime int64
+	// Time when the connection is kept (0 if the connection is in use)
+	idleTime int64
+	// Commands held until CPong is received
+	pending     []*util.Pair[protocol.Command, common.DataConsumeListener]
+	pendingData bool
+	pendingEnd  bool
 }
 
 func NewAjpWarpHandler() *AjpWarpHandler {
 	h := &AjpWarpHandler{}
 	h.resetState()
 
-	var _ tour.TourHandler = h     // cast check
-	var _ warpship.WarpHandler = h // cast check
-	var _ AjpHandler = h           // cast check
-	var _ util.Reusable = h        // cast check
+	var _ tour.TourHandler = h          // cast check
+	var _ warpship.WarpHandler = h      // cast check
+	var _ AjpHandler = h                // cast check
+	var _ util.Reusable = h             // cast check
+	var _ warpship.WarpRetryHandler = h // cast check
 	return h
 }
 
@@ -51,6 +73,10 @@ func (h *AjpWarpHandler) String() string {
 func (h *AjpWarpHandler) Reset() {
 	h.resetState()
 	h.contReadLen = 0
+	h.pinging = false
+	h.pingTime = 0
+	h.idleTime = 0
+	h.clearPending()
 }
 
 /****************************************/
@@ -58,15 +84,27 @@ func (h *AjpWarpHandler) Reset() {
 /****************************************/
 
 func (h *AjpWarpHandler) SendHeaders(tur tour.Tour) exception2.IOException {
+	if h.idleTime > 0 && !h.pinging && h.Ship().Docker().(AjpWarpDocker).PingOnReuse() {
+		// Validates idle connection before sending request
+		ioerr := h.sendCPing()
+		if ioerr != nil {
+			return ioerr
+		}
+	}
+	h.idleTime = 0
 	return h.sendForwardRequest(tur)
 }
 
+func (h *AjpWarpHandler) SendInformational(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	return nil
+}
+
 func (h *AjpWarpHandler) SendContent(tur tour.Tour, buf []byte, start int, length int, lis common.DataConsumeListener) exception2.IOException {
 	return h.sendData(tur, buf, start, length, lis)
 }
 
 func (h *AjpWarpHandler) SendEnd(tour tour.Tour, keepAlive bool, lis common.DataConsumeListener) exception2.IOException {
-	return h.Ship().Post(nil, lis)
+	return h.post(nil, lis)
 }
 
 func (h *AjpWarpHandler) OnProtocolError(e exception.ProtocolException) (bool, exception2.IOException) {
@@ -90,6 +128,43 @@ func (h *AjpWarpHandler) VerifyProtocol(protocol string) exception2.IOException
 	return nil
 }
 
+/****************************************/
+/* Implements WarpRetryHandler          */
+/****************************************/
+
+func (h *AjpWarpHandler) RetryTour(tur tour.Tour) bool {
+	if !h.pinging || h.pendingData {
+		// Server might have received the request
+		return false
+	}
+
+	wsip := h.Ship()
+	baylog.Debug("%s Connection lost before CPong. Retry tour: %s", wsip, tur)
+
+	reqEnded := h.pendingEnd
+	h.pinging = false
+	h.clearPending()
+	wsip.EndWarpTour(tur, false)
+	tur.Req().ResetReqContentHandler()
+
+	hterr := wsip.Docker().(docker.Club).Arrive(tur)
+	if hterr != nil {
+		ioerr := tur.Res().SendHttpException(tourimpl.TOUR_ID_NOCHECK, hterr)
+		if ioerr != nil {
+			baylog.DebugE(ioerr, "")
+		}
+		return true
+	}
+
+	if reqEnded {
+		ioerr, _ := warpship.WarpDataGet(tur).OnEndReqContent(tur)
+		if ioerr != nil {
+			baylog.DebugE(ioerr, "")
+		}
+	}
+	return true
+}
+
 /****************************************/
 /* Implements AjpCommandHandler         */
 /****************************************/
@@ -117,6 +192,9 @@ func (h *AjpWarpHandler) HandleEndResponse(cmd *CmdEndResponse) (common.NextSock
 			}
 		}
 
+		if cmd.Reuse {
+			h.idleTime = sysutil.CurrentTimeSecs()
+		}
 		ioerr = h.endResContent(tur, cmd.Reuse)
 		if ioerr != nil {
 			break
@@ -229,6 +307,28 @@ func (h *AjpWarpHandler) HandleGetBodyChunk(cmd *CmdGetBodyChunk) (common.NextSo
 	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 }
 
+func (h *AjpWarpHandler) HandleCPing(cmd *CmdCPing) (common.NextSocketAction, exception2.IOException) {
+	return -1, exception.NewProtocolException("Invalid AJP command: %d", cmd.Type())
+}
+
+func (h *AjpWarpHandler) HandleCPong(cmd *CmdCPong) (common.NextSocketAction, exception2.IOException) {
+	baylog.Debug("%s handleCPong", h)
+
+	if !h.pinging {
+		return -1, exception.NewProtocolException("Unexpected CPong")
+	}
+	h.pinging = false
+
+	for _, cmdAndLis := range h.pending {
+		ioerr := h.Ship().Post(cmdAndLis.A, cmdAndLis.B)
+		if ioerr != nil {
+			return -1, ioerr
+		}
+	}
+	h.clearPending()
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
 func (h *AjpWarpHandler) NeedData() bool {
 	return false
 }
@@ -266,6 +366,58 @@ func (h *AjpWarpHandler) resetState() {
 	h.changeState(STATE_READ_HEADER)
 }
 
+// CheckPing is called periodically to send CPing through idle connection and to check CPong timeout
+func (h *AjpWarpHandler) CheckPing() {
+	wsip := h.Ship()
+	dkr := wsip.Docker().(AjpWarpDocker)
+	now := sysutil.CurrentTimeSecs()
+
+	if h.pinging {
+		if now-h.pingTime >= int64(dkr.PingTimeoutSec()) {
+			// Tour waiting for CPong is retried when the ship is closed
+			baylog.Warn("%s CPong timed out", wsip)
+			wsip.Abort(wsip.ShipId())
+		}
+
+	} else if h.idleTime > 0 && dkr.PingIntervalSec() > 0 &&
+		now-h.idleTime >= int64(dkr.PingIntervalSec()) && now-h.pingTime >= int64(dkr.PingIntervalSec()) {
+		ioerr := h.sendCPing()
+		if ioerr != nil {
+			baylog.DebugE(ioerr, "%s Cannot send CPing", wsip)
+			wsip.Abort(wsip.ShipId())
+		}
+	}
+}
+
+func (h *AjpWarpHandler) sendCPing() exception2.IOException {
+	baylog.Debug("%s send CPing", h)
+	h.pinging = true
+	h.pingTime = sysutil.CurrentTimeSecs()
+	return h.Ship().Post(NewCmdCPing(), nil)
+}
+
+// Holds command until CPong is received
+func (h *AjpWarpHandler) post(cmd protocol.Command, lis common.DataConsumeListener) exception2.IOException {
+	if !h.pinging {
+		return h.Ship().Post(cmd, lis)
+	}
+
+	switch cmd.(type) {
+	case *CmdData:
+		h.pendingData = true
+	case nil:
+		h.pendingEnd = true
+	}
+	h.pending = append(h.pending, util.NewPair(cmd, lis))
+	return nil
+}
+
+func (h *AjpWarpHandler) clearPending() {
+	h.pending = h.pending[:0]
+	h.pendingData = false
+	h.pendingEnd = false
+}
+
 func (h *AjpWarpHandler) sendForwardRequest(tur tour.Tour) exception2.IOException {
 	baylog.Debug("%s %s construct header", h, tur)
 	wsip := h.Ship()
@@ -300,6 +452,17 @@ func (h *AjpWarpHandler) sendForwardRequest(tur tour.Tour) exception2.IOExceptio
 	cmd.ServerName = tur.Req().ServerName()
 	cmd.ServerPort = tur.Req().ServerPort()
 	cmd.IsSsl = tur.Secure()
+	if cmd.IsSsl {
+		h.setSslAttributes(tur, cmd)
+	}
+	secret := wsip.Docker().(AjpWarpDocker).Secret()
+	if secret != "" {
+		cmd.Attributes["?secret"] = secret
+	}
+	if tur.Req().RequestId() != "" {
+		// Sent as request attribute
+		cmd.Attributes[REQUEST_ID_ATTRIBUTE] = tur.Req().RequestId()
+	}
 	tur.Req().Headers().CopyTo(cmd.Headers)
 	cmd.ServerPort = wsip.Docker().Port()
 
@@ -310,20 +473,77 @@ func (h *AjpWarpHandler) sendForwardRequest(tur tour.Tour) exception2.IOExceptio
 			}
 		}
 	}
-	ioerr := h.Ship().Post(cmd, nil)
+	ioerr := h.post(cmd, nil)
 	return ioerr
 }
 
+// Sets attributes of the TLS connection on which the tour arrived
+func (h *AjpWarpHandler) setSslAttributes(tur tour.Tour, cmd *CmdForwardRequest) {
+	sip, ok := tur.Ship().(ship.Ship)
+	if !ok {
+		return
+	}
+	rd, ok := sip.Rudder().(*impl.TcpConnRudder)
+	if !ok {
+		return
+	}
+	tlsConn, ok := rd.Conn.(*tls.Conn)
+	if !ok {
+		return
+	}
+
+	state := tlsConn.ConnectionState()
+	cmd.Attributes["?ssl_cipher"] = tls.CipherSuiteName(state.CipherSuite)
+	keySize := cipherKeySize(state.CipherSuite)
+	if keySize > 0 {
+		cmd.Attributes["?ssl_key_size"] = strconv.Itoa(keySize)
+	}
+
+	// TLS 1.3 has no session id, so an exported value unique to the connection is used instead
+	session, err := state.ExportKeyingMaterial("EXPORTER-AJP-SSL-SESSION", nil, 32)
+	if err == nil {
+		cmd.Attributes["?ssl_session"] = hex.EncodeToString(session)
+	}
+
+	if len(state.PeerCertificates) > 0 {
+		certs := strings.Builder{}
+		for _, cert := range state.PeerCertificates {
+			certs.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+		}
+		cmd.Attributes["?ssl_cert"] = certs.String()
+	}
+}
+
 func (h *AjpWarpHandler) sendData(tur tour.Tour, data []byte, ofs int, length int, lis common.DataConsumeListener) exception2.IOException {
 	baylog.Debug("%s construct contents", tur)
-	wsip := h.Ship()
 
 	cmd := NewCmdData(data, ofs, length)
 	cmd.SetToServer(true)
-	ioerr := wsip.Post(cmd, lis)
+	ioerr := h.post(cmd, lis)
 	return ioerr
 }
 
 func (h *AjpWarpHandler) Ship() warpship.WarpShip {
 	return h.protocolHandler.Ship().(warpship.WarpShip)
 }
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+// Returns the symmetric key size in bits of the cipher suite
+func cipherKeySize(id uint16) int {
+	name := tls.CipherSuiteName(id)
+	switch {
+	case strings.Contains(name, "AES_128"):
+		return 128
+	case strings.Contains(name, "AES_256"), strings.Contains(name, "CHACHA20"):
+		return 256
+	case strings.Contains(name, "3DES"):
+		return 168
+	case strings.Contains(name, "RC4_128"):
+		return 128
+	default:
+		return 0
+	}
+}
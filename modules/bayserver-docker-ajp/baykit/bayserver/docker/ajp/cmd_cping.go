@@ -0,0 +1,49 @@
+package ajp
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * CPing command format
+ *
+ * AJP13_CPING :=
+ *   prefix_code       10
+ */
+
+type CmdCPing struct {
+	*AjpCommandBase
+}
+
+func NewCmdCPing() *CmdCPing {
+	c := CmdCPing{
+		AjpCommandBase: NewAjpCommandBase(AJP_TYPE_CPING, true),
+	}
+	var _ protocol.Command = &c // cast check
+	var _ AjpCommand = &c       // cast check
+	return &c
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdCPing) Unpack(pkt protocol.Packet) exception2.IOException {
+	c.AjpCommandBase.Unpack(pkt.(*AjpPacket))
+	return nil
+}
+
+func (c *CmdCPing) Pack(pkt protocol.Packet) exception2.IOException {
+	acc := pkt.(*AjpPacket).NewAjpDataAccessor()
+	acc.PutByte(c.Type())
+
+	// must be called from last line
+	c.AjpCommandBase.Pack(pkt.(*AjpPacket))
+	return nil
+}
+
+func (c *CmdCPing) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
+	return h.(AjpCommandHandler).HandleCPing(c)
+}
@@ -0,0 +1,49 @@
+package ajp
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * CPong reply format
+ *
+ * AJP13_CPONG_REPLY :=
+ *   prefix_code       9
+ */
+
+type CmdCPong struct {
+	*AjpCommandBase
+}
+
+func NewCmdCPong() *CmdCPong {
+	c := CmdCPong{
+		AjpCommandBase: NewAjpCommandBase(AJP_TYPE_CPONG, false),
+	}
+	var _ protocol.Command = &c // cast check
+	var _ AjpCommand = &c       // cast check
+	return &c
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdCPong) Unpack(pkt protocol.Packet) exception2.IOException {
+	c.AjpCommandBase.Unpack(pkt.(*AjpPacket))
+	return nil
+}
+
+func (c *CmdCPong) Pack(pkt protocol.Packet) exception2.IOException {
+	acc := pkt.(*AjpPacket).NewAjpDataAccessor()
+	acc.PutByte(c.Type())
+
+	// must be called from last line
+	c.AjpCommandBase.Pack(pkt.(*AjpPacket))
+	return nil
+}
+
+func (c *CmdCPong) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
+	return h.(AjpCommandHandler).HandleCPong(c)
+}
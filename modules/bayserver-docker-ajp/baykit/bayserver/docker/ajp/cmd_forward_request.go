@@ -281,9 +281,17 @@ func (c *CmdForwardRequest) writeAttributes(acc *AjpAccessor) {
 		if code != -1 {
 			acc.PutByte(code)
 		} else {
+			acc.PutByte(0x0a) // "?req_attribute"
 			acc.PutString(name)
 		}
-		acc.PutString(value)
+
+		if code == 0x0b { // "?ssl_key_size"
+			size, _ := strconv.Atoi(value)
+			acc.PutShort(size)
+
+		} else {
+			acc.PutString(value)
+		}
 	}
 	acc.PutByte(0xFF) // termination code
 }
@@ -1,17 +1,22 @@
 package impl
 
 import (
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/docker/base"
 	"bayserver-core/baykit/bayserver/protocol/packetstore"
 	"bayserver-core/baykit/bayserver/protocol/protocolhandlerstore"
 	"bayserver-docker-ajp/baykit/bayserver/docker/ajp"
+	"strings"
 )
 
 var portRegistered = false
 
 type AjpPortDockerImpl struct {
 	*base.PortBase
+
+	secret string
 }
 
 func NewAjpPort() docker.Port {
@@ -33,6 +38,29 @@ func (d *AjpPortDockerImpl) String() string {
 	return "AjpPortDocker"
 }
 
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (d *AjpPortDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.ConfigException) {
+	switch strings.ToLower(kv.Key) {
+	default:
+		return d.PortBase.InitKeyVal(kv)
+
+	case "secret":
+		d.secret = kv.Value
+	}
+	return true, nil
+}
+
+/****************************************/
+/* Implements AjpPortDocker             */
+/****************************************/
+
+func (d *AjpPortDockerImpl) Secret() string {
+	return d.secret
+}
+
 /****************************************/
 /* Implements Port                      */
 /****************************************/
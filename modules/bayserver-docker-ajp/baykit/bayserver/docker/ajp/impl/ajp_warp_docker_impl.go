@@ -3,6 +3,9 @@ package impl
 import (
 	"bayserver-core/baykit/bayserver/agent"
 	"bayserver-core/baykit/bayserver/agent/multiplexer"
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	"bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/docker/base"
 	"bayserver-core/baykit/bayserver/protocol/packetstore"
@@ -10,26 +13,91 @@ import (
 	"bayserver-core/baykit/bayserver/rudder"
 	"bayserver-core/baykit/bayserver/rudder/impl"
 	"bayserver-core/baykit/bayserver/ship"
+	"bayserver-core/baykit/bayserver/symbol"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/strutil"
 	"bayserver-docker-ajp/baykit/bayserver/docker/ajp"
+	"strings"
 )
 
 var warpRegisterd = false
 
+/****************************************/
+/*  Type AjpWarpPinger                  */
+/****************************************/
+
+// Sends CPing through connections of an agent periodically
+type AjpWarpPinger struct {
+	// implements common.TimerHandler
+
+	docker  *AjpWarpDockerImpl
+	agentId int
+}
+
+func (p *AjpWarpPinger) OnTimer() {
+	for _, wsip := range p.docker.GetShipStore(p.agentId).Ships() {
+		if !wsip.Initialized() {
+			continue
+		}
+		if h, ok := wsip.ProtocolHandler().CommandHandler().(*ajp.AjpWarpHandler); ok {
+			h.CheckPing()
+		}
+	}
+}
+
+/****************************************/
+/*  Type AjpWarpDocker_LifeCycleListener */
+/****************************************/
+
+type AjpWarpDocker_LifeCycleListener struct {
+	// implements common.LifecycleListener
+
+	docker *AjpWarpDockerImpl
+}
+
+func (l *AjpWarpDocker_LifeCycleListener) Add(agentId int) {
+	pinger := &AjpWarpPinger{docker: l.docker, agentId: agentId}
+	l.docker.pingers[agentId] = pinger
+	agent.Get(agentId).AddTimerHandler(pinger)
+}
+
+func (l *AjpWarpDocker_LifeCycleListener) Remove(agentId int) {
+	agent.Get(agentId).RemoveTimerHandler(l.docker.pingers[agentId])
+	delete(l.docker.pingers, agentId)
+}
+
+/****************************************/
+/*  Type AjpWarpDockerImpl              */
+/****************************************/
+
 type AjpWarpDockerImpl struct {
 	*base.WarpBase
+
+	secret          string
+	pingTimeoutSec  int
+	pingIntervalSec int
+	pingOnReuse     bool
+
+	/** Agent ID => AjpWarpPinger */
+	pingers map[int]*AjpWarpPinger
 }
 
 func NewAjpWarpDocker() docker.Docker {
 	if !warpRegisterd {
 		registerWarpProtocols()
 	}
-	dkr := &AjpWarpDockerImpl{}
+	dkr := &AjpWarpDockerImpl{
+		pingTimeoutSec:  ajp.DEFAULT_PING_TIMEOUT_SEC,
+		pingIntervalSec: ajp.DEFAULT_PING_INTERVAL_SEC,
+		pingOnReuse:     ajp.DEFAULT_PING_ON_REUSE,
+		pingers:         map[int]*AjpWarpPinger{},
+	}
 	dkr.WarpBase = base.NewWarpBase(dkr)
 
-	var _ docker.Docker = dkr // implement check
-	var _ docker.Warp = dkr   // implement check
-	var _ docker.Club = dkr   // implement check
+	var _ docker.Docker = dkr     // implement check
+	var _ docker.Warp = dkr       // implement check
+	var _ docker.Club = dkr       // implement check
+	var _ ajp.AjpWarpDocker = dkr // implement check
 	return dkr
 }
 
@@ -37,6 +105,71 @@ func (d *AjpWarpDockerImpl) String() string {
 	return "AjpWarp"
 }
 
+/****************************************/
+/* Implements Docker                    */
+/****************************************/
+
+func (d *AjpWarpDockerImpl) Init(elm *bcf.BcfElement, parent docker.Docker) exception.ConfigException {
+	cerr := d.WarpBase.Init(elm, parent)
+	if cerr != nil {
+		return cerr
+	}
+
+	if d.pingTimeoutSec > 0 {
+		agent.AddLifeCycleListener(&AjpWarpDocker_LifeCycleListener{docker: d})
+	}
+	return nil
+}
+
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (d *AjpWarpDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.ConfigException) {
+	var err error = nil
+	switch strings.ToLower(kv.Key) {
+	default:
+		return d.WarpBase.InitKeyVal(kv)
+
+	case "secret":
+		d.secret = kv.Value
+
+	case "pingtimeout":
+		d.pingTimeoutSec, err = strutil.ParseInt(kv.Value)
+
+	case "pinginterval":
+		d.pingIntervalSec, err = strutil.ParseInt(kv.Value)
+
+	case "pingonreuse":
+		d.pingOnReuse, err = strutil.ParseBool(kv.Value)
+	}
+
+	if err != nil {
+		return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+	}
+	return true, nil
+}
+
+/****************************************/
+/* Implements AjpWarpDocker             */
+/****************************************/
+
+func (d *AjpWarpDockerImpl) Secret() string {
+	return d.secret
+}
+
+func (d *AjpWarpDockerImpl) PingTimeoutSec() int {
+	return d.pingTimeoutSec
+}
+
+func (d *AjpWarpDockerImpl) PingIntervalSec() int {
+	return d.pingIntervalSec
+}
+
+func (d *AjpWarpDockerImpl) PingOnReuse() bool {
+	return d.pingOnReuse && d.pingTimeoutSec > 0
+}
+
 /****************************************/
 /* Implements WarpSub                  */
 /****************************************/
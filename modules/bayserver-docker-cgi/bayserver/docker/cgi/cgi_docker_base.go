@@ -3,19 +3,29 @@ package cgi
 import (
 	"bayserver-core/baykit/bayserver/bayserver"
 	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/logfile"
+	"bayserver-core/baykit/bayserver/common/metrics"
+	"bayserver-core/baykit/bayserver/common/process"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/docker/base"
+	"bayserver-core/baykit/bayserver/symbol"
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/cgiutil"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"bayserver-core/baykit/bayserver/util/logsink"
+	"bayserver-core/baykit/bayserver/util/strutil"
 	"bayserver-core/baykit/bayserver/util/sysutil"
+	"fmt"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type CgiDockerSub interface {
@@ -32,6 +42,17 @@ type CgiDockerBase struct {
 	docRoot      string
 	timeoutSec   int
 	maxProcesses int
+	streaming    bool
+
+	// Process isolation
+	process *process.ProcessSettings
+	suexec  bool
+	setEnv  [][2]string
+	passEnv []string
+
+	// Error log (stderr of scripts)
+	errorLog     *logfile.LogFile
+	errorLogName string
 
 	processCount int
 	waitCount    int
@@ -44,6 +65,8 @@ func NewCgiDockerBase(sub CgiDockerSub) *CgiDockerBase {
 		scriptBase:  "",
 		docRoot:     "",
 		timeoutSec:  DEFAULT_TIMEOUT_SEC,
+		process:     process.NewProcessSettings(),
+		errorLog:    logfile.NewLogFile(),
 	}
 	b.ClubBase = base.NewClubBase(b)
 	b.cgiDockerSub = sub
@@ -57,6 +80,38 @@ func (d *CgiDockerBase) String() string {
 	return "CgiDocker"
 }
 
+/****************************************/
+/* Implements Docker                    */
+/****************************************/
+
+func (d *CgiDockerBase) Init(elm *bcf.BcfElement, parent docker.Docker) exception2.ConfigException {
+	cerr := d.ClubBase.Init(elm, parent)
+	if cerr != nil {
+		return cerr
+	}
+
+	if d.errorLogName != "" {
+		d.errorLog.SetFileName(d.errorLogName)
+		d.errorLog.SetSeverity(logsink.SEVERITY_ERR)
+		err := d.errorLog.Open()
+		if err != nil {
+			baylog.ErrorE(err, "")
+			return exception2.NewConfigException(elm.FileName, elm.LineNo, "Cannot create directory")
+		}
+	}
+
+	townName := ""
+	if town, ok := parent.(docker.Town); ok {
+		townName = town.Name()
+	}
+	metrics.CgiProcesses.SetCollector(fmt.Sprintf("%p", d), func(emit metrics.Emitter) {
+		emit(float64(d.GetProcessCount()), townName, elm.Arg, "running")
+		emit(float64(d.GetWaitCount()), townName, elm.Arg, "waiting")
+	})
+
+	return nil
+}
+
 /****************************************/
 /* Implements DockerInitializer         */
 /****************************************/
@@ -87,7 +142,45 @@ func (d *CgiDockerBase) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception2.ConfigEx
 			baylog.ErrorE(exception.NewExceptionFromError(err), "")
 		}
 
+	case "streaming":
+		var err exception.Exception
+		d.streaming, err = strutil.ParseBool(kv.Value)
+		if err != nil {
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "suexec":
+		var err exception.Exception
+		d.suexec, err = strutil.ParseBool(kv.Value)
+		if err != nil {
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "setenv":
+		pos := strings.Index(kv.Value, "=")
+		if pos <= 0 {
+			return false, exception2.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+		d.setEnv = append(d.setEnv, [2]string{kv.Value[:pos], kv.Value[pos+1:]})
+
+	case "errorlog":
+		d.errorLogName = kv.Value
+
+	case "passenv":
+		for _, name := range strings.Fields(kv.Value) {
+			d.passEnv = append(d.passEnv, name)
+		}
+
 	default:
+		// Rotation settings of the error log
+		if ok, cerr := d.errorLog.InitKeyVal(kv); ok || cerr != nil {
+			return ok, cerr
+		}
+
+		if ok, cerr := d.process.InitKeyVal(kv); ok || cerr != nil {
+			return ok, cerr
+		}
+
 		var cerr exception2.ConfigException
 		_, cerr = d.ClubBase.InitKeyVal(kv)
 		if cerr != nil {
@@ -127,6 +220,15 @@ func (d *CgiDockerBase) Arrive(tur tour.Tour) exception2.HttpException {
 	}
 
 	env := cgiutil.GetEnv(town.Name(), root, base, tur)
+	for _, name := range d.passEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	for _, nv := range d.setEnv {
+		env[nv[0]] = nv[1]
+	}
+
 	if bayserver.Harbor().TraceHeader() {
 		for name, value := range env {
 			baylog.Info("%s cgi: env: %s=%s", tur, name, value)
@@ -138,6 +240,13 @@ func (d *CgiDockerBase) Arrive(tur tour.Tour) exception2.HttpException {
 		return exception2.NewHttpException(httpstatus.NOT_FOUND, fileName)
 	}
 
+	if d.suexec {
+		herr := d.checkScriptOwner(fileName)
+		if herr != nil {
+			return herr
+		}
+	}
+
 	handler := NewCgiReqContentHandler(d, tur, env)
 	tur.Req().SetReqContentHandler(handler)
 	handler.reqStartTour()
@@ -149,14 +258,65 @@ func (d *CgiDockerBase) Arrive(tur tour.Tour) exception2.HttpException {
 /* Custom functions                     */
 /****************************************/
 
+// PrepareProcess applies user, process group and resource limit settings to the command before it starts
+func (d *CgiDockerBase) PrepareProcess(cmd *exec.Cmd, env map[string]string) exception.IOException {
+	ownerFile := ""
+	if d.suexec {
+		ownerFile = env[cgiutil.SCRIPT_FILENAME]
+	}
+	return d.process.Prepare(cmd, ownerFile)
+}
+
+// KillProcess kills the process (and its children if process group is enabled)
+func (d *CgiDockerBase) KillProcess(cmd *exec.Cmd) exception.IOException {
+	return d.process.Kill(cmd)
+}
+
+// OutputLimit returns max bytes of output the process can write (0 means unlimited)
+func (d *CgiDockerBase) OutputLimit() int {
+	return d.process.OutputLimit()
+}
+
+// Streaming returns true if output of long-running scripts is streamed without timeout
+func (d *CgiDockerBase) Streaming() bool {
+	return d.streaming
+}
+
+// HasErrorLog returns true if stderr of scripts is written to the error log file
+func (d *CgiDockerBase) HasErrorLog() bool {
+	return d.errorLogName != ""
+}
+
+// WriteErrorLog writes stderr output of script to the error log file of the agent.
+// Each line is prefixed with the date, tour id and request URI.
+func (d *CgiDockerBase) WriteErrorLog(agentId int, tourId int, uri string, msg string) exception.IOException {
+	prefix := "[" + time.Now().Format("2006-01-02 15:04:05 MST") + "] [tour#" + strconv.Itoa(tourId) + "] [" + uri + "] "
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(msg, "\r\n"), "\n") {
+		sb.WriteString(prefix)
+		sb.WriteString(strings.TrimRight(line, "\r"))
+		sb.WriteString("\n")
+	}
+
+	return d.errorLog.Write(agentId, []byte(sb.String()))
+}
+
 func (d *CgiDockerBase) TimeoutSec() int {
 	return d.timeoutSec
 }
 
 func (d *CgiDockerBase) GetWaitCount() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 	return d.waitCount
 }
 
+func (d *CgiDockerBase) GetProcessCount() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.processCount
+}
+
 func (d *CgiDockerBase) AddProcessCount() bool {
 	d.lock.Lock()
 	var ret bool
@@ -184,3 +344,29 @@ func (d *CgiDockerBase) SubWaitCount() {
 	d.waitCount--
 	d.lock.Unlock()
 }
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+// Check the script like suexec does since it runs as the owner
+func (d *CgiDockerBase) checkScriptOwner(fileName string) exception2.HttpException {
+	uid, gid, ioerr := sysutil.GetFileOwner(fileName)
+	if ioerr != nil {
+		return exception2.NewHttpException(httpstatus.INTERNAL_SERVER_ERROR, "%s", ioerr.Error())
+	}
+	if uid == 0 || gid == 0 {
+		baylog.Error("%s Script owned by root user or group is not allowed: %s", d, fileName)
+		return exception2.NewHttpException(httpstatus.FORBIDDEN, fileName)
+	}
+
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return exception2.NewHttpException(httpstatus.INTERNAL_SERVER_ERROR, "%s", err.Error())
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		baylog.Error("%s Script writable by others is not allowed: %s", d, fileName)
+		return exception2.NewHttpException(httpstatus.FORBIDDEN, fileName)
+	}
+	return nil
+}
@@ -13,15 +13,20 @@ import (
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/tour/impl"
 	"bayserver-core/baykit/bayserver/util/baylog"
+	"bayserver-core/baykit/bayserver/util/cgiutil"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/httpstatus"
 	"errors"
 	"io"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 )
 
+const NPH_PREFIX = "nph-"
+
 type CgiReqContentHandler struct {
 	cgiDocker    *CgiDockerBase
 	tour         tour.Tour
@@ -35,6 +40,11 @@ type CgiReqContentHandler struct {
 	stdErrClosed bool
 	lastAccess   int64
 	env          map[string]string
+
+	// Non-parsed-header script writes status line by itself
+	nph        bool
+	streaming  bool
+	headerSent bool
 }
 
 func NewCgiReqContentHandler(dkr *CgiDockerBase, tur tour.Tour, env map[string]string) *CgiReqContentHandler {
@@ -44,6 +54,8 @@ func NewCgiReqContentHandler(dkr *CgiDockerBase, tur tour.Tour, env map[string]s
 		tourId:    tur.TourId(),
 		env:       env,
 	}
+	h.nph = strings.HasPrefix(filepath.Base(env[cgiutil.SCRIPT_FILENAME]), NPH_PREFIX)
+	h.streaming = dkr.Streaming() || h.nph
 	var _ tour.ReqContentHandler = &h // Cast check
 	h.Access()
 	return &h
@@ -77,6 +89,11 @@ func (c *CgiReqContentHandler) OnAbortReq(tur tour.Tour) bool {
 		if err != nil {
 			baylog.ErrorE(exception2.NewIOExceptionFromError(err), "")
 		}
+
+		if c.streaming && c.cmd.Process != nil {
+			// Streaming script may not write anything for a long time
+			c.Kill()
+		}
 	}
 	return false // not aborted immediately
 }
@@ -95,13 +112,18 @@ func (c *CgiReqContentHandler) StartTour() {
 	c.available = false
 
 	var ioerr exception2.IOException = nil
+	var err error = nil
 	for { // try catch
 		c.cmd, ioerr = c.cgiDocker.cgiDockerSub.CreateProcess(c.env)
 		if ioerr != nil {
 			break
 		}
 
-		var err error
+		ioerr = c.cgiDocker.PrepareProcess(c.cmd, c.env)
+		if ioerr != nil {
+			break
+		}
+
 		c.inPipe, err = c.cmd.StdinPipe()
 		if err != nil {
 			break
@@ -123,12 +145,18 @@ func (c *CgiReqContentHandler) StartTour() {
 		break
 	}
 
+	if err != nil {
+		ioerr = exception2.NewIOExceptionFromError(err)
+	}
+
 	if ioerr != nil {
-		baylog.ErrorE(ioerr, "")
+		baylog.ErrorE(ioerr, "%s Cannot start CGI process", c.tour)
+		c.cgiDocker.SubProcessCount()
 		ioerr = c.tour.Res().SendError(c.tourId, httpstatus.INTERNAL_SERVER_ERROR, "Process Error", ioerr)
 		if ioerr != nil {
 			baylog.ErrorE(ioerr, "")
 		}
+		return
 	}
 
 	c.stdOutClosed = false
@@ -211,6 +239,13 @@ func (c *CgiReqContentHandler) StdErrClosed() {
 	}
 }
 
+func (c *CgiReqContentHandler) Kill() {
+	ioerr := c.cgiDocker.KillProcess(c.cmd)
+	if ioerr != nil {
+		baylog.ErrorE(ioerr, "%s Cannot kill cmd: %s", c.tour, c.cmd)
+	}
+}
+
 func (c *CgiReqContentHandler) Access() {
 	c.lastAccess = time.Now().Unix()
 }
@@ -220,6 +255,11 @@ func (c *CgiReqContentHandler) Timeout() bool {
 		return false
 	}
 
+	if c.streaming && c.headerSent {
+		// Streaming script can run as long as the client is connected
+		return false
+	}
+
 	baylog.Debug("%s Check CGI timeout: now=%d, last=%d", c.tour, time.Now().Unix(), c.lastAccess)
 
 	durationSec := int(time.Now().Unix() - c.lastAccess)
@@ -7,13 +7,21 @@ import (
 	"bayserver-core/baykit/bayserver/rudder"
 	ship2 "bayserver-core/baykit/bayserver/ship/impl"
 	"bayserver-core/baykit/bayserver/util/baylog"
+	"bayserver-core/baykit/bayserver/util/cgiutil"
 	"bayserver-core/baykit/bayserver/util/exception"
+	"bytes"
 	"strconv"
 )
 
+// Partial line longer than this is written to the error log without waiting for the newline
+const MAX_PARTIAL_LINE = 8192
+
 type CgiStdErrShip struct {
 	ship2.ShipImpl
 	handler *CgiReqContentHandler
+
+	// Trailing partial line kept until the next newline or EOF (Only for error log)
+	partial []byte
 }
 
 func NewCgiStdErrShip() *CgiStdErrShip {
@@ -38,6 +46,7 @@ func (sip *CgiStdErrShip) String() string {
 func (sip *CgiStdErrShip) Reset() {
 	sip.ShipImpl.Reset()
 	sip.handler = nil
+	sip.partial = nil
 }
 
 /****************************************/
@@ -56,9 +65,22 @@ func (sip *CgiStdErrShip) NotifyConnect() (common.NextSocketAction, exception.IO
 
 func (sip *CgiStdErrShip) NotifyRead(buf []byte) (common.NextSocketAction, exception.IOException) {
 	baylog.Debug("%s CGI StdErr: read %d bytes", sip, len(buf))
-	msg := string(buf)
-	if len(msg) > 0 {
-		baylog.Error("CGI Stderr: %s", msg)
+	if len(buf) > 0 {
+		if sip.handler.cgiDocker.HasErrorLog() {
+			data := append(sip.partial, buf...)
+			pos := bytes.LastIndexByte(data, '\n')
+			if pos < 0 && len(data) <= MAX_PARTIAL_LINE {
+				sip.partial = data
+			} else {
+				if pos < 0 {
+					pos = len(data) - 1
+				}
+				sip.partial = append([]byte(nil), data[pos+1:]...)
+				sip.writeErrorLog(string(data[:pos+1]))
+			}
+		} else {
+			baylog.Error("CGI Stderr: %s", string(buf))
+		}
 	}
 
 	sip.handler.Access()
@@ -67,6 +89,7 @@ func (sip *CgiStdErrShip) NotifyRead(buf []byte) (common.NextSocketAction, excep
 
 func (sip *CgiStdErrShip) NotifyEof() common.NextSocketAction {
 	baylog.Debug("%s EOF", sip)
+	sip.flushPartial()
 	return common.NEXT_SOCKET_ACTION_CLOSE
 }
 
@@ -80,9 +103,31 @@ func (sip *CgiStdErrShip) NotifyProtocolError(e exception2.ProtocolException) (b
 }
 
 func (sip *CgiStdErrShip) NotifyClose() {
+	sip.flushPartial()
 	sip.handler.StdErrClosed()
 }
 
 func (sip *CgiStdErrShip) CheckTimeout(durationSec int) bool {
 	return sip.handler.Timeout()
 }
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (sip *CgiStdErrShip) flushPartial() {
+	if len(sip.partial) > 0 {
+		msg := string(sip.partial)
+		sip.partial = nil
+		sip.writeErrorLog(msg)
+	}
+}
+
+func (sip *CgiStdErrShip) writeErrorLog(msg string) {
+	dkr := sip.handler.cgiDocker
+	ioerr := dkr.WriteErrorLog(sip.AgentId(), sip.handler.tourId, sip.handler.env[cgiutil.REQUEST_URI], msg)
+	if ioerr != nil {
+		baylog.ErrorE(ioerr, "%s Cannot write error log", sip)
+		baylog.Error("CGI Stderr: %s", msg)
+	}
+}
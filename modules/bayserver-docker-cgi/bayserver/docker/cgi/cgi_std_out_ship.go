@@ -20,8 +20,9 @@ type CgiStdOutShip struct {
 	tourId       int
 	handler      *CgiReqContentHandler
 
-	remain        string
-	headerReading bool
+	remain         string
+	headerReading  bool
+	statusLineRead bool
 }
 
 func NewCgiStdOutShip() *CgiStdOutShip {
@@ -52,6 +53,7 @@ func (sip *CgiStdOutShip) Reset() {
 	sip.tourId = 0
 	sip.tour = nil
 	sip.headerReading = true
+	sip.statusLineRead = false
 	sip.remain = ""
 	sip.handler = nil
 }
@@ -74,6 +76,17 @@ func (sip *CgiStdOutShip) NotifyRead(buf []byte) (common.NextSocketAction, excep
 	sip.fileWroteLen += len(buf)
 	baylog.Debug("%s read %d bytes: total=%d", sip, len(buf), sip.fileWroteLen)
 
+	limit := sip.handler.cgiDocker.OutputLimit()
+	if limit > 0 && sip.fileWroteLen > limit {
+		baylog.Warn("%s Output exceeds limit (%d bytes). Kill cmd!: %s", sip.tour, limit, sip.handler.cmd)
+		sip.handler.Kill()
+		buf = buf[:len(buf)-(sip.fileWroteLen-limit)]
+		sip.fileWroteLen = limit
+		if len(buf) == 0 {
+			return common.NEXT_SOCKET_ACTION_CLOSE, nil
+		}
+	}
+
 	var ioerr exception.IOException = nil
 	if sip.headerReading {
 		for {
@@ -105,6 +118,7 @@ func (sip *CgiStdOutShip) NotifyRead(buf []byte) (common.NextSocketAction, excep
 			//  finish header reading.
 			if line == "" {
 				sip.headerReading = false
+				sip.handler.headerSent = true
 				ioerr = sip.tour.Res().SendHeaders(sip.tourId)
 				break
 			} else {
@@ -112,6 +126,15 @@ func (sip *CgiStdOutShip) NotifyRead(buf []byte) (common.NextSocketAction, excep
 					baylog.Info("%s CGI: res header line: %s", sip.tour, line)
 				}
 
+				if sip.handler.nph && !sip.statusLineRead {
+					// NPH script writes status line at first
+					sip.statusLineRead = true
+					if sip.readStatusLine(line) {
+						continue
+					}
+					baylog.Warn("%s CGI: Invalid status line of NPH script (Treat as header): %s", sip.tour, line)
+				}
+
 				sepPos := strings.Index(line, ":")
 				if sepPos >= 0 {
 					key := strings.TrimSpace(line[0:sepPos])
@@ -130,6 +153,10 @@ func (sip *CgiStdOutShip) NotifyRead(buf []byte) (common.NextSocketAction, excep
 							}
 						}
 
+					} else if sip.handler.nph && isHopByHopHeader(key) {
+						// Connection is managed by BayServer
+						baylog.Debug("%s CGI: ignore header of NPH script: %s", sip.tour, line)
+
 					} else {
 						sip.tour.Res().Headers().Add(key, val)
 					}
@@ -188,8 +215,36 @@ func (sip *CgiStdOutShip) CheckTimeout(durationSec int) bool {
 	if sip.handler.Timeout() {
 		// Kill cgi cmd instead of handing timeout
 		baylog.Warn("%s Kill cmd!: %s", sip.tour, sip.handler.cmd)
-		sip.handler.cmd.Process.Kill()
+		sip.handler.Kill()
 		return true
 	}
 	return false
 }
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (sip *CgiStdOutShip) readStatusLine(line string) bool {
+	// HTTP/1.1 200 OK
+	tokens := strings.Fields(line)
+	if len(tokens) < 2 || !strings.HasPrefix(tokens[0], "HTTP/") {
+		return false
+	}
+
+	status, err := strconv.Atoi(tokens[1])
+	if err != nil {
+		return false
+	}
+	sip.tour.Res().Headers().SetStatus(status)
+	return true
+}
+
+func isHopByHopHeader(name string) bool {
+	switch strings.ToLower(name) {
+	case "connection", "keep-alive":
+		return true
+	default:
+		return false
+	}
+}
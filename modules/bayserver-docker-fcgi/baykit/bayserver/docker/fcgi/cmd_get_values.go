@@ -0,0 +1,65 @@
+package fcgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * FCGI spec
+ *   http://www.mit.edu/~yandros/doc/specs/fcgi-spec.html
+ *
+ * Get values command format
+ *   Name-Value pairs with empty values (same format as Params)
+ *   Request ID of the command is always FCGI_NULL_REQUEST_ID
+ */
+
+// Variable names of GET_VALUES
+const FCGI_MAX_CONNS = "FCGI_MAX_CONNS"
+const FCGI_MAX_REQS = "FCGI_MAX_REQS"
+const FCGI_MPXS_CONNS = "FCGI_MPXS_CONNS"
+
+type CmdGetValues struct {
+	*FcgCommandBase
+	Names []string
+}
+
+func NewCmdGetValues() *CmdGetValues {
+	c := CmdGetValues{
+		FcgCommandBase: NewFcgCommandBase(FCG_TYPE_GETVALUES, FCGI_NULL_REQUEST_ID),
+		Names:          make([]string, 0),
+	}
+	var _ protocol.Command = &c // cast check
+	var _ FcgCommand = &c       // cast check
+	return &c
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdGetValues) Unpack(pkt protocol.Packet) exception2.IOException {
+	c.FcgCommandBase.Unpack(pkt.(*FcgPacket))
+
+	for _, nv := range unpackNameValues(pkt) {
+		c.Names = append(c.Names, nv[0])
+	}
+	return nil
+}
+
+func (c *CmdGetValues) Pack(pkt protocol.Packet) exception2.IOException {
+	nvs := make([][]string, len(c.Names))
+	for i, name := range c.Names {
+		nvs[i] = []string{name, ""}
+	}
+	packNameValues(pkt, nvs)
+
+	// must be called from last line
+	c.FcgCommandBase.Pack(pkt.(*FcgPacket))
+	return nil
+}
+
+func (c *CmdGetValues) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
+	return h.(FcgCommandHandler).HandleGetValues(c)
+}
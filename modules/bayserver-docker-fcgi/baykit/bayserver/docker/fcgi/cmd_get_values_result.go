@@ -0,0 +1,70 @@
+package fcgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * FCGI spec
+ *   http://www.mit.edu/~yandros/doc/specs/fcgi-spec.html
+ *
+ * Get values result command format
+ *   Name-Value pairs (same format as Params)
+ *   Request ID of the command is always FCGI_NULL_REQUEST_ID
+ */
+
+type CmdGetValuesResult struct {
+	*FcgCommandBase
+	Values [][]string
+}
+
+func NewCmdGetValuesResult() *CmdGetValuesResult {
+	c := CmdGetValuesResult{
+		FcgCommandBase: NewFcgCommandBase(FCG_TYPE_GETVALUESRESULT, FCGI_NULL_REQUEST_ID),
+		Values:         make([][]string, 0),
+	}
+	var _ protocol.Command = &c // cast check
+	var _ FcgCommand = &c       // cast check
+	return &c
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdGetValuesResult) Unpack(pkt protocol.Packet) exception2.IOException {
+	c.FcgCommandBase.Unpack(pkt.(*FcgPacket))
+	c.Values = unpackNameValues(pkt)
+	return nil
+}
+
+func (c *CmdGetValuesResult) Pack(pkt protocol.Packet) exception2.IOException {
+	packNameValues(pkt, c.Values)
+
+	// must be called from last line
+	c.FcgCommandBase.Pack(pkt.(*FcgPacket))
+	return nil
+}
+
+func (c *CmdGetValuesResult) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
+	return h.(FcgCommandHandler).HandleGetValuesResult(c)
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+func (c *CmdGetValuesResult) AddValue(name string, value string) {
+	c.Values = append(c.Values, []string{name, value})
+}
+
+func (c *CmdGetValuesResult) Value(name string) string {
+	for _, nv := range c.Values {
+		if nv[0] == name {
+			return nv[1]
+		}
+	}
+	return ""
+}
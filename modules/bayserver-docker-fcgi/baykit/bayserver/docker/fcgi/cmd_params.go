@@ -81,10 +81,47 @@ func NewCmdParams(reqId int) *CmdParams {
 func (c *CmdParams) Unpack(pkt protocol.Packet) exception2.IOException {
 	c.FcgCommandBase.Unpack(pkt.(*FcgPacket))
 
+	for _, nv := range unpackNameValues(pkt) {
+		c.addParam(nv[0], nv[1])
+	}
+	return nil
+}
+
+func (c *CmdParams) Pack(pkt protocol.Packet) exception2.IOException {
+	packNameValues(pkt, c.Params)
+
+	// must be called from last line
+	c.FcgCommandBase.Pack(pkt.(*FcgPacket))
+	return nil
+}
+
+func (c *CmdParams) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
+	return h.(FcgCommandHandler).HandleParams(c)
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (c *CmdParams) addParam(name string, value string) {
+	c.Params = append(c.Params, []string{name, value})
+}
+
+/****************************************/
+/* Custome method                       */
+/****************************************/
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+// Name-Value pairs are also used by GET_VALUES and GET_VALUES_RESULT
+func unpackNameValues(pkt protocol.Packet) [][]string {
+	nvs := [][]string{}
 	acc := pkt.NewDataAccessor()
 	for acc.Pos() < pkt.DataLen() {
-		nameLen := c.readLength(acc)
-		valueLen := c.readLength(acc)
+		nameLen := readNameValueLength(acc)
+		valueLen := readNameValueLength(acc)
 		data := make([]byte, nameLen)
 		acc.GetBytes(data, 0, len(data))
 		name := string(data)
@@ -93,41 +130,29 @@ func (c *CmdParams) Unpack(pkt protocol.Packet) exception2.IOException {
 		acc.GetBytes(data, 0, len(data))
 		value := string(data)
 
-		c.addParam(name, value)
+		nvs = append(nvs, []string{name, value})
 	}
-	return nil
+	return nvs
 }
 
-func (c *CmdParams) Pack(pkt protocol.Packet) exception2.IOException {
+func packNameValues(pkt protocol.Packet, nvs [][]string) {
 	acc := pkt.NewDataAccessor()
 
-	for _, nv := range c.Params {
+	for _, nv := range nvs {
 		name := []byte(nv[0])
 		value := []byte(nv[1])
 		nameLen := len(name)
 		valueLen := len(value)
 
-		c.writeLength(nameLen, acc)
-		c.writeLength(valueLen, acc)
+		writeNameValueLength(nameLen, acc)
+		writeNameValueLength(valueLen, acc)
 
 		acc.PutBytes(name, 0, nameLen)
 		acc.PutBytes(value, 0, valueLen)
 	}
-
-	// must be called from last line
-	c.FcgCommandBase.Pack(pkt.(*FcgPacket))
-	return nil
-}
-
-func (c *CmdParams) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
-	return h.(FcgCommandHandler).HandleParams(c)
 }
 
-/****************************************/
-/* Private functions                    */
-/****************************************/
-
-func (c *CmdParams) readLength(acc *protocol.PacketPartAccessor) int {
+func readNameValueLength(acc *protocol.PacketPartAccessor) int {
 	len1 := acc.GetByte()
 	if len1>>7 == 0 {
 		return len1
@@ -140,7 +165,7 @@ func (c *CmdParams) readLength(acc *protocol.PacketPartAccessor) int {
 	}
 }
 
-func (c *CmdParams) writeLength(length int, acc *protocol.PacketPartAccessor) {
+func writeNameValueLength(length int, acc *protocol.PacketPartAccessor) {
 	if length>>7 == 0 {
 		acc.PutByte(length)
 
@@ -152,11 +177,3 @@ func (c *CmdParams) writeLength(length int, acc *protocol.PacketPartAccessor) {
 		acc.PutBytes([]byte{byte(len1), byte(len2), byte(len3), byte(len4)}, 0, 4)
 	}
 }
-
-func (c *CmdParams) addParam(name string, value string) {
-	c.Params = append(c.Params, []string{name, value})
-}
-
-/****************************************/
-/* Custome method                       */
-/****************************************/
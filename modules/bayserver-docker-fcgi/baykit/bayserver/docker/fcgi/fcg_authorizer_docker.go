@@ -0,0 +1,14 @@
+package fcgi
+
+import "bayserver-core/baykit/bayserver/docker"
+
+/**
+ * Permission docker which asks FastCGI application of Authorizer role whether the tour is admitted
+ */
+
+type FcgAuthorizerDocker interface {
+	docker.Permission
+}
+
+// Prefix of response headers which are passed to the club as CGI variables
+const FCG_AUTHORIZER_VARIABLE_PREFIX = "Variable-"
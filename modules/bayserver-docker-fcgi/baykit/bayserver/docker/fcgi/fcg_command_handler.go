@@ -14,4 +14,6 @@ type FcgCommandHandler interface {
 	HandleStdErr(cmd *CmdStdErr) (common.NextSocketAction, exception.IOException)
 	HandleStdIn(cmd *CmdStdIn) (common.NextSocketAction, exception.IOException)
 	HandleStdOut(cmd *CmdStdOut) (common.NextSocketAction, exception.IOException)
+	HandleGetValues(cmd *CmdGetValues) (common.NextSocketAction, exception.IOException)
+	HandleGetValuesResult(cmd *CmdGetValuesResult) (common.NextSocketAction, exception.IOException)
 }
@@ -57,6 +57,12 @@ func (cu *FcgCommandUnpacker) PacketReceived(pkt protocol.Packet) (common.NextSo
 	case FCG_TYPE_STDERR:
 		cmd = NewCmdStdErr(reqId)
 
+	case FCG_TYPE_GETVALUES:
+		cmd = NewCmdGetValues()
+
+	case FCG_TYPE_GETVALUESRESULT:
+		cmd = NewCmdGetValuesResult()
+
 	default:
 		baylog.FatalE(exception2.NewSink("Illegal State"), "")
 		panic("Error")
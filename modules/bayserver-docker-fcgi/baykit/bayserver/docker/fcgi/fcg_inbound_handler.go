@@ -18,25 +18,32 @@ import (
 	"bytes"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const COMMAND_STATE_READ_BEGIN_REQUEST = 1
 const COMMAND_STATE_READ_PARAMS = 2
 const COMMAND_STATE_READ_STDIN = 3
+const COMMAND_STATE_READ_END = 4
+
+// State of each request on the connection (Requests may be multiplexed)
+type fcgInboundRequest struct {
+	state        int
+	env          map[string]string
+	reqKeepAlive bool
+}
 
 type FcgInboundHandler struct {
 	protocolHandler *FcgProtocolHandlerImpl
-	state           int
-	env             map[string]string
-	reqId           int
-	reqKeepAlive    bool
+	requests        map[int]*fcgInboundRequest
+	closeRequested  bool
+	lock            sync.Mutex
 }
 
 func NewFcgInboundHandler() *FcgInboundHandler {
 	h := &FcgInboundHandler{
-		env: map[string]string{},
+		requests: map[int]*fcgInboundRequest{},
 	}
-	h.resetState()
 
 	var _ tour.TourHandler = h // interface check
 	return h
@@ -55,8 +62,11 @@ func (h *FcgInboundHandler) String() string {
 /****************************************/
 
 func (h *FcgInboundHandler) Reset() {
-	h.resetState()
-	h.env = map[string]string{}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.requests = map[int]*fcgInboundRequest{}
+	h.closeRequested = false
 }
 
 /****************************************/
@@ -105,6 +115,11 @@ func (h *FcgInboundHandler) SendHeaders(tur tour.Tour) exception2.IOException {
 	return h.protocolHandler.Post(cmd, nil)
 }
 
+func (h *FcgInboundHandler) SendInformational(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	// FastCGI responder has no way to send interim responses
+	return nil
+}
+
 func (h *FcgInboundHandler) SendContent(tur tour.Tour, bytes []byte, ofs int, length int, lis common.DataConsumeListener) exception2.IOException {
 	cmd := NewCmdStdOut(tur.Req().Key(), bytes, ofs, length)
 	return h.protocolHandler.Post(cmd, lis)
@@ -114,6 +129,15 @@ func (h *FcgInboundHandler) SendEnd(tur tour.Tour, keepAlive bool, lis common.Da
 	sip := h.Ship()
 	baylog.Debug("%s Fcg sendEnd: tur=%s keep=%t", sip, tur, keepAlive)
 
+	// Connection is closed after all the multiplexed requests end
+	h.lock.Lock()
+	delete(h.requests, tur.Req().Key())
+	if !keepAlive {
+		h.closeRequested = true
+	}
+	closeCon := h.closeRequested && len(h.requests) == 0
+	h.lock.Unlock()
+
 	// Send empty stdout command
 	stdOutCmd := NewCmdStdOut(tur.Req().Key(), nil, 0, 0)
 	ioerr := h.protocolHandler.Post(stdOutCmd, nil)
@@ -126,7 +150,7 @@ func (h *FcgInboundHandler) SendEnd(tur tour.Tour, keepAlive bool, lis common.Da
 	sid := sip.ShipId()
 	ensureFunc := func() {
 		//baylog.Debug("%s Call back from post end. keep=%t", sip, keepAlive)
-		if !keepAlive {
+		if closeCon {
 			sip.PostClose(sid)
 		}
 	}
@@ -154,12 +178,28 @@ func (h *FcgInboundHandler) HandleBeginRequest(cmd *CmdBeginRequest) (common.Nex
 	sip := h.Ship()
 	baylog.Debug("%s handleBeginRequest: reqId=%d keep=%t", sip, cmd.ReqId(), cmd.KeepCon)
 
-	if h.state != COMMAND_STATE_READ_BEGIN_REQUEST {
-		return -1, exception.NewProtocolException("fcgi: Invalid command: %d state=%d", cmd.Type(), h.state)
+	reqId := cmd.ReqId()
+	if reqId == FCGI_NULL_REQUEST_ID {
+		return -1, exception.NewProtocolException("Invalid request id: %d", reqId)
+	}
+
+	h.lock.Lock()
+	_, exists := h.requests[reqId]
+	nRequests := len(h.requests)
+	h.lock.Unlock()
+
+	if exists {
+		return -1, exception.NewProtocolException("fcgi: Request id is in use: %d", reqId)
+	}
+
+	port := sip.PortDocker().(FcgPortDocker)
+	if nRequests > 0 && !port.Multiplex() {
+		return h.rejectRequest(reqId, FCGI_CANT_MPX_CONN)
+
+	} else if nRequests >= port.MaxConcurrentRequests() {
+		return h.rejectRequest(reqId, FCGI_OVERLOADED)
 	}
 
-	reqId := cmd.ReqId()
-	h.checkReqId(reqId)
 	tur := sip.GetTour(reqId, false, true)
 
 	if tur == nil {
@@ -172,9 +212,14 @@ func (h *FcgInboundHandler) HandleBeginRequest(cmd *CmdBeginRequest) (common.Nex
 		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 	}
 
-	h.reqKeepAlive = cmd.KeepCon
+	h.lock.Lock()
+	h.requests[reqId] = &fcgInboundRequest{
+		state:        COMMAND_STATE_READ_PARAMS,
+		env:          map[string]string{},
+		reqKeepAlive: cmd.KeepCon,
+	}
+	h.lock.Unlock()
 
-	h.changeState(COMMAND_STATE_READ_PARAMS)
 	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 }
 
@@ -186,11 +231,15 @@ func (h *FcgInboundHandler) HandleParams(cmd *CmdParams) (common.NextSocketActio
 	sip := h.Ship()
 	baylog.Debug("%s handleParams reqId=%d nParams=%d", sip, cmd.ReqId(), len(cmd.Params))
 
-	if h.state != COMMAND_STATE_READ_PARAMS {
-		return -1, exception.NewProtocolException("Invalid FCGI Command: %d", cmd.Type())
+	fr := h.findRequest(cmd.ReqId())
+	if fr == nil {
+		// Request is not active
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 	}
 
-	h.checkReqId(cmd.ReqId())
+	if fr.state != COMMAND_STATE_READ_PARAMS {
+		return -1, exception.NewProtocolException("Invalid FCGI Command: %d", cmd.Type())
+	}
 
 	tur := sip.GetTour(cmd.ReqId(), false, true)
 
@@ -202,7 +251,7 @@ func (h *FcgInboundHandler) HandleParams(cmd *CmdParams) (common.NextSocketActio
 
 			// check keep-alive
 			//  keep-alive flag of BeginRequest has high priority
-			if h.reqKeepAlive {
+			if fr.reqKeepAlive {
 				if !tur.Req().Headers().Contains(headers.CONNECTION) {
 					tur.Req().Headers().Set(headers.CONNECTION, "Keep-Alive")
 				}
@@ -229,9 +278,9 @@ func (h *FcgInboundHandler) HandleParams(cmd *CmdParams) (common.NextSocketActio
 				tur.Req().SetLimit(contLen)
 			}
 
-			h.changeState(COMMAND_STATE_READ_STDIN)
+			fr.state = COMMAND_STATE_READ_STDIN
 
-			hterr := h.startTour(tur)
+			hterr := h.startTour(tur, fr)
 
 			if hterr != nil {
 				baylog.Debug("%s Http error occurred: %s", sip, hterr)
@@ -246,7 +295,7 @@ func (h *FcgInboundHandler) HandleParams(cmd *CmdParams) (common.NextSocketActio
 
 				} else {
 					// Delay send
-					h.changeState(COMMAND_STATE_READ_STDIN)
+					fr.state = COMMAND_STATE_READ_STDIN
 					tur.SetHttpError(hterr)
 					tur.Req().SetReqContentHandler(tour.NewDevNullContentHandler())
 					return common.NEXT_SOCKET_ACTION_CONTINUE, nil
@@ -258,7 +307,7 @@ func (h *FcgInboundHandler) HandleParams(cmd *CmdParams) (common.NextSocketActio
 			for _, nv := range cmd.Params {
 				name := nv[0]
 				value := nv[1]
-				h.env[name] = value
+				fr.env[name] = value
 
 				if strings.HasPrefix(name, "HTTP_") {
 					hname := name[5:]
@@ -276,9 +325,9 @@ func (h *FcgInboundHandler) HandleParams(cmd *CmdParams) (common.NextSocketActio
 				}
 			}
 
-			tur.Req().SetUri(h.env["REQUEST_URI"])
-			tur.Req().SetProtocol(h.env["SERVER_PROTOCOL"])
-			tur.Req().SetMethod(h.env["REQUEST_METHOD"])
+			tur.Req().SetUri(fr.env["REQUEST_URI"])
+			tur.Req().SetProtocol(fr.env["SERVER_PROTOCOL"])
+			tur.Req().SetMethod(fr.env["REQUEST_METHOD"])
 
 			return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 		}
@@ -297,7 +346,13 @@ func (h *FcgInboundHandler) HandleStdIn(cmd *CmdStdIn) (common.NextSocketAction,
 	sip := h.Ship()
 	baylog.Debug("%s handleStdIn reqId=%d len=%d", sip, cmd.ReqId(), cmd.Length)
 
-	if h.state != COMMAND_STATE_READ_STDIN {
+	fr := h.findRequest(cmd.ReqId())
+	if fr == nil {
+		// Request is not active
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+
+	if fr.state != COMMAND_STATE_READ_STDIN {
 		return -1, exception.NewProtocolException("Invalid FCGI Command: %d", cmd.Type())
 	}
 
@@ -307,8 +362,6 @@ func (h *FcgInboundHandler) HandleStdIn(cmd *CmdStdIn) (common.NextSocketAction,
 
 	for {
 		// try catch
-		h.checkReqId(cmd.ReqId())
-
 		if cmd.Length == 0 {
 			// request content completed
 			if tur.Error() != nil {
@@ -319,7 +372,7 @@ func (h *FcgInboundHandler) HandleStdIn(cmd *CmdStdIn) (common.NextSocketAction,
 
 			} else {
 				var ioerr exception2.IOException
-				ioerr, hterr = h.endReqContent(impl.TOUR_ID_NOCHECK, tur)
+				ioerr, hterr = h.endReqContent(impl.TOUR_ID_NOCHECK, tur, fr)
 				if ioerr != nil {
 					return -1, ioerr
 				}
@@ -359,8 +412,8 @@ func (h *FcgInboundHandler) HandleStdIn(cmd *CmdStdIn) (common.NextSocketAction,
 
 	if hterr != nil {
 		tur.Req().Abort()
+		fr.state = COMMAND_STATE_READ_END
 		tur.Res().SendHttpException(impl.TOUR_ID_NOCHECK, hterr)
-		h.resetState()
 		return common.NEXT_SOCKET_ACTION_WRITE, nil
 	}
 
@@ -371,6 +424,38 @@ func (h *FcgInboundHandler) HandleStdOut(cmd *CmdStdOut) (common.NextSocketActio
 	return -1, exception.NewProtocolException("Invalid FCGI Command: %d", cmd.Type())
 }
 
+func (h *FcgInboundHandler) HandleGetValues(cmd *CmdGetValues) (common.NextSocketAction, exception2.IOException) {
+	sip := h.Ship()
+	baylog.Debug("%s handleGetValues names=%v", sip, cmd.Names)
+
+	port := sip.PortDocker().(FcgPortDocker)
+	res := NewCmdGetValuesResult()
+	for _, name := range cmd.Names {
+		// Unknown variables are ignored
+		switch name {
+		case FCGI_MPXS_CONNS:
+			if port.Multiplex() {
+				res.AddValue(name, "1")
+			} else {
+				res.AddValue(name, "0")
+			}
+
+		case FCGI_MAX_REQS:
+			res.AddValue(name, strconv.Itoa(port.MaxConcurrentRequests()))
+		}
+	}
+
+	ioerr := h.protocolHandler.Post(res, nil)
+	if ioerr != nil {
+		return -1, ioerr
+	}
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+func (h *FcgInboundHandler) HandleGetValuesResult(cmd *CmdGetValuesResult) (common.NextSocketAction, exception2.IOException) {
+	return -1, exception.NewProtocolException("Invalid FCGI Command: %d", cmd.Type())
+}
+
 /****************************************/
 /* Private functions                    */
 /****************************************/
@@ -379,42 +464,39 @@ func (h *FcgInboundHandler) Ship() *common2.InboundShipImpl {
 	return h.protocolHandler.Ship().(*common2.InboundShipImpl)
 }
 
-func (h *FcgInboundHandler) changeState(newState int) {
-	h.state = newState
-}
-
-func (h *FcgInboundHandler) resetState() {
-	h.changeState(COMMAND_STATE_READ_BEGIN_REQUEST)
-	h.reqId = FCGI_NULL_REQUEST_ID
-}
-
-func (h *FcgInboundHandler) checkReqId(receivedId int) exception2.IOException {
-	if receivedId == FCGI_NULL_REQUEST_ID {
-		return exception.NewProtocolException("Invalid request id: %d", receivedId)
-	}
+func (h *FcgInboundHandler) findRequest(reqId int) *fcgInboundRequest {
+	h.lock.Lock()
+	defer h.lock.Unlock()
 
-	if h.reqId == FCGI_NULL_REQUEST_ID {
-		h.reqId = receivedId
+	fr := h.requests[reqId]
+	if fr == nil {
+		baylog.Debug("%s request is not active (ignore): reqId=%d", h.Ship(), reqId)
 	}
+	return fr
+}
 
-	if h.reqId != receivedId {
-		baylog.Error("%s invalid request id: received=%d reqId=%d", h.Ship(), receivedId, h.reqId)
-		return exception.NewProtocolException("Invalid request id: %d", receivedId)
+// Rejects new request by sending END_REQUEST
+func (h *FcgInboundHandler) rejectRequest(reqId int, protocolStatus int) (common.NextSocketAction, exception2.IOException) {
+	baylog.Debug("%s reject request: reqId=%d status=%d", h.Ship(), reqId, protocolStatus)
+	cmd := NewCmdEndRequest(reqId)
+	cmd.ProtocolStatus = protocolStatus
+	ioerr := h.protocolHandler.Post(cmd, nil)
+	if ioerr != nil {
+		return -1, ioerr
 	}
-
-	return nil
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 }
 
-func (h *FcgInboundHandler) endReqContent(checkTourId int, tur tour.Tour) (exception2.IOException, exception.HttpException) {
+func (h *FcgInboundHandler) endReqContent(checkTourId int, tur tour.Tour, fr *fcgInboundRequest) (exception2.IOException, exception.HttpException) {
+	fr.state = COMMAND_STATE_READ_END
 	ioerr, hterr := tur.Req().EndReqContent(checkTourId)
 	if ioerr != nil || hterr != nil {
 		return ioerr, hterr
 	}
-	h.resetState()
 	return nil, nil
 }
 
-func (h *FcgInboundHandler) startTour(tur tour.Tour) exception.HttpException {
+func (h *FcgInboundHandler) startTour(tur tour.Tour, fr *fcgInboundRequest) exception.HttpException {
 	secure := h.Ship().PortDocker().Secure()
 	defaultPort := 80
 	if secure {
@@ -424,20 +506,20 @@ func (h *FcgInboundHandler) startTour(tur tour.Tour) exception.HttpException {
 	req.ParseHostPort(defaultPort)
 	req.ParseAuthorization()
 
-	remotePort, err := strconv.Atoi(h.env[cgiutil.REMOTE_PORT])
+	remotePort, err := strconv.Atoi(fr.env[cgiutil.REMOTE_PORT])
 	if err != nil {
 		baylog.ErrorE(err, "")
 	}
 	req.SetRemotePort(remotePort)
 
-	req.SetRemoteAddress(h.env[cgiutil.REMOTE_ADDR])
+	req.SetRemoteAddress(fr.env[cgiutil.REMOTE_ADDR])
 	req.SetRemoteHostFunc(tour.NewDefaultRemoteHostResolver(req.RemoteAddress()))
 
 	req.SetServerName(req.ReqHost())
 	req.SetServerPort(req.ReqPort())
 
 	var serverPort int
-	serverPort, err = strconv.Atoi(h.env[cgiutil.SERVER_PORT])
+	serverPort, err = strconv.Atoi(fr.env[cgiutil.SERVER_PORT])
 	if err != nil {
 		baylog.ErrorE(err, "")
 		serverPort = 80
@@ -30,6 +30,7 @@ const FCG_VERSION = 1
 const FCG_PACKET_MAXLEN = 65535
 
 const FCGI_NULL_REQUEST_ID = 0
+const FCGI_MAX_REQUEST_ID = 65535
 
 type FcgPacket struct {
 	protocol.PacketImpl
@@ -1,4 +1,12 @@
 package fcgi
 
+const DEFAULT_MULTIPLEX = true
+const DEFAULT_MAX_CONCURRENT_REQUESTS = 100
+
 type FcgPortDocker interface {
+	// Multiplex returns true if concurrent requests on a connection are accepted (FCGI_MPXS_CONNS)
+	Multiplex() bool
+
+	// MaxConcurrentRequests returns the number of concurrent requests per connection (FCGI_MAX_REQS)
+	MaxConcurrentRequests() int
 }
@@ -1,6 +1,22 @@
 package fcgi
 
+import "bayserver-core/baykit/bayserver/ship"
+
 type FcgWarpDocker interface {
 	ScriptBase() string
 	DocRoot() string
+
+	// BeginRequest is called when request is sent through the warp ship.
+	// Returns false if the connection should be closed after the request
+	BeginRequest(wsip ship.Ship) bool
+
+	// EndRequest is called when the request sent through the warp ship is finished
+	EndRequest(wsip ship.Ship)
+
+	// Multiplex returns true if multiplexing is negotiated with the server using GET_VALUES
+	Multiplex() bool
+
+	// SetServerValues is called when the server returns GET_VALUES_RESULT
+	//  (or rejects the request with FCGI_CANT_MPX_CONN)
+	SetServerValues(mpxsConns bool, maxReqs int)
 }
@@ -13,20 +13,29 @@ import (
 	"bayserver-core/baykit/bayserver/util/cgiutil"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/headers"
-	"github.com/google/uuid"
+	"bayserver-core/baykit/bayserver/util/httpstatus"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 )
 
 const STATE_READ_HEADER = 1
 const STATE_READ_CONTENT = 2
 
+// State of each request on the connection (Requests may be multiplexed)
+type fcgWarpRequest struct {
+	state   int
+	keepCon bool
+	lineBuf []byte
+}
+
 type FcgWarpHandler struct {
 	protocolHandler *FcgProtocolHandlerImpl
 	curWarpId       int
-	state           int
-	lineBuf         []byte
+	requests        map[int]*fcgWarpRequest
+	requestsLock    sync.Mutex
+	valuesRequested bool
 
 	// for read header/contents
 	pos  int
@@ -35,8 +44,9 @@ type FcgWarpHandler struct {
 }
 
 func NewFcgWarpHandler() *FcgWarpHandler {
-	h := &FcgWarpHandler{}
-	h.resetState()
+	h := &FcgWarpHandler{
+		requests: map[int]*fcgWarpRequest{},
+	}
 
 	var _ tour.TourHandler = h     // cast check
 	var _ warpship.WarpHandler = h // cast check
@@ -58,8 +68,10 @@ func (h *FcgWarpHandler) String() string {
 /****************************************/
 
 func (h *FcgWarpHandler) Reset() {
-	h.resetState()
-	h.lineBuf = h.lineBuf[:0]
+	h.requestsLock.Lock()
+	h.requests = map[int]*fcgWarpRequest{}
+	h.requestsLock.Unlock()
+	h.valuesRequested = false
 	h.pos = 0
 	h.last = 0
 	h.data = nil
@@ -78,6 +90,10 @@ func (h *FcgWarpHandler) SendHeaders(tur tour.Tour) exception2.IOException {
 	return h.sendParams(tur)
 }
 
+func (h *FcgWarpHandler) SendInformational(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	return nil
+}
+
 func (h *FcgWarpHandler) SendContent(tur tour.Tour, buf []byte, start int, length int, lis common.DataConsumeListener) exception2.IOException {
 	return h.sendStdIn(tur, buf, start, length, lis)
 }
@@ -96,8 +112,16 @@ func (h *FcgWarpHandler) OnProtocolError(e exception.ProtocolException) (bool, e
 /****************************************/
 
 func (h *FcgWarpHandler) NextWarpId() int {
-	h.curWarpId++
-	return h.curWarpId
+	// Request ID is 16 bit and must not be in use on the connection
+	for {
+		h.curWarpId++
+		if h.curWarpId > FCGI_MAX_REQUEST_ID {
+			h.curWarpId = 1
+		}
+		if h.findRequest(h.curWarpId) == nil {
+			return h.curWarpId
+		}
+	}
 }
 
 func (h *FcgWarpHandler) NewWarpData(warpId int) *warpship.WarpData {
@@ -121,6 +145,24 @@ func (h *FcgWarpHandler) HandleEndRequest(cmd *CmdEndRequest) (common.NextSocket
 	if perr != nil {
 		return -1, perr
 	}
+
+	if cmd.ProtocolStatus != FCGI_REQUEST_COMPLETE {
+		// Request is rejected by server
+		baylog.Warn("%s fcgi: request rejected: reqId=%d status=%d", h.Ship(), cmd.ReqId(), cmd.ProtocolStatus)
+		if cmd.ProtocolStatus == FCGI_CANT_MPX_CONN {
+			h.Ship().Docker().(FcgWarpDocker).SetServerValues(false, 0)
+		}
+
+		if !tur.Res().HeaderSent() {
+			h.endRequest(tur)
+			ioerr := tur.Res().SendError(tourimpl.TOUR_ID_NOCHECK, httpstatus.SERVICE_UNAVAILABLE, "fcgi: request rejected", nil)
+			if ioerr != nil {
+				return -1, ioerr
+			}
+			return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+		}
+	}
+
 	h.endReqContent(tur)
 	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 }
@@ -150,25 +192,29 @@ func (h *FcgWarpHandler) HandleStdOut(cmd *CmdStdOut) (common.NextSocketAction,
 
 	if cmd.Length == 0 {
 		// stdout end
-		h.resetState()
 		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 	}
 
+	fr := h.findRequest(cmd.ReqId())
+	if fr == nil {
+		return -1, exception.NewProtocolException("fcgi: request not found: reqId=%d", cmd.ReqId())
+	}
+
 	h.data = cmd.Data
 	h.pos = cmd.Start
 	h.last = cmd.Start + cmd.Length
 
 	var ioerr exception2.IOException = nil
 	for { // try catch
-		if h.state == STATE_READ_HEADER {
-			ioerr = h.readHeader(tur)
+		if fr.state == STATE_READ_HEADER {
+			ioerr = h.readHeader(tur, fr)
 			if ioerr != nil {
 				break
 			}
 		}
 
 		if h.pos < h.last {
-			if h.state == STATE_READ_CONTENT {
+			if fr.state == STATE_READ_CONTENT {
 				var available bool
 				available, ioerr = tur.Res().SendResContent(tourimpl.TOUR_ID_NOCHECK, h.data, h.pos, h.last-h.pos)
 				if ioerr != nil {
@@ -186,47 +232,77 @@ func (h *FcgWarpHandler) HandleStdOut(cmd *CmdStdOut) (common.NextSocketAction,
 	return -1, ioerr
 }
 
+func (h *FcgWarpHandler) HandleGetValues(cmd *CmdGetValues) (common.NextSocketAction, exception2.IOException) {
+	return -1, exception.NewProtocolException("Invalid FCGI command: %d", cmd.Type())
+}
+
+func (h *FcgWarpHandler) HandleGetValuesResult(cmd *CmdGetValuesResult) (common.NextSocketAction, exception2.IOException) {
+	baylog.Debug("%s handleGetValuesResult values=%v", h.Ship(), cmd.Values)
+
+	maxReqs, err := strconv.Atoi(cmd.Value(FCGI_MAX_REQS))
+	if err != nil {
+		maxReqs = 0
+	}
+	h.Ship().Docker().(FcgWarpDocker).SetServerValues(cmd.Value(FCGI_MPXS_CONNS) == "1", maxReqs)
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
 /****************************************/
 /* Custom functions                     */
 /****************************************/
 
-func (h *FcgWarpHandler) endResHeader(tur tour.Tour) exception2.IOException {
+func (h *FcgWarpHandler) endResHeader(tur tour.Tour, fr *fcgWarpRequest) exception2.IOException {
 	wdat := warpship.WarpDataGet(tur)
 	wdat.ResHeaders.CopyTo(tur.Res().Headers())
 	ioerr := tur.Res().SendHeaders(tourimpl.TOUR_ID_NOCHECK)
 	if ioerr != nil {
 		return ioerr
 	}
-	h.changeState(STATE_READ_CONTENT)
+	fr.state = STATE_READ_CONTENT
 	return nil
 }
 
 func (h *FcgWarpHandler) endReqContent(tur tour.Tour) exception2.IOException {
-	h.Ship().EndWarpTour(tur, true)
+	h.endRequest(tur)
 	ioerr := tur.Res().EndResContent(tourimpl.TOUR_ID_NOCHECK)
 	if ioerr != nil {
 		return ioerr
 	}
-	h.resetState()
 	return nil
 }
 
-func (h *FcgWarpHandler) changeState(newState int) {
-	h.state = newState
+func (h *FcgWarpHandler) endRequest(tur tour.Tour) {
+	warpId := warpship.WarpDataGet(tur).WarpId
+	keepCon := false
+	h.requestsLock.Lock()
+	fr := h.requests[warpId]
+	if fr != nil {
+		keepCon = fr.keepCon
+		delete(h.requests, warpId)
+	}
+	h.requestsLock.Unlock()
+
+	if fr != nil {
+		h.Ship().Docker().(FcgWarpDocker).EndRequest(h.Ship())
+	}
+	h.Ship().EndWarpTour(tur, keepCon)
 }
 
-func (h *FcgWarpHandler) resetState() {
-	h.changeState(STATE_READ_HEADER)
+func (h *FcgWarpHandler) findRequest(warpId int) *fcgWarpRequest {
+	h.requestsLock.Lock()
+	defer h.requestsLock.Unlock()
+
+	return h.requests[warpId]
 }
 
-func (h *FcgWarpHandler) readHeader(tur tour.Tour) exception2.IOException {
+func (h *FcgWarpHandler) readHeader(tur tour.Tour, fr *fcgWarpRequest) exception2.IOException {
 	wdat := warpship.WarpDataGet(tur)
 
 	var ioerr exception2.IOException = nil
 	for { // try catch
 
 		var headerFinished bool
-		headerFinished, ioerr = h.parseHeader(wdat.ResHeaders)
+		headerFinished, ioerr = h.parseHeader(fr, wdat.ResHeaders)
 		if ioerr != nil {
 			break
 		}
@@ -267,7 +343,7 @@ func (h *FcgWarpHandler) readHeader(tur tour.Tour) exception2.IOException {
 				break
 			}
 
-			h.changeState(STATE_READ_CONTENT)
+			fr.state = STATE_READ_CONTENT
 		}
 
 		return nil
@@ -276,7 +352,7 @@ func (h *FcgWarpHandler) readHeader(tur tour.Tour) exception2.IOException {
 	return ioerr
 }
 
-func (h *FcgWarpHandler) parseHeader(headers *headers.Headers) (bool, exception2.IOException) {
+func (h *FcgWarpHandler) parseHeader(fr *fcgWarpRequest, headers *headers.Headers) (bool, exception2.IOException) {
 
 	var ioerr exception2.IOException = nil
 
@@ -293,7 +369,7 @@ func (h *FcgWarpHandler) parseHeader(headers *headers.Headers) (bool, exception2
 			continue
 
 		} else if c == '\n' {
-			line := string(h.lineBuf)
+			line := string(fr.lineBuf)
 			if line == "" {
 				return true, nil
 			}
@@ -329,9 +405,9 @@ func (h *FcgWarpHandler) parseHeader(headers *headers.Headers) (bool, exception2
 					baylog.Info("%s fcgi_warp: resHeader: %s=%s", h.Ship(), name, value)
 				}
 			}
-			h.lineBuf = h.lineBuf[:0]
+			fr.lineBuf = fr.lineBuf[:0]
 		} else {
-			h.lineBuf = append(h.lineBuf, c)
+			fr.lineBuf = append(fr.lineBuf, c)
 		}
 
 	}
@@ -349,9 +425,30 @@ func (h *FcgWarpHandler) sendStdIn(tur tour.Tour, data []byte, ofs int, length i
 }
 
 func (h *FcgWarpHandler) sendBeginReq(tur tour.Tour) exception2.IOException {
-	cmd := NewCmdBeginRequest(warpship.WarpDataGet(tur).WarpId)
+	dkr := h.Ship().Docker().(FcgWarpDocker)
+	if dkr.Multiplex() && !h.valuesRequested {
+		// Ask whether the server accepts concurrent requests on the connection
+		h.valuesRequested = true
+		valCmd := NewCmdGetValues()
+		valCmd.Names = append(valCmd.Names, FCGI_MAX_REQS, FCGI_MPXS_CONNS)
+		ioerr := h.Ship().Post(valCmd, nil)
+		if ioerr != nil {
+			return ioerr
+		}
+	}
+
+	warpId := warpship.WarpDataGet(tur).WarpId
+	fr := &fcgWarpRequest{
+		state:   STATE_READ_HEADER,
+		keepCon: dkr.BeginRequest(h.Ship()),
+	}
+	h.requestsLock.Lock()
+	h.requests[warpId] = fr
+	h.requestsLock.Unlock()
+
+	cmd := NewCmdBeginRequest(warpId)
 	cmd.Role = FCGI_RESPONDER
-	cmd.KeepCon = true
+	cmd.KeepCon = fr.keepCon
 	return h.Ship().Post(cmd, nil)
 }
 
@@ -390,11 +487,17 @@ func (h *FcgWarpHandler) sendParams(tur tour.Tour) exception2.IOException {
 			}
 		})
 
-		scriptFname = "proxy:fcgi://" + h.Ship().Docker().Host() + ":" + strconv.Itoa(h.Ship().Docker().Port()) + scriptFname
+		host := h.Ship().Docker().Host()
+		if host == "" || strings.HasPrefix(host, ":unix:") {
+			// Unix domain socket or managed process
+			scriptFname = "proxy:fcgi://localhost" + scriptFname
+		} else {
+			scriptFname = "proxy:fcgi://" + host + ":" + strconv.Itoa(h.Ship().Docker().Port()) + scriptFname
+		}
 		cmd.addParam(cgiutil.SCRIPT_FILENAME, scriptFname)
 
 		cmd.addParam(CONTEXT_PREFIX, "")
-		cmd.addParam(UNIQUE_ID, uuid.New().String())
+		cmd.addParam(UNIQUE_ID, tur.Req().RequestId())
 
 		if bayserver.Harbor().TraceHeader() {
 			for _, kv := range cmd.Params {
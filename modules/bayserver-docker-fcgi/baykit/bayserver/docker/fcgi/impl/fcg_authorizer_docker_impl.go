@@ -0,0 +1,390 @@
+package impl
+
+import (
+	"bayserver-core/baykit/bayserver/agent"
+	"bayserver-core/baykit/bayserver/bayserver"
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/docker/base"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/rudder"
+	"bayserver-core/baykit/bayserver/ship"
+	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/tour"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	"bayserver-core/baykit/bayserver/util/cgiutil"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/headers"
+	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"bayserver-docker-fcgi/baykit/bayserver/docker/fcgi"
+	"bytes"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_AUTHORIZER_TIMEOUT_SEC = 10
+const DEFAULT_AUTHORIZER_MAX_SHIPS = 8
+
+// Number of requests which can wait for a free connection
+const AUTHORIZER_QUEUE_SIZE = 1024
+
+// Request ID used for authorizer requests (Requests are not multiplexed on a connection)
+const AUTHORIZER_REQUEST_ID = 1
+
+// Request waiting for a connection to the authorizer
+type authorizerJob struct {
+	params [][]string
+	done   func(status int, resHeaders [][]string, ioerr exception2.IOException)
+}
+
+type FcgAuthorizerDockerImpl struct {
+	*base.DockerBase
+
+	host       string
+	port       int
+	timeoutSec int
+	maxShips   int
+	addr       net.Addr
+
+	// Each worker owns one connection and keeps it between requests
+	jobs        chan *authorizerJob
+	workersOnce sync.Once
+}
+
+func NewFcgAuthorizerDocker() docker.Docker {
+	d := &FcgAuthorizerDockerImpl{
+		timeoutSec: DEFAULT_AUTHORIZER_TIMEOUT_SEC,
+		maxShips:   DEFAULT_AUTHORIZER_MAX_SHIPS,
+		jobs:       make(chan *authorizerJob, AUTHORIZER_QUEUE_SIZE),
+	}
+	d.DockerBase = base.NewDockerBase(d)
+
+	var _ docker.AsyncPermission = d   // implement check
+	var _ fcgi.FcgAuthorizerDocker = d // implement check
+	return d
+}
+
+func (d *FcgAuthorizerDockerImpl) String() string {
+	return "FcgAuthorizer[" + d.host + ":" + strconv.Itoa(d.port) + "]"
+}
+
+/****************************************/
+/* Implements Docker                    */
+/****************************************/
+
+func (d *FcgAuthorizerDockerImpl) Init(elm *bcf.BcfElement, parent docker.Docker) exception.ConfigException {
+	err := d.DockerBase.Init(elm, parent)
+	if err != nil {
+		return err
+	}
+
+	if d.maxShips <= 0 {
+		return exception.NewConfigException(elm.FileName, elm.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, "maxShips"))
+	}
+
+	if d.host == "" {
+		return exception.NewConfigException(elm.FileName, elm.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, "destCity"))
+	}
+
+	var er error
+	if strings.HasPrefix(d.host, ":unix:") {
+		d.addr, er = net.ResolveUnixAddr("unix", d.host[6:])
+	} else {
+		d.addr, er = net.ResolveTCPAddr("tcp", d.host+":"+strconv.Itoa(d.port))
+	}
+	if er != nil {
+		return exception.NewConfigException(elm.FileName, elm.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, d.host))
+	}
+
+	return nil
+}
+
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (d *FcgAuthorizerDockerImpl) InitDocker(dkr docker.Docker) (bool, exception.ConfigException) {
+	return false, nil
+}
+
+func (d *FcgAuthorizerDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.ConfigException) {
+	var err error = nil
+	switch strings.ToLower(kv.Key) {
+	default:
+		return d.DockerBase.DefaultInitKeyVal(kv)
+
+	case "destcity":
+		d.host = kv.Value
+
+	case "destport":
+		d.port, err = strconv.Atoi(kv.Value)
+
+	case "timeout":
+		d.timeoutSec, err = strconv.Atoi(kv.Value)
+
+	case "maxships":
+		d.maxShips, err = strconv.Atoi(kv.Value)
+	}
+
+	if err != nil {
+		return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+	}
+	return true, nil
+}
+
+/****************************************/
+/* Implements Permission                */
+/****************************************/
+
+func (d *FcgAuthorizerDockerImpl) SocketAdmitted(rd rudder.Rudder) exception.HttpException {
+	// Authorizer checks only requests
+	return nil
+}
+
+func (d *FcgAuthorizerDockerImpl) TourAdmitted(tur tour.Tour) exception.HttpException {
+	// Authorizer is called only through TourAdmittedAsync
+	baylog.Error("%s %s Authorizer must be checked asynchronously", tur, d)
+	return exception.NewHttpException(httpstatus.INTERNAL_SERVER_ERROR, tur.Req().Uri())
+}
+
+/****************************************/
+/* Implements AsyncPermission           */
+/****************************************/
+
+func (d *FcgAuthorizerDockerImpl) TourAdmittedAsync(tur tour.Tour, callback docker.PermissionCallback) {
+	agt := agent.Get(tur.Ship().(ship.Ship).AgentId())
+	params := d.params(tur)
+
+	if bayserver.Harbor().TraceHeader() {
+		for _, kv := range params {
+			baylog.Info("%s fcgi_authorizer: env: %s=%s", tur, kv[0], kv[1])
+		}
+	}
+
+	// Talk to authorizer in a worker and resume the tour in its agent
+	d.workersOnce.Do(d.startWorkers)
+	job := &authorizerJob{
+		params: params,
+		done: func(status int, resHeaders [][]string, ioerr exception2.IOException) {
+			agt.SendRunLetter(func() {
+				callback(d.admitted(tur, status, resHeaders, ioerr))
+			}, true)
+		},
+	}
+
+	select {
+	case d.jobs <- job:
+	default:
+		baylog.Error("%s %s Too many requests wait for authorizer", tur, d)
+		agt.SendRunLetter(func() {
+			callback(exception.NewHttpException(httpstatus.SERVICE_UNAVAILABLE, tur.Req().Uri()))
+		}, true)
+	}
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+// Decides whether the tour is admitted from the response of the authorizer
+func (d *FcgAuthorizerDockerImpl) admitted(tur tour.Tour, status int, resHeaders [][]string, ioerr exception2.IOException) exception.HttpException {
+	if ioerr != nil {
+		baylog.ErrorE(ioerr, "%s %s Cannot get response of authorizer", tur, d)
+		return exception.NewHttpException(httpstatus.INTERNAL_SERVER_ERROR, tur.Req().Uri())
+	}
+
+	baylog.Debug("%s %s authorizer status=%d", tur, d, status)
+
+	if status == httpstatus.OK {
+		for _, nv := range resHeaders {
+			if strings.HasPrefix(strings.ToLower(nv[0]), strings.ToLower(fcgi.FCG_AUTHORIZER_VARIABLE_PREFIX)) {
+				tur.Req().AddVariable(nv[0][len(fcgi.FCG_AUTHORIZER_VARIABLE_PREFIX):], nv[1])
+			}
+		}
+		return nil
+	}
+
+	// Pass headers such as WWW-Authenticate to client
+	for _, nv := range resHeaders {
+		if !strings.EqualFold(nv[0], headers.STATUS) &&
+			!strings.HasPrefix(strings.ToLower(nv[0]), strings.ToLower(fcgi.FCG_AUTHORIZER_VARIABLE_PREFIX)) {
+			tur.Res().Headers().Add(nv[0], nv[1])
+		}
+	}
+	return exception.NewHttpException(status, tur.Req().Uri())
+}
+
+// Starts workers which talk to the authorizer (Concurrency is limited to maxShips)
+func (d *FcgAuthorizerDockerImpl) startWorkers() {
+	for i := 0; i < d.maxShips; i++ {
+		go d.work()
+	}
+}
+
+func (d *FcgAuthorizerDockerImpl) work() {
+	defer func() {
+		bayserver.BDefer()
+	}()
+
+	var conn net.Conn = nil
+	for job := range d.jobs {
+		var status int
+		var resHeaders [][]string
+		var ioerr exception2.IOException
+		reused := conn != nil
+		conn, status, resHeaders, ioerr = d.authorize(conn, job.params)
+		if ioerr != nil && reused {
+			// Authorizer may have closed the idle connection
+			baylog.Debug("%s retry with new connection: %s", d, ioerr)
+			conn, status, resHeaders, ioerr = d.authorize(nil, job.params)
+		}
+		job.done(status, resHeaders, ioerr)
+	}
+}
+
+// Sends request to the authorizer and returns status and response headers (Blocks until the response is read)
+// Returns the connection to reuse for the next request (nil if it is closed)
+func (d *FcgAuthorizerDockerImpl) authorize(conn net.Conn, params [][]string) (net.Conn, int, [][]string, exception2.IOException) {
+	if conn == nil {
+		var err error
+		conn, err = net.DialTimeout(d.addr.Network(), d.addr.String(), time.Duration(d.timeoutSec)*time.Second)
+		if err != nil {
+			return nil, 0, nil, exception2.NewIOExceptionFromError(err)
+		}
+	}
+
+	status, resHeaders, ioerr := d.exchange(conn, params)
+	if ioerr != nil {
+		conn.Close()
+		return nil, 0, nil, ioerr
+	}
+	return conn, status, resHeaders, nil
+}
+
+func (d *FcgAuthorizerDockerImpl) exchange(conn net.Conn, params [][]string) (int, [][]string, exception2.IOException) {
+	if d.timeoutSec > 0 {
+		conn.SetDeadline(time.Now().Add(time.Duration(d.timeoutSec) * time.Second))
+	}
+
+	begin := fcgi.NewCmdBeginRequest(AUTHORIZER_REQUEST_ID)
+	begin.Role = fcgi.FCGI_AUTHORIZER
+	begin.KeepCon = true
+
+	paramsCmd := fcgi.NewCmdParams(AUTHORIZER_REQUEST_ID)
+	paramsCmd.Params = params
+
+	// Authorizer does not receive request content
+	cmds := []protocol.Command{
+		begin,
+		paramsCmd,
+		fcgi.NewCmdParams(AUTHORIZER_REQUEST_ID),
+		fcgi.NewCmdStdIn(AUTHORIZER_REQUEST_ID),
+	}
+	for _, cmd := range cmds {
+		pkt := fcgi.NewFcgPacket(cmd.Type())
+		pkt.Reset()
+		ioerr := cmd.Pack(pkt)
+		if ioerr != nil {
+			return 0, nil, ioerr
+		}
+		_, err := conn.Write(pkt.Buf()[:pkt.BufLen()])
+		if err != nil {
+			return 0, nil, exception2.NewIOExceptionFromError(err)
+		}
+	}
+
+	out, ioerr := d.readStdOut(conn)
+	if ioerr != nil {
+		return 0, nil, ioerr
+	}
+	return d.parseResponse(out)
+}
+
+// Reads records until END_REQUEST and returns content of stdout
+func (d *FcgAuthorizerDockerImpl) readStdOut(conn net.Conn) ([]byte, exception2.IOException) {
+	out := bytes.Buffer{}
+	header := make([]byte, fcgi.FCG_PREAMBLE_SIZE)
+	for {
+		_, err := io.ReadFull(conn, header)
+		if err != nil {
+			return nil, exception2.NewIOExceptionFromError(err)
+		}
+
+		typ := int(header[1])
+		contentLen := int(header[4])<<8 | int(header[5])
+		paddingLen := int(header[6])
+		content := make([]byte, contentLen+paddingLen)
+		_, err = io.ReadFull(conn, content)
+		if err != nil {
+			return nil, exception2.NewIOExceptionFromError(err)
+		}
+		content = content[:contentLen]
+
+		switch typ {
+		case fcgi.FCG_TYPE_STDOUT:
+			out.Write(content)
+
+		case fcgi.FCG_TYPE_STDERR:
+			if contentLen > 0 {
+				baylog.Error("%s authorizer error: %s", d, string(content))
+			}
+
+		case fcgi.FCG_TYPE_END_REQUEST:
+			return out.Bytes(), nil
+
+		default:
+			return nil, exception2.NewIOException("%s Invalid FCGI command: %d", d, typ)
+		}
+	}
+}
+
+func (d *FcgAuthorizerDockerImpl) parseResponse(out []byte) (int, [][]string, exception2.IOException) {
+	status := httpstatus.OK
+	resHeaders := [][]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			// Content of the response is ignored
+			break
+		}
+
+		pos := strings.Index(line, ":")
+		if pos <= 0 {
+			return 0, nil, exception2.NewIOException("%s Invalid header of authorizer: %s", d, line)
+		}
+		name := strings.TrimSpace(line[:pos])
+		value := strings.TrimSpace(line[pos+1:])
+
+		if strings.EqualFold(name, headers.STATUS) {
+			var err error
+			status, err = strconv.Atoi(strings.Fields(value + " ")[0])
+			if err != nil {
+				return 0, nil, exception2.NewIOException("%s Invalid status of authorizer: %s", d, value)
+			}
+		}
+		resHeaders = append(resHeaders, []string{name, value})
+	}
+	return status, resHeaders, nil
+}
+
+// Authorizer receives the same params as responder except CONTENT_LENGTH, PATH_INFO, PATH_TRANSLATED and SCRIPT_NAME
+func (d *FcgAuthorizerDockerImpl) params(tur tour.Tour) [][]string {
+	params := [][]string{}
+	cgiutil.GetEnv2("", "", "", tur, func(name string, value string) {
+		switch name {
+		case cgiutil.CONTENT_LENGTH, cgiutil.PATH_INFO, cgiutil.PATH_TRANSLATED, cgiutil.SCRIPT_NAME,
+			cgiutil.SCRIPT_FILENAME, cgiutil.CONTEXT_DOCUMENT_ROOT:
+			// Not determined before the tour arrives at club
+
+		default:
+			params = append(params, []string{name, value})
+		}
+	})
+	return params
+}
@@ -1,24 +1,36 @@
 package impl
 
 import (
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	"bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/docker/base"
 	"bayserver-core/baykit/bayserver/protocol/packetstore"
 	"bayserver-core/baykit/bayserver/protocol/protocolhandlerstore"
+	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/util/strutil"
 	"bayserver-docker-fcgi/baykit/bayserver/docker/fcgi"
+	"strings"
 )
 
 var registerd = false
 
 type FcgPortDockerImpl struct {
 	*base.PortBase
+
+	multiplex             bool
+	maxConcurrentRequests int
 }
 
 func NewFcgPort() docker.Port {
 	if !registerd {
 		registerProtocols()
 	}
-	h := &FcgPortDockerImpl{}
+	h := &FcgPortDockerImpl{
+		multiplex:             fcgi.DEFAULT_MULTIPLEX,
+		maxConcurrentRequests: fcgi.DEFAULT_MAX_CONCURRENT_REQUESTS,
+	}
 	h.PortBase = base.NewPortBase(h)
 
 	// interface check
@@ -33,6 +45,32 @@ func (d *FcgPortDockerImpl) String() string {
 	return "FcgPortDocker"
 }
 
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (d *FcgPortDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.ConfigException) {
+	var err error = nil
+	switch strings.ToLower(kv.Key) {
+	default:
+		return d.PortBase.InitKeyVal(kv)
+
+	case "multiplex":
+		d.multiplex, err = strutil.ParseBool(kv.Value)
+
+	case "maxconcurrentrequests":
+		d.maxConcurrentRequests, err = strutil.ParseInt(kv.Value)
+		if err == nil && d.maxConcurrentRequests <= 0 {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+	}
+
+	if err != nil {
+		return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+	}
+	return true, nil
+}
+
 /****************************************/
 /* Implements Port                      */
 /****************************************/
@@ -53,6 +91,18 @@ func (d *FcgPortDockerImpl) SupportUnanchored() bool {
 	return false
 }
 
+/****************************************/
+/* Implements FcgPortDocker             */
+/****************************************/
+
+func (d *FcgPortDockerImpl) Multiplex() bool {
+	return d.multiplex
+}
+
+func (d *FcgPortDockerImpl) MaxConcurrentRequests() int {
+	return d.maxConcurrentRequests
+}
+
 /****************************************/
 /* Static function                      */
 /****************************************/
@@ -0,0 +1,560 @@
+package impl
+
+import (
+	"bayserver-core/baykit/bayserver/agent"
+	"bayserver-core/baykit/bayserver/bayserver"
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/process"
+	"bayserver-core/baykit/bayserver/ship"
+	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/sysutil"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * Process manager of FastCGI application
+ *
+ *   Each worker process listens on its own unix domain socket passed as the standard input
+ *   (FCGI_LISTENSOCK_FILENO). Since a worker serves one connection at a time, the manager
+ *   assigns an idle worker to each warp connection. When all workers are held by kept-alive
+ *   connections which carry no tours, the manager asks the agents of the connections to close them.
+ */
+
+const PROCESS_MODE_STATIC = "static"
+const PROCESS_MODE_DYNAMIC = "dynamic"
+
+const DEFAULT_PROCESS_MODE = PROCESS_MODE_STATIC
+const DEFAULT_PROCESSES = 4
+const DEFAULT_MIN_PROCESSES = 1
+const DEFAULT_MAX_PROCESSES = 8
+const DEFAULT_PROCESS_IDLE_TIMEOUT_SEC = 30
+
+// Wait before restarting crashed worker
+const WORKER_RESTART_DELAY = time.Second
+
+type fcgWorker struct {
+	id          int
+	sockPath    string
+	addr        *net.UnixAddr
+	listener    *net.UnixListener
+	cmd         *exec.Cmd
+	running     bool
+	connections int
+	tours       int
+	requests    int
+	retiring    bool
+	idleSince   time.Time
+}
+
+func (w *fcgWorker) String() string {
+	return "fcgWorker#" + strconv.Itoa(w.id)
+}
+
+// Warp connection assigned to worker
+type fcgConnection struct {
+	worker  *fcgWorker
+	tours   int
+	closing bool
+}
+
+/****************************************/
+/*  Type FcgProcessManager_LifeCycleListener */
+/****************************************/
+
+type FcgProcessManager_LifeCycleListener struct {
+	// implements common.LifecycleListener
+
+	manager *FcgProcessManager
+}
+
+func (l *FcgProcessManager_LifeCycleListener) Add(agentId int) {
+	agent.Get(agentId).AddTimerHandler(l.manager)
+	l.manager.agentAdded()
+}
+
+func (l *FcgProcessManager_LifeCycleListener) Remove(agentId int) {
+	agent.Get(agentId).RemoveTimerHandler(l.manager)
+	l.manager.agentRemoved()
+}
+
+/****************************************/
+/*  Type FcgProcessManager              */
+/****************************************/
+
+type FcgProcessManager struct {
+	name           string
+	command        []string
+	mode           string
+	processes      int
+	minProcesses   int
+	maxProcesses   int
+	idleTimeoutSec int
+	maxRequests    int
+	socketDir      string
+	env            []string
+	workDir        string
+	process        *process.ProcessSettings
+	closeKept      func(wsip ship.Ship) bool
+
+	workers  []*fcgWorker
+	ships    map[ship.Ship]*fcgConnection
+	nextId   int
+	agents   int
+	stopping bool
+	lock     sync.Mutex
+}
+
+func NewFcgProcessManager(name string, closeKept func(wsip ship.Ship) bool) *FcgProcessManager {
+	m := &FcgProcessManager{
+		name:           name,
+		mode:           DEFAULT_PROCESS_MODE,
+		processes:      DEFAULT_PROCESSES,
+		minProcesses:   DEFAULT_MIN_PROCESSES,
+		maxProcesses:   DEFAULT_MAX_PROCESSES,
+		idleTimeoutSec: DEFAULT_PROCESS_IDLE_TIMEOUT_SEC,
+		process:        process.NewProcessSettings(),
+		closeKept:      closeKept,
+		ships:          make(map[ship.Ship]*fcgConnection),
+	}
+
+	var _ common.TimerHandler = m // implement check
+	return m
+}
+
+func (m *FcgProcessManager) String() string {
+	return "FcgProcessManager[" + m.name + "]"
+}
+
+/****************************************/
+/* Implements TimerHandler              */
+/****************************************/
+
+func (m *FcgProcessManager) OnTimer() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopping || m.mode != PROCESS_MODE_DYNAMIC {
+		return
+	}
+
+	// Stop workers which have been idle for a while
+	now := time.Now()
+	for _, w := range append([]*fcgWorker{}, m.workers...) {
+		if len(m.workers) <= m.minProcesses {
+			break
+		}
+		if w.running && w.connections == 0 && now.Sub(w.idleSince) >= time.Duration(m.idleTimeoutSec)*time.Second {
+			baylog.Info("%s stop idle worker: %s", m, w)
+			m.removeWorker(w)
+		}
+	}
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+func (m *FcgProcessManager) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.ConfigException) {
+	var err error = nil
+	switch strings.ToLower(kv.Key) {
+	case "command":
+		m.command = strings.Fields(kv.Value)
+
+	case "processmode":
+		m.mode = strings.ToLower(kv.Value)
+		if m.mode != PROCESS_MODE_STATIC && m.mode != PROCESS_MODE_DYNAMIC {
+			err = errors.New("invalid mode")
+		}
+
+	case "processes":
+		m.processes, err = strconv.Atoi(kv.Value)
+
+	case "minprocesses":
+		m.minProcesses, err = strconv.Atoi(kv.Value)
+
+	case "maxprocesses":
+		m.maxProcesses, err = strconv.Atoi(kv.Value)
+
+	case "processidletimeout":
+		m.idleTimeoutSec, err = strconv.Atoi(kv.Value)
+
+	case "maxrequests":
+		m.maxRequests, err = strconv.Atoi(kv.Value)
+
+	case "socketdir":
+		m.socketDir = bayserver.GetLocation(kv.Value)
+
+	case "env":
+		if !strings.Contains(kv.Value, "=") {
+			err = errors.New("invalid env")
+		}
+		m.env = append(m.env, kv.Value)
+
+	case "workdir":
+		m.workDir = bayserver.GetLocation(kv.Value)
+
+	default:
+		return m.process.InitKeyVal(kv)
+	}
+
+	if err != nil {
+		return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+	}
+	return true, nil
+}
+
+func (m *FcgProcessManager) Enabled() bool {
+	return len(m.command) > 0
+}
+
+func (m *FcgProcessManager) Init(elm *bcf.BcfElement) exception.ConfigException {
+	if m.processes < 1 || m.minProcesses < 0 || m.maxProcesses < 1 || m.minProcesses > m.maxProcesses {
+		return exception.NewConfigException(elm.FileName, elm.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, "processes"))
+	}
+
+	if m.socketDir == "" {
+		m.socketDir = filepath.Join(os.TempDir(), "bayserver-fcgi-"+strconv.Itoa(sysutil.Pid()))
+	}
+
+	agent.AddLifeCycleListener(&FcgProcessManager_LifeCycleListener{manager: m})
+	return nil
+}
+
+// NextDestination assigns idle worker to the connection of warp ship
+func (m *FcgProcessManager) NextDestination(wsip ship.Ship) (net.Addr, exception2.IOException) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopping {
+		return nil, nil
+	}
+
+	var w *fcgWorker = nil
+	for _, wk := range m.workers {
+		if wk.running && !wk.retiring && wk.connections == 0 {
+			w = wk
+			break
+		}
+	}
+
+	if w == nil && m.mode == PROCESS_MODE_DYNAMIC && len(m.workers) < m.maxProcesses {
+		var ioerr exception2.IOException
+		w, ioerr = m.addWorker()
+		if ioerr != nil {
+			return nil, ioerr
+		}
+	}
+
+	if w == nil {
+		// Take over worker which is held only by kept-alive connections
+		for _, wk := range m.workers {
+			if wk.running && !wk.retiring && wk.tours == 0 {
+				w = wk
+				break
+			}
+		}
+		if w == nil {
+			baylog.Debug("%s no idle worker", m)
+			return nil, nil
+		}
+		m.closeConnections(w)
+	}
+
+	w.connections++
+	m.ships[wsip] = &fcgConnection{worker: w}
+	baylog.Debug("%s assign %s to %s", m, w, wsip)
+	return w.addr, nil
+}
+
+// Release is called when the connection of warp ship is closed
+func (m *FcgProcessManager) Release(wsip ship.Ship) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c := m.ships[wsip]
+	if c == nil {
+		return
+	}
+	delete(m.ships, wsip)
+
+	w := c.worker
+	w.tours -= c.tours
+	w.connections--
+	if w.connections == 0 {
+		w.idleSince = time.Now()
+		if w.retiring && w.running {
+			baylog.Debug("%s retire worker: %s requests=%d", m, w, w.requests)
+			m.terminate(w)
+		}
+	}
+}
+
+// BeginRequest counts requests of the worker and returns false if the connection should be closed after the request
+func (m *FcgProcessManager) BeginRequest(wsip ship.Ship) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c := m.ships[wsip]
+	if c == nil {
+		return true
+	}
+
+	w := c.worker
+	c.tours++
+	w.tours++
+	w.requests++
+	if m.maxRequests > 0 && w.requests >= m.maxRequests {
+		w.retiring = true
+	}
+
+	// In dynamic mode, connection is not kept so that idle workers can be detected
+	return !w.retiring && !c.closing && m.mode != PROCESS_MODE_DYNAMIC
+}
+
+// EndRequest is called when the request sent through the warp ship is finished
+func (m *FcgProcessManager) EndRequest(wsip ship.Ship) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	c := m.ships[wsip]
+	if c == nil || c.tours == 0 {
+		return
+	}
+
+	c.tours--
+	c.worker.tours--
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (m *FcgProcessManager) agentAdded() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.agents++
+	if m.agents > 1 {
+		return
+	}
+
+	m.stopping = false
+	err := os.MkdirAll(m.socketDir, 0700)
+	if err != nil {
+		baylog.ErrorE(exception2.NewIOExceptionFromError(err), "%s Cannot create socket directory: %s", m, m.socketDir)
+		return
+	}
+
+	n := m.processes
+	if m.mode == PROCESS_MODE_DYNAMIC {
+		n = m.minProcesses
+	}
+	for i := 0; i < n; i++ {
+		_, ioerr := m.addWorker()
+		if ioerr != nil {
+			baylog.ErrorE(ioerr, "%s Cannot start worker", m)
+			return
+		}
+	}
+}
+
+func (m *FcgProcessManager) agentRemoved() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.agents--
+	if m.agents > 0 {
+		return
+	}
+
+	baylog.Info("%s stop workers", m)
+	m.stopping = true
+	for _, w := range append([]*fcgWorker{}, m.workers...) {
+		m.removeWorker(w)
+	}
+	m.ships = make(map[ship.Ship]*fcgConnection)
+}
+
+// Asks agents to close kept-alive connections to the worker. Caller must hold lock
+func (m *FcgProcessManager) closeConnections(w *fcgWorker) {
+	for wsip, c := range m.ships {
+		if c.worker != w || c.closing {
+			continue
+		}
+
+		baylog.Debug("%s close idle connection to take over %s: %s", m, w, wsip)
+		c.closing = true
+		sipId := wsip.ShipId()
+		agent.Get(wsip.AgentId()).SendRunLetter(func() {
+			m.closeConnection(wsip, sipId)
+		}, true)
+	}
+}
+
+// Closes kept-alive connection. Called in the agent of the warp ship
+func (m *FcgProcessManager) closeConnection(wsip ship.Ship, sipId int) {
+	m.lock.Lock()
+	c := m.ships[wsip]
+	idle := c != nil && c.tours == 0 && wsip.ShipId() == sipId
+	m.lock.Unlock()
+
+	// Busy connection is closed after the request since it is not kept any more
+	if idle && !m.closeKept(wsip) {
+		baylog.Debug("%s connection is not kept: %s", m, wsip)
+	}
+}
+
+// Caller must hold lock
+func (m *FcgProcessManager) addWorker() (*fcgWorker, exception2.IOException) {
+	m.nextId++
+	w := &fcgWorker{
+		id:        m.nextId,
+		sockPath:  filepath.Join(m.socketDir, m.name+"-"+strconv.Itoa(m.nextId)+".sock"),
+		idleSince: time.Now(),
+	}
+
+	var err error
+	_ = os.Remove(w.sockPath)
+	w.addr, err = net.ResolveUnixAddr("unix", w.sockPath)
+	if err != nil {
+		return nil, exception2.NewIOExceptionFromError(err)
+	}
+	w.listener, err = net.ListenUnix("unix", w.addr)
+	if err != nil {
+		return nil, exception2.NewIOExceptionFromError(err)
+	}
+	w.listener.SetUnlinkOnClose(true)
+
+	ioerr := m.start(w)
+	if ioerr != nil {
+		_ = w.listener.Close()
+		return nil, ioerr
+	}
+
+	m.workers = append(m.workers, w)
+	return w, nil
+}
+
+// Caller must hold lock
+func (m *FcgProcessManager) removeWorker(w *fcgWorker) {
+	for i, wk := range m.workers {
+		if wk == w {
+			m.workers = append(m.workers[:i], m.workers[i+1:]...)
+			break
+		}
+	}
+	if w.running {
+		m.terminate(w)
+	}
+	_ = w.listener.Close()
+}
+
+// Caller must hold lock
+func (m *FcgProcessManager) start(w *fcgWorker) exception2.IOException {
+	sock, err := w.listener.File()
+	if err != nil {
+		return exception2.NewIOExceptionFromError(err)
+	}
+	defer sock.Close()
+
+	cmd := exec.Command(m.command[0], m.command[1:]...)
+	cmd.Stdin = sock
+	cmd.Stderr = os.Stderr
+	cmd.Dir = m.workDir
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, m.env...)
+
+	ioerr := m.process.Prepare(cmd, "")
+	if ioerr != nil {
+		return ioerr
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return exception2.NewIOExceptionFromError(err)
+	}
+
+	w.cmd = cmd
+	w.running = true
+	w.retiring = false
+	w.requests = 0
+	baylog.Info("%s start worker: %s pid=%d socket=%s", m, w, cmd.Process.Pid, w.sockPath)
+
+	go m.supervise(w, cmd)
+	return nil
+}
+
+// Caller must hold lock
+func (m *FcgProcessManager) terminate(w *fcgWorker) {
+	ioerr := m.process.Terminate(w.cmd)
+	if ioerr != nil {
+		baylog.ErrorE(ioerr, "%s Cannot terminate worker: %s", m, w)
+	}
+}
+
+func (m *FcgProcessManager) supervise(w *fcgWorker, cmd *exec.Cmd) {
+	defer func() {
+		bayserver.BDefer()
+	}()
+
+	err := cmd.Wait()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	w.running = false
+	if m.stopping || !m.isWorker(w) {
+		baylog.Debug("%s worker exited: %s", m, w)
+		return
+	}
+
+	if w.retiring {
+		// Restart worker immediately since it is retired by maxRequests
+		ioerr := m.start(w)
+		if ioerr != nil {
+			baylog.ErrorE(ioerr, "%s Cannot restart worker: %s", m, w)
+		}
+		return
+	}
+
+	if err != nil {
+		baylog.Warn("%s worker exited unexpectedly: %s (%s)", m, w, err)
+	} else {
+		baylog.Info("%s worker exited: %s", m, w)
+	}
+
+	time.AfterFunc(WORKER_RESTART_DELAY, func() {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		if m.stopping || !m.isWorker(w) || w.running {
+			return
+		}
+		ioerr := m.start(w)
+		if ioerr != nil {
+			baylog.ErrorE(ioerr, "%s Cannot restart worker: %s", m, w)
+		}
+	})
+}
+
+// Caller must hold lock
+func (m *FcgProcessManager) isWorker(w *fcgWorker) bool {
+	for _, wk := range m.workers {
+		if wk == w {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"bayserver-core/baykit/bayserver/agent"
 	"bayserver-core/baykit/bayserver/agent/multiplexer"
 	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
 	"bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/docker"
 	"bayserver-core/baykit/bayserver/docker/base"
@@ -12,10 +13,14 @@ import (
 	"bayserver-core/baykit/bayserver/rudder"
 	"bayserver-core/baykit/bayserver/rudder/impl"
 	"bayserver-core/baykit/bayserver/ship"
+	"bayserver-core/baykit/bayserver/symbol"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/strutil"
 	"bayserver-docker-fcgi/baykit/bayserver/docker/fcgi"
+	"net"
 	"strings"
+	"sync"
 )
 
 var warpRegisterd = false
@@ -24,6 +29,16 @@ type FcgWarpDockerImpl struct {
 	*base.WarpBase
 	scriptBase string
 	docRoot    string
+
+	// Manages FastCGI application processes if command is specified
+	processManager *FcgProcessManager
+
+	// Multiplexing is used only when the server advertises FCGI_MPXS_CONNS
+	multiplex             bool
+	maxConcurrentRequests int
+	serverMpxsConns       bool
+	serverMaxReqs         int
+	serverValuesLock      sync.Mutex
 }
 
 func NewFcgWarpDocker() docker.Docker {
@@ -32,10 +47,14 @@ func NewFcgWarpDocker() docker.Docker {
 	}
 	dkr := &FcgWarpDockerImpl{}
 	dkr.WarpBase = base.NewWarpBase(dkr)
+	dkr.processManager = NewFcgProcessManager("fcgi", dkr.CloseKept)
+	dkr.maxConcurrentRequests = fcgi.DEFAULT_MAX_CONCURRENT_REQUESTS
 
-	var _ docker.Warp = dkr        // implement check
-	var _ docker.Club = dkr        // implement check
-	var _ fcgi.FcgWarpDocker = dkr // implement check
+	var _ docker.Warp = dkr             // implement check
+	var _ docker.Club = dkr             // implement check
+	var _ fcgi.FcgWarpDocker = dkr      // implement check
+	var _ base.WarpDestinationSub = dkr // implement check
+	var _ base.WarpMultiplexSub = dkr   // implement check
 	return dkr
 }
 
@@ -57,6 +76,19 @@ func (d *FcgWarpDockerImpl) Init(elm *bcf.BcfElement, parent docker.Docker) exce
 		baylog.Warn("docRoot is not specified")
 	}
 
+	if d.processManager.Enabled() {
+		err = d.processManager.Init(elm)
+		if err != nil {
+			return err
+		}
+
+		if d.multiplex {
+			// Managed processes are assigned to connections one by one
+			baylog.Warn("%s multiplex is not available for managed processes", d)
+			d.multiplex = false
+		}
+	}
+
 	return nil
 }
 
@@ -72,13 +104,44 @@ func (d *FcgWarpDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.Confi
 	case "docroot":
 		d.docRoot = kv.Value
 
+	case "multiplex":
+		var err error
+		d.multiplex, err = strutil.ParseBool(kv.Value)
+		if err != nil {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "maxconcurrentrequests":
+		var err error
+		d.maxConcurrentRequests, err = strutil.ParseInt(kv.Value)
+		if err != nil || d.maxConcurrentRequests <= 0 {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
 	default:
-		return d.WarpBase.InitKeyVal(kv)
+		ok, cerr := d.processManager.InitKeyVal(kv)
+		if cerr != nil {
+			return false, cerr
+		}
+		if !ok {
+			return d.WarpBase.InitKeyVal(kv)
+		}
 	}
 
 	return true, nil
 }
 
+/****************************************/
+/* Implements Warp                      */
+/****************************************/
+
+func (d *FcgWarpDockerImpl) OnEndShip(wsip ship.Ship) {
+	if d.processManager.Enabled() {
+		d.processManager.Release(wsip)
+	}
+	d.WarpBase.OnEndShip(wsip)
+}
+
 /****************************************/
 /* Implements WarpSub                  */
 /****************************************/
@@ -106,6 +169,35 @@ func (d *FcgWarpDockerImpl) NewTransporter(agt agent.GrandAgent, rd rudder.Rudde
 	return tp, nil
 }
 
+/****************************************/
+/* Implements WarpDestinationSub        */
+/****************************************/
+
+func (d *FcgWarpDockerImpl) NextDestination(wsip ship.Ship) (net.Addr, exception2.IOException) {
+	if !d.processManager.Enabled() {
+		return d.HostAddr(), nil
+	}
+	return d.processManager.NextDestination(wsip)
+}
+
+/****************************************/
+/* Implements WarpMultiplexSub          */
+/****************************************/
+
+func (d *FcgWarpDockerImpl) MaxWarpTours() int {
+	d.serverValuesLock.Lock()
+	defer d.serverValuesLock.Unlock()
+
+	if !d.multiplex || !d.serverMpxsConns {
+		return 1
+	}
+
+	if d.serverMaxReqs > 0 && d.serverMaxReqs < d.maxConcurrentRequests {
+		return d.serverMaxReqs
+	}
+	return d.maxConcurrentRequests
+}
+
 /****************************************/
 /* Implements FcgWarpDocker             */
 /****************************************/
@@ -118,6 +210,34 @@ func (d *FcgWarpDockerImpl) DocRoot() string {
 	return d.docRoot
 }
 
+func (d *FcgWarpDockerImpl) BeginRequest(wsip ship.Ship) bool {
+	if !d.processManager.Enabled() {
+		return true
+	}
+	return d.processManager.BeginRequest(wsip)
+}
+
+func (d *FcgWarpDockerImpl) EndRequest(wsip ship.Ship) {
+	if d.processManager.Enabled() {
+		d.processManager.EndRequest(wsip)
+	}
+}
+
+func (d *FcgWarpDockerImpl) Multiplex() bool {
+	return d.multiplex
+}
+
+func (d *FcgWarpDockerImpl) SetServerValues(mpxsConns bool, maxReqs int) {
+	d.serverValuesLock.Lock()
+	defer d.serverValuesLock.Unlock()
+
+	if mpxsConns != d.serverMpxsConns || maxReqs != d.serverMaxReqs {
+		baylog.Debug("%s server values: mpxsConns=%t maxReqs=%d", d, mpxsConns, maxReqs)
+	}
+	d.serverMpxsConns = mpxsConns
+	d.serverMaxReqs = maxReqs
+}
+
 /****************************************/
 /* Private function                      */
 /****************************************/
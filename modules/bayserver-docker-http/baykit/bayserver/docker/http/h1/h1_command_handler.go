@@ -12,4 +12,5 @@ type H1CommandHandler interface {
 	HandleContent(cmd *CmdContent) (common.NextSocketAction, exception.IOException)
 	HandleEndContent(cmd *CmdEndContent) (common.NextSocketAction, exception.IOException)
 	ReqFinished() bool
+	HeaderExpected() bool
 }
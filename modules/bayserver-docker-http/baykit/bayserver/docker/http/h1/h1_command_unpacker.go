@@ -54,3 +54,7 @@ func (cu *H1CommandUnpacker) PacketReceived(pkt protocol.Packet) (common.NextSoc
 func (cu *H1CommandUnpacker) ReqFinished() bool {
 	return cu.Handler.ReqFinished()
 }
+
+func (cu *H1CommandUnpacker) HeaderExpected() bool {
+	return cu.Handler.HeaderExpected()
+}
@@ -7,7 +7,7 @@ import (
 	"bayserver-core/baykit/bayserver/common/exception"
 	common2 "bayserver-core/baykit/bayserver/common/inboundship/impl"
 	"bayserver-core/baykit/bayserver/protocol/protocolhandlerstore"
-	ship "bayserver-core/baykit/bayserver/ship/impl"
+	"bayserver-core/baykit/bayserver/ship"
 	"bayserver-core/baykit/bayserver/symbol"
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/tour/impl"
@@ -90,6 +90,11 @@ func (h *H1InboundHandler) SendHeaders(tur tour.Tour) exception2.IOException {
 	} else {
 		resCon = "Keep-Alive"
 		// Client supports "Keep-Alive"
+		if (tur.Req().Headers().Contains(headers.EXPECT) && tur.Req().BytesPosted() < tur.Req().Headers().ContentLength()) ||
+			hasChunkedContent(tur) {
+			// Request content which is not read yet remains (or may remain) on the connection
+			resCon = "Close"
+		}
 		if tur.Res().Headers().GetConnection() != headers.CONNECTION_TYPE_KEEP_ALIVE {
 			clen := tur.Res().Headers().ContentLength()
 
@@ -120,6 +125,18 @@ func (h *H1InboundHandler) SendHeaders(tur tour.Tour) exception2.IOException {
 	return h.protocolHandler.Post(cmd, nil)
 }
 
+func (h *H1InboundHandler) SendInformational(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	// HTTP/1.0 clients do not understand interim responses
+	if tur.Req().Protocol() != "HTTP/1.1" {
+		return nil
+	}
+
+	baylog.Debug("%s H1 sendInformational: tur=%s status=%d", h.Ship(), tur, status)
+	cmd := NewResHeader(hdrs, tur.Req().Protocol())
+	cmd.status = status
+	return h.protocolHandler.Post(cmd, nil)
+}
+
 func (h *H1InboundHandler) SendContent(tour tour.Tour, bytes []byte, ofs int, length int, lis common.DataConsumeListener) exception2.IOException {
 	cmd := NewCmdContent(bytes, ofs, length)
 	return h.protocolHandler.Post(cmd, lis)
@@ -235,7 +252,13 @@ func (h *H1InboundHandler) HandleHeader(cmd *CmdHeader) (common.NextSocketAction
 	var ioerr exception2.IOException = nil
 	var hterr exception.HttpException = nil
 
+	expect := tur.Req().Headers().Get(headers.EXPECT)
 	for { // try/catch
+		if expect != "" && !tur.Req().ExpectsContinue() {
+			hterr = exception.NewHttpException(httpstatus.EXPECTATION_FAILED, expect)
+			break
+		}
+
 		hterr = h.startTour(tur)
 		if hterr != nil {
 			break
@@ -253,6 +276,7 @@ func (h *H1InboundHandler) HandleHeader(cmd *CmdHeader) (common.NextSocketAction
 			return common.NEXT_SOCKET_ACTION_SUSPEND, nil
 
 		} else {
+			// 100 Continue is sent by the city when the tour is admitted
 			h.changeState(STATE_READ_CONTENT)
 			return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 		}
@@ -265,7 +289,7 @@ func (h *H1InboundHandler) HandleHeader(cmd *CmdHeader) (common.NextSocketAction
 		// hterr != nil
 
 		baylog.DebugE(hterr, "%s Http error occurred: %v", h, hterr)
-		if reqContLen <= 0 {
+		if reqContLen <= 0 && !hasChunkedContent(tur) {
 			// not post data
 			ioerr = tur.Res().SendHttpException(impl.TOUR_ID_NOCHECK, hterr)
 			if ioerr != nil {
@@ -275,6 +299,17 @@ func (h *H1InboundHandler) HandleHeader(cmd *CmdHeader) (common.NextSocketAction
 			h.resetState()
 			return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 
+		} else if expect != "" || reqContLen <= 0 {
+			// Client may be waiting for 100 Continue, or content cannot be read.
+			// Send final status without reading content (Connection is closed after the response)
+			ioerr = tur.Res().SendHttpException(impl.TOUR_ID_NOCHECK, hterr)
+			if ioerr != nil {
+				return 0, ioerr
+			}
+
+			h.resetState()
+			return common.NEXT_SOCKET_ACTION_SUSPEND, nil
+
 		} else {
 			// Delay send
 			h.changeState(STATE_READ_CONTENT)
@@ -311,7 +346,7 @@ func (h *H1InboundHandler) HandleContent(cmd *CmdContent) (common.NextSocketActi
 			cmd.length,
 			func(length int, resume bool) {
 				if resume {
-					tur.Ship().(*ship.ShipImpl).ResumeRead(sid)
+					tur.Ship().(ship.Ship).ResumeRead(sid)
 				}
 			})
 
@@ -369,6 +404,10 @@ func (h *H1InboundHandler) ReqFinished() bool {
 	return h.state == COMMAND_STATE_READ_FINISHED
 }
 
+func (h *H1InboundHandler) HeaderExpected() bool {
+	return false
+}
+
 /****************************************/
 /* Private functions                    */
 /****************************************/
@@ -427,3 +466,12 @@ func (h *H1InboundHandler) endReqContent(checkTourId int, tur tour.Tour) (except
 	h.resetState()
 	return nil, nil
 }
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+// Checks if the request has content of unknown length (which this handler does not read)
+func hasChunkedContent(tur tour.Tour) bool {
+	return tur.Req().Headers().Contains(headers.HDR_TRANSFER_ENCODING)
+}
@@ -88,6 +88,13 @@ func (pu *H1PacketUnpacker) BytesReceived(buf []byte) (common.NextSocketAction,
 						return -1, ioerr
 					}
 
+					if nextAct == common.NEXT_SOCKET_ACTION_CONTINUE && pu.cmdUnpacker.HeaderExpected() {
+						// Interim (1xx) response received. Read next header
+						pu.tmpBuf.Reset()
+						lineLen = 0
+						continue
+					}
+
 					switch nextAct {
 					case common.NEXT_SOCKET_ACTION_CONTINUE, common.NEXT_SOCKET_ACTION_SUSPEND:
 						if pu.cmdUnpacker.ReqFinished() {
@@ -122,6 +122,10 @@ func (h *H1WarpHandler) SendHeaders(tur tour.Tour) exception2.IOException {
 	return sip.Post(cmd, nil)
 }
 
+func (h *H1WarpHandler) SendInformational(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	return nil
+}
+
 func (h *H1WarpHandler) SendContent(tur tour.Tour, buf []byte, start int, length int, lis common.DataConsumeListener) exception2.IOException {
 	cmd := NewCmdContent(buf, start, length)
 	return h.Ship().Post(cmd, lis)
@@ -187,6 +191,21 @@ func (h *H1WarpHandler) HandleHeader(cmd *CmdHeader) (common.NextSocketAction, e
 			baylog.Info("%s warp_http: resStatus: %d", wdat, cmd.status)
 		}
 
+		if cmd.status >= 100 && cmd.status < 200 && cmd.status != httpstatus.SWITCHING_PROTOCOLS {
+			// Interim response: relay it and wait for the final response
+			if cmd.status == httpstatus.EARLY_HINTS || (cmd.status == httpstatus.CONTINUE && tur.Req().ContinueRelayed()) {
+				hdrs := headers.NewHeaders()
+				for _, nv := range cmd.headers {
+					hdrs.Add(nv[0], nv[1])
+				}
+				ioerr = tur.Res().SendInformational(tourimpl.TOUR_ID_NOCHECK, cmd.status, hdrs)
+				if ioerr != nil {
+					break
+				}
+			}
+			return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+		}
+
 		for _, nv := range cmd.headers {
 			tur.Res().Headers().Add(nv[0], nv[1])
 			if bayserver.Harbor().TraceHeader() {
@@ -273,6 +292,10 @@ func (h *H1WarpHandler) ReqFinished() bool {
 	return h.state == STATE_FINISHED
 }
 
+func (h *H1WarpHandler) HeaderExpected() bool {
+	return h.state == STATE_READ_HEADER
+}
+
 /****************************************/
 /* Custom functions                     */
 /****************************************/
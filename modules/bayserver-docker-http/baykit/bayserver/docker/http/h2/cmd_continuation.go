@@ -0,0 +1,52 @@
+package h2
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * HTTP/2 Continuation payload format
+ *
+ * +---------------------------------------------------------------+
+ * |                   Header Block Fragment (*)                 ...
+ * +---------------------------------------------------------------+
+ */
+
+type CmdContinuation struct {
+	*H2CommandBase
+	Fragment []byte
+}
+
+func NewCmdContinuation(streamId int, flags *H2Flags) *CmdContinuation {
+	c := &CmdContinuation{
+		H2CommandBase: NewH2CommandBase(H2_TYPE_CONTINUATION, flags, streamId),
+	}
+	var _ protocol.Command = c // implement check
+	var _ H2Command = c        // implement check
+	return c
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdContinuation) Unpack(pkt protocol.Packet) exception.IOException {
+	c.H2CommandBase.Unpack(pkt.(*H2Packet))
+	acc := pkt.NewDataAccessor()
+	c.Fragment = make([]byte, pkt.DataLen())
+	acc.GetBytes(c.Fragment, 0, len(c.Fragment))
+	return nil
+}
+
+func (c *CmdContinuation) Pack(pkt protocol.Packet) exception.IOException {
+	acc := pkt.NewDataAccessor()
+	acc.PutBytes(c.Fragment, 0, len(c.Fragment))
+	c.H2CommandBase.Pack(pkt.(*H2Packet))
+	return nil
+}
+
+func (c *CmdContinuation) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception.IOException) {
+	return h.(H2CommandHandler).HandleContinuation(c)
+}
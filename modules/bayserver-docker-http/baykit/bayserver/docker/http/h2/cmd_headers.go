@@ -2,6 +2,7 @@ package h2
 
 import (
 	"bayserver-core/baykit/bayserver/common"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/protocol"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
@@ -30,6 +31,7 @@ type CmdHeaders struct {
 	StreamDependency int
 	Weight           int
 	HeaderBlocks     []*HeaderBlock
+	Fragment         []byte // Header block fragment (Decoded after END_HEADERS)
 }
 
 func NewCmdHeaders(streamId int, flags *H2Flags) *CmdHeaders {
@@ -60,7 +62,13 @@ func (c *CmdHeaders) Unpack(pkt protocol.Packet) exception.IOException {
 		c.StreamDependency = ExtractInt31(val)
 		c.Weight = acc.GetByte()
 	}
-	c.readHeaderBlock(acc, pkt.DataLen())
+
+	fragLen := pkt.DataLen() - acc.Pos() - c.PadLength
+	if fragLen < 0 {
+		return exception2.NewProtocolException("Invalid padding length: %d", c.PadLength)
+	}
+	c.Fragment = make([]byte, fragLen)
+	acc.GetBytes(c.Fragment, 0, fragLen)
 	return nil
 }
 
@@ -75,7 +83,11 @@ func (c *CmdHeaders) Pack(pkt protocol.Packet) exception.IOException {
 		acc.PutInt(MakeStreamDependency32(c.Excluded, c.StreamDependency))
 		acc.PutByte(c.Weight)
 	}
-	c.writeHeaderBlock(acc)
+	if c.Fragment != nil {
+		acc.PutBytes(c.Fragment, 0, len(c.Fragment))
+	} else {
+		c.writeHeaderBlock(acc)
+	}
 	c.H2CommandBase.Pack(pkt.(*H2Packet))
 	return nil
 }
@@ -84,14 +96,40 @@ func (c *CmdHeaders) Handle(h protocol.CommandHandler) (common.NextSocketAction,
 	return h.(H2CommandHandler).HandleHeaders(c)
 }
 
-func (c *CmdHeaders) readHeaderBlock(acc *H2DataAccessor, length int) {
-	for acc.Pos() < length {
-		blk := HeaderBlockUnPack(acc)
+// DecodeHeaderBlocks decodes the assembled header block fragment
+func (c *CmdHeaders) DecodeHeaderBlocks() exception2.ProtocolException {
+	acc := NewH2FragmentAccessor(c.Fragment)
+	for acc.Remaining() > 0 {
+		blk, perr := HeaderBlockUnPack(acc)
+		if perr != nil {
+			return perr
+		}
 		if baylog.IsTraceMode() {
 			baylog.Trace("h2: header block read: %s", blk)
 		}
+		if blk.op == HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE && len(c.HeaderBlocks) > 0 &&
+			c.HeaderBlocks[len(c.HeaderBlocks)-1].op != HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE {
+			// Table size update must appear at the beginning of header block (RFC 7541 4.2)
+			return exception2.NewProtocolException("Dynamic table size update after header field")
+		}
 		c.AddHeaderBlock(blk)
 	}
+	return nil
+}
+
+// EncodeHeaderBlocks encodes header blocks to make header block fragment
+func EncodeHeaderBlocks(blks []*HeaderBlock) []byte {
+	pkt := NewH2Packet(H2_TYPE_HEADERS)
+	acc := pkt.NewH2DataAccessor()
+	for _, blk := range blks {
+		HeaderBlockPack(blk, acc)
+	}
+	return append([]byte{}, pkt.Buf()[pkt.HeaderLen():pkt.BufLen()]...)
+}
+
+// AppendFragment appends header block fragment of CONTINUATION frame
+func (c *CmdHeaders) AppendFragment(fragment []byte) {
+	c.Fragment = append(c.Fragment, fragment...)
 }
 
 func (c *CmdHeaders) writeHeaderBlock(acc *H2DataAccessor) {
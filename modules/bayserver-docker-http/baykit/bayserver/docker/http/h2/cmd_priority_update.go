@@ -0,0 +1,63 @@
+package h2
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * HTTP/2 PriorityUpdate payload format (RFC 9218)
+ *
+ * +-+-------------------------------------------------------------+
+ * |R|                Prioritized Stream ID (31)                   |
+ * +-+-------------------------------------------------------------+
+ * |                  Priority Field Value (*)                   ...
+ * +---------------------------------------------------------------+
+ */
+
+type CmdPriorityUpdate struct {
+	*H2CommandBase
+	PrioritizedStreamId int
+	PriorityFieldValue  string
+}
+
+func NewCmdPriorityUpdate(streamId int, flags *H2Flags) *CmdPriorityUpdate {
+	c := &CmdPriorityUpdate{
+		H2CommandBase: NewH2CommandBase(H2_TYPE_PRIORITY_UPDATE, flags, streamId),
+	}
+	var _ protocol.Command = c // implement check
+	var _ H2Command = c        // implement check
+	return c
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdPriorityUpdate) Unpack(pkt protocol.Packet) exception.IOException {
+	h2pkt := pkt.(*H2Packet)
+	c.H2CommandBase.Unpack(h2pkt)
+
+	acc := h2pkt.NewH2DataAccessor()
+	c.PrioritizedStreamId = ExtractInt31(acc.GetInt())
+
+	value := make([]byte, pkt.DataLen()-4)
+	acc.GetBytes(value, 0, len(value))
+	c.PriorityFieldValue = string(value)
+	return nil
+}
+
+func (c *CmdPriorityUpdate) Pack(pkt protocol.Packet) exception.IOException {
+	h2pkt := pkt.(*H2Packet)
+	acc := h2pkt.NewH2DataAccessor()
+	acc.PutInt(ConsolidateFlagAndInt32(0, c.PrioritizedStreamId))
+	value := []byte(c.PriorityFieldValue)
+	acc.PutBytes(value, 0, len(value))
+	c.H2CommandBase.Pack(h2pkt)
+	return nil
+}
+
+func (c *CmdPriorityUpdate) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception.IOException) {
+	return h.(H2CommandHandler).HandlePriorityUpdate(c)
+}
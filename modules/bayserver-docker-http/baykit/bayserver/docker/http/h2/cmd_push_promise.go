@@ -0,0 +1,78 @@
+package h2
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * HTTP/2 PushPromise payload format
+ *
+ * +---------------+
+ * |Pad Length? (8)|
+ * +-+-------------+-----------------------------------------------+
+ * |R|                  Promised Stream ID (31)                    |
+ * +-+-----------------------------+-------------------------------+
+ * |                   Header Block Fragment (*)                 ...
+ * +---------------------------------------------------------------+
+ * |                           Padding (*)                       ...
+ * +---------------------------------------------------------------+
+ */
+
+type CmdPushPromise struct {
+	*H2CommandBase
+	PadLength        int
+	PromisedStreamId int
+	Fragment         []byte
+}
+
+func NewCmdPushPromise(streamId int, flags *H2Flags) *CmdPushPromise {
+	c := &CmdPushPromise{
+		H2CommandBase: NewH2CommandBase(H2_TYPE_PUSH_PROMISE, flags, streamId),
+	}
+	var _ protocol.Command = c // implement check
+	var _ H2Command = c        // implement check
+	return c
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdPushPromise) Unpack(pkt protocol.Packet) exception.IOException {
+	h2pkt := pkt.(*H2Packet)
+	c.H2CommandBase.Unpack(h2pkt)
+
+	acc := h2pkt.NewH2DataAccessor()
+	if h2pkt.Flags.IsPadded() {
+		c.PadLength = acc.GetByte()
+	}
+	c.PromisedStreamId = ExtractInt31(acc.GetInt())
+
+	fragLen := pkt.DataLen() - acc.Pos() - c.PadLength
+	if fragLen < 0 {
+		return exception2.NewProtocolException("Invalid padding length: %d", c.PadLength)
+	}
+	c.Fragment = make([]byte, fragLen)
+	acc.GetBytes(c.Fragment, 0, fragLen)
+	return nil
+}
+
+func (c *CmdPushPromise) Pack(pkt protocol.Packet) exception.IOException {
+	h2pkt := pkt.(*H2Packet)
+
+	acc := h2pkt.NewH2DataAccessor()
+	if c.Flags().IsPadded() {
+		acc.PutByte(c.PadLength)
+	}
+	acc.PutInt(ConsolidateFlagAndInt32(0, c.PromisedStreamId))
+	acc.PutBytes(c.Fragment, 0, len(c.Fragment))
+	c.H2CommandBase.Pack(h2pkt)
+	return nil
+}
+
+func (c *CmdPushPromise) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception.IOException) {
+	return h.(H2CommandHandler).HandlePushPromise(c)
+}
@@ -20,7 +20,7 @@ type CmdRstStream struct {
 	ErrorCode int
 }
 
-func NewCmdRstStream(streamId int, flags *H2Flags) protocol.Command {
+func NewCmdRstStream(streamId int, flags *H2Flags) *CmdRstStream {
 	c := &CmdRstStream{
 		H2CommandBase: NewH2CommandBase(H2_TYPE_RST_STREAM, flags, streamId),
 	}
@@ -29,6 +29,8 @@ const MAX_FRAME_SIZE = 0x5
 
 const MAX_HEADER_LIST_SIZE = 0x6
 
+const NO_RFC7540_PRIORITIES = 0x9
+
 const INIT_HEADER_TABLE_SIZE = 4096
 
 const INIT_ENABLE_PUSH = 1
@@ -17,4 +17,7 @@ type H2CommandHandler interface {
 	HandleGoAway(cmd *CmdGoAway) (common.NextSocketAction, exception.IOException)
 	HandlePing(cmd *CmdPing) (common.NextSocketAction, exception.IOException)
 	HandleRstStream(cmd *CmdRstStream) (common.NextSocketAction, exception.IOException)
+	HandleContinuation(cmd *CmdContinuation) (common.NextSocketAction, exception.IOException)
+	HandlePushPromise(cmd *CmdPushPromise) (common.NextSocketAction, exception.IOException)
+	HandlePriorityUpdate(cmd *CmdPriorityUpdate) (common.NextSocketAction, exception.IOException)
 }
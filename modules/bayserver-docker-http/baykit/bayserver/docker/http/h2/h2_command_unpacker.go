@@ -2,6 +2,7 @@ package h2
 
 import (
 	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/protocol"
 	"bayserver-core/baykit/bayserver/protocol/impl"
 	"bayserver-core/baykit/bayserver/util/baylog"
@@ -11,6 +12,9 @@ import (
 type H2CommandUnPacker struct {
 	impl.CommandUnpackerImpl
 	cmdHandler H2CommandHandler
+
+	// Stream id of header block in progress (0 means none)
+	headerStreamId int
 }
 
 func NewH2CommandUnpacker(handler H2CommandHandler) *H2CommandUnPacker {
@@ -20,6 +24,14 @@ func NewH2CommandUnpacker(handler H2CommandHandler) *H2CommandUnPacker {
 	return &cu
 }
 
+/****************************************/
+/* Implements Reusable                  */
+/****************************************/
+
+func (cu *H2CommandUnPacker) Reset() {
+	cu.headerStreamId = 0
+}
+
 /****************************************/
 /* Implements CommandUnpacker           */
 /****************************************/
@@ -28,6 +40,22 @@ func (cu *H2CommandUnPacker) PacketReceived(pkt protocol.Packet) (common.NextSoc
 	baylog.Debug("h1: read packet type=%d length=%d", pkt.Type(), pkt.DataLen())
 	h2pkt := pkt.(*H2Packet)
 
+	// Header block must be followed by CONTINUATION frames of the same stream only
+	if cu.headerStreamId != 0 {
+		if pkt.Type() != H2_TYPE_CONTINUATION || h2pkt.StreamId != cu.headerStreamId {
+			return -1, exception.NewProtocolException("Header block interrupted: type=%d stm=%d", pkt.Type(), h2pkt.StreamId)
+		}
+		if h2pkt.Flags.IsEndHeaders() {
+			cu.headerStreamId = 0
+		}
+
+	} else if pkt.Type() == H2_TYPE_CONTINUATION {
+		return -1, exception.NewProtocolException("Unexpected CONTINUATION frame: stm=%d", h2pkt.StreamId)
+
+	} else if pkt.Type() == H2_TYPE_HEADERS && !h2pkt.Flags.IsEndHeaders() {
+		cu.headerStreamId = h2pkt.StreamId
+	}
+
 	var cmd protocol.Command
 	switch pkt.Type() {
 	case H2_TYPE_PREFACE:
@@ -57,9 +85,19 @@ func (cu *H2CommandUnPacker) PacketReceived(pkt protocol.Packet) (common.NextSoc
 	case H2_TYPE_RST_STREAM:
 		cmd = NewCmdRstStream(h2pkt.StreamId, h2pkt.Flags)
 
+	case H2_TYPE_CONTINUATION:
+		cmd = NewCmdContinuation(h2pkt.StreamId, h2pkt.Flags)
+
+	case H2_TYPE_PUSH_PROMISE:
+		cmd = NewCmdPushPromise(h2pkt.StreamId, h2pkt.Flags)
+
+	case H2_TYPE_PRIORITY_UPDATE:
+		cmd = NewCmdPriorityUpdate(h2pkt.StreamId, h2pkt.Flags)
+
 	default:
-		baylog.FatalE(exception2.NewSink("Illegal State"), "")
-		panic("Error")
+		// Unknown frame types must be ignored (RFC 7540 4.1)
+		baylog.Debug("h2: ignore unknown frame type=%d", pkt.Type())
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 	}
 
 	ioerr := cmd.Unpack(pkt)
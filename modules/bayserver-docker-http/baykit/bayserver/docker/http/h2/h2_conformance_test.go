@@ -0,0 +1,297 @@
+package h2
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/util/exception"
+	"testing"
+)
+
+/**
+ * Conformance tests of frame sequencing and HPACK decoding
+ * (Based on h2spec test cases of RFC 7540 6.10 and RFC 7541)
+ */
+
+/****************************************/
+/* Helpers                              */
+/****************************************/
+
+type recordingHandler struct {
+	H2CommandHandler
+	headers       []*CmdHeaders
+	continuations []*CmdContinuation
+	pings         int
+}
+
+func (h *recordingHandler) HandleHeaders(cmd *CmdHeaders) (common.NextSocketAction, exception.IOException) {
+	h.headers = append(h.headers, cmd)
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+func (h *recordingHandler) HandleContinuation(cmd *CmdContinuation) (common.NextSocketAction, exception.IOException) {
+	h.continuations = append(h.continuations, cmd)
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+func (h *recordingHandler) HandlePing(cmd *CmdPing) (common.NextSocketAction, exception.IOException) {
+	h.pings++
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+func newFrame(typ int, flags int, stmId int, payload []byte) *H2Packet {
+	pkt := NewH2Packet(typ)
+	pkt.Flags = NewH2Flags(flags)
+	pkt.StreamId = stmId
+	pkt.NewH2DataAccessor().PutBytes(payload, 0, len(payload))
+	return pkt
+}
+
+func receive(t *testing.T, cu *H2CommandUnPacker, pkt *H2Packet) exception.IOException {
+	t.Helper()
+	_, ioerr := cu.PacketReceived(pkt)
+	return ioerr
+}
+
+// ":method: GET", ":scheme: http", ":path: /"
+var getFragment = []byte{0x82, 0x86, 0x84}
+
+/****************************************/
+/* CONTINUATION sequencing              */
+/****************************************/
+
+func TestHeadersWithContinuation(t *testing.T) {
+	h := &recordingHandler{}
+	cu := NewH2CommandUnpacker(h)
+
+	if err := receive(t, cu, newFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM, 1, getFragment[:1])); err != nil {
+		t.Fatalf("HEADERS rejected: %v", err)
+	}
+	if err := receive(t, cu, newFrame(H2_TYPE_CONTINUATION, FLAGS_NONE, 1, getFragment[1:2])); err != nil {
+		t.Fatalf("first CONTINUATION rejected: %v", err)
+	}
+	if err := receive(t, cu, newFrame(H2_TYPE_CONTINUATION, FLAGS_END_HEADERS, 1, getFragment[2:])); err != nil {
+		t.Fatalf("last CONTINUATION rejected: %v", err)
+	}
+	if len(h.headers) != 1 || len(h.continuations) != 2 {
+		t.Fatalf("unexpected commands: headers=%d continuations=%d", len(h.headers), len(h.continuations))
+	}
+
+	// Header block is complete, so other frames are accepted again
+	if err := receive(t, cu, newFrame(H2_TYPE_PING, FLAGS_NONE, 0, make([]byte, 8))); err != nil {
+		t.Fatalf("PING after header block rejected: %v", err)
+	}
+}
+
+func TestHeaderBlockInterruptedByOtherFrame(t *testing.T) {
+	h := &recordingHandler{}
+	cu := NewH2CommandUnpacker(h)
+
+	if err := receive(t, cu, newFrame(H2_TYPE_HEADERS, FLAGS_NONE, 1, getFragment)); err != nil {
+		t.Fatalf("HEADERS rejected: %v", err)
+	}
+	if err := receive(t, cu, newFrame(H2_TYPE_PING, FLAGS_NONE, 0, make([]byte, 8))); err == nil {
+		t.Fatal("PING interleaved in header block must be a connection error")
+	}
+	if h.pings != 0 {
+		t.Fatal("interleaved PING must not be handled")
+	}
+}
+
+func TestHeaderBlockInterruptedByOtherStream(t *testing.T) {
+	cu := NewH2CommandUnpacker(&recordingHandler{})
+
+	if err := receive(t, cu, newFrame(H2_TYPE_HEADERS, FLAGS_NONE, 1, getFragment)); err != nil {
+		t.Fatalf("HEADERS rejected: %v", err)
+	}
+	if err := receive(t, cu, newFrame(H2_TYPE_CONTINUATION, FLAGS_END_HEADERS, 3, nil)); err == nil {
+		t.Fatal("CONTINUATION of other stream must be a connection error")
+	}
+}
+
+func TestHeaderBlockInterruptedByHeaders(t *testing.T) {
+	cu := NewH2CommandUnpacker(&recordingHandler{})
+
+	if err := receive(t, cu, newFrame(H2_TYPE_HEADERS, FLAGS_NONE, 1, getFragment)); err != nil {
+		t.Fatalf("HEADERS rejected: %v", err)
+	}
+	if err := receive(t, cu, newFrame(H2_TYPE_HEADERS, FLAGS_END_HEADERS, 3, getFragment)); err == nil {
+		t.Fatal("HEADERS interleaved in header block must be a connection error")
+	}
+}
+
+func TestUnexpectedContinuation(t *testing.T) {
+	cu := NewH2CommandUnpacker(&recordingHandler{})
+
+	if err := receive(t, cu, newFrame(H2_TYPE_CONTINUATION, FLAGS_END_HEADERS, 1, getFragment)); err == nil {
+		t.Fatal("CONTINUATION without HEADERS must be a connection error")
+	}
+
+	cu = NewH2CommandUnpacker(&recordingHandler{})
+	if err := receive(t, cu, newFrame(H2_TYPE_HEADERS, FLAGS_END_HEADERS, 1, getFragment)); err != nil {
+		t.Fatalf("HEADERS rejected: %v", err)
+	}
+	if err := receive(t, cu, newFrame(H2_TYPE_CONTINUATION, FLAGS_END_HEADERS, 1, nil)); err == nil {
+		t.Fatal("CONTINUATION after END_HEADERS must be a connection error")
+	}
+}
+
+/****************************************/
+/* HPACK decoding                       */
+/****************************************/
+
+func decodeFragment(fragment []byte) ([]*HeaderBlock, exception.IOException) {
+	cmd := NewCmdHeaders(1, nil)
+	cmd.Fragment = fragment
+	perr := cmd.DecodeHeaderBlocks()
+	if perr != nil {
+		return nil, perr
+	}
+	return cmd.HeaderBlocks, nil
+}
+
+func TestIndexedHeaderField(t *testing.T) {
+	blks, err := decodeFragment(getFragment)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	tbl := CreateDynamicTable()
+	anl := NewHeaderBlockAnalyzer()
+	if perr := anl.AnalyzeHeaderBlock(blks[0], tbl); perr != nil || anl.Method != "GET" {
+		t.Fatalf("unexpected method: %s (%v)", anl.Method, perr)
+	}
+	if perr := anl.AnalyzeHeaderBlock(blks[2], tbl); perr != nil || anl.Path != "/" {
+		t.Fatalf("unexpected path: %s (%v)", anl.Path, perr)
+	}
+}
+
+func TestTruncatedIndex(t *testing.T) {
+	// 7 bit prefix is full but no continuation byte follows
+	if _, err := decodeFragment([]byte{0xFF}); err == nil {
+		t.Fatal("truncated index must be an error")
+	}
+	// Continuation bit is set on the last byte
+	if _, err := decodeFragment([]byte{0xFF, 0x80}); err == nil {
+		t.Fatal("truncated index must be an error")
+	}
+}
+
+func TestTruncatedString(t *testing.T) {
+	// New header name whose length exceeds the fragment
+	if _, err := decodeFragment([]byte{0x40, 0x05, 'a', 'b'}); err == nil {
+		t.Fatal("truncated string must be an error")
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	if _, err := decodeFragment([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}); err == nil {
+		t.Fatal("too large integer must be an error")
+	}
+}
+
+func TestTableSizeUpdate(t *testing.T) {
+	tbl := CreateDynamicTable()
+	anl := NewHeaderBlockAnalyzer()
+
+	// Fill dynamic table
+	tbl.Insert("x-test", "value")
+	if tbl.Size() != EntrySize("x-test", "value") {
+		t.Fatalf("unexpected table size: %d", tbl.Size())
+	}
+
+	// Size update to 0 evicts all entries (0x20 = 001 00000)
+	blks, err := decodeFragment([]byte{0x20})
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if perr := anl.AnalyzeHeaderBlock(blks[0], tbl); perr != nil {
+		t.Fatalf("size update rejected: %v", perr)
+	}
+	if tbl.Size() != 0 || tbl.MaxSize() != 0 {
+		t.Fatalf("table not emptied: size=%d max=%d", tbl.Size(), tbl.MaxSize())
+	}
+
+	// Size update using multi-byte integer (31 + 4065 = 4096)
+	blks, err = decodeFragment([]byte{0x3F, 0xE1, 0x1F})
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if perr := anl.AnalyzeHeaderBlock(blks[0], tbl); perr != nil {
+		t.Fatalf("size update rejected: %v", perr)
+	}
+	if tbl.MaxSize() != DEFAULT_HEADER_TABLE_SIZE {
+		t.Fatalf("unexpected max size: %d", tbl.MaxSize())
+	}
+}
+
+func TestTableSizeUpdateExceedsLimit(t *testing.T) {
+	tbl := CreateDynamicTable()
+	anl := NewHeaderBlockAnalyzer()
+
+	// 31 + 4066 = 4097 > SETTINGS_HEADER_TABLE_SIZE
+	blks, err := decodeFragment([]byte{0x3F, 0xE2, 0x1F})
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if perr := anl.AnalyzeHeaderBlock(blks[0], tbl); perr == nil {
+		t.Fatal("size update over the limit must be an error")
+	}
+}
+
+func TestTruncatedTableSizeUpdate(t *testing.T) {
+	if _, err := decodeFragment([]byte{0x3F}); err == nil {
+		t.Fatal("truncated size update must be an error")
+	}
+}
+
+func TestTableSizeUpdateAfterHeaderField(t *testing.T) {
+	// Size update must be at the beginning of the header block
+	if _, err := decodeFragment([]byte{0x82, 0x20}); err == nil {
+		t.Fatal("size update after header field must be an error")
+	}
+	if _, err := decodeFragment([]byte{0x20, 0x3F, 0xE1, 0x1F, 0x82}); err != nil {
+		t.Fatalf("size updates at the beginning rejected: %v", err)
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	encTbl := CreateDynamicTable()
+	bld := NewHeaderBlockBuilder()
+	var blks []*HeaderBlock
+	blks = append(blks, bld.BuildTableSizeUpdate(256, encTbl))
+	for _, kv := range [][2]string{{"content-type", "text/html"}, {"x-custom", "foo"}, {"x-custom", "foo"}} {
+		blk, perr := bld.BuildHeaderBlock(kv[0], kv[1], encTbl)
+		if perr != nil {
+			t.Fatalf("build failed: %v", perr)
+		}
+		blks = append(blks, blk)
+	}
+
+	decoded, err := decodeFragment(EncodeHeaderBlocks(blks))
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	decTbl := CreateDynamicTable()
+	anl := NewHeaderBlockAnalyzer()
+	var got []string
+	for _, blk := range decoded {
+		if perr := anl.AnalyzeHeaderBlock(blk, decTbl); perr != nil {
+			t.Fatalf("analyze failed: %v", perr)
+		}
+		if anl.Name != "" {
+			got = append(got, anl.Name+": "+anl.Value)
+		}
+	}
+	want := []string{"content-type: text/html", "x-custom: foo", "x-custom: foo"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected headers: %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected header: %s (want %s)", got[i], want[i])
+		}
+	}
+	if decTbl.MaxSize() != 256 || decTbl.Size() != encTbl.Size() {
+		t.Fatalf("tables out of sync: dec=%d/%d enc=%d/%d", decTbl.Size(), decTbl.MaxSize(), encTbl.Size(), encTbl.MaxSize())
+	}
+}
@@ -2,6 +2,7 @@ package h2
 
 import (
 	"bayserver-core/baykit/bayserver/bayserver"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/protocol"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-docker-http/baykit/bayserver/docker/http/h2/huffman"
@@ -9,31 +10,59 @@ import (
 
 type H2DataAccessor struct {
 	*protocol.PacketPartAccessor
+	limit int
 }
 
 func NewH2DataAccessor(pkt protocol.Packet, start int, maxLen int) *H2DataAccessor {
+	limit := maxLen
+	if limit < 0 {
+		limit = pkt.BufLen() - start
+	}
 	return &H2DataAccessor{
-		protocol.NewPacketPartAccessor(pkt, start, maxLen),
+		PacketPartAccessor: protocol.NewPacketPartAccessor(pkt, start, maxLen),
+		limit:              limit,
 	}
 }
 
-func (a *H2DataAccessor) GetHPackInt(prefix int, head *int) int {
+// NewH2FragmentAccessor creates accessor to read an assembled header block fragment
+func NewH2FragmentAccessor(fragment []byte) *H2DataAccessor {
+	pkt := NewH2Packet(H2_TYPE_HEADERS)
+	NewH2DataAccessor(pkt, pkt.HeaderLen(), -1).PutBytes(fragment, 0, len(fragment))
+	return NewH2DataAccessor(pkt, pkt.HeaderLen(), len(fragment))
+}
+
+func (a *H2DataAccessor) Remaining() int {
+	return a.limit - a.Pos()
+}
+
+func (a *H2DataAccessor) GetHPackInt(prefix int, head *int) (int, exception2.ProtocolException) {
+	if a.Remaining() < 1 {
+		return 0, exception2.NewProtocolException("HPACK integer truncated")
+	}
 	maxVal := 0xFF >> (8 - prefix)
 
 	firstByte := a.GetByte()
 	firstVal := firstByte & maxVal
 	*head = firstByte >> prefix
 	if firstVal != maxVal {
-		return firstVal
+		return firstVal, nil
 
 	} else {
-		return maxVal + a.GetHPackIntRest()
+		rest, perr := a.GetHPackIntRest()
+		return maxVal + rest, perr
 	}
 }
 
-func (a *H2DataAccessor) GetHPackIntRest() int {
+func (a *H2DataAccessor) GetHPackIntRest() (int, exception2.ProtocolException) {
 	rest := 0
 	for i := 0; ; i++ {
+		if a.Remaining() < 1 {
+			return 0, exception2.NewProtocolException("HPACK integer truncated")
+		}
+		if i > 3 {
+			// More than 28 bits is not acceptable
+			return 0, exception2.NewProtocolException("HPACK integer overflow")
+		}
 		data := a.GetByte()
 		cont := (data & 0x80) != 0
 		value := data & 0x7F
@@ -42,21 +71,31 @@ func (a *H2DataAccessor) GetHPackIntRest() int {
 			break
 		}
 	}
-	return rest
+	return rest, nil
 }
 
-func (a *H2DataAccessor) GetHPackString() string {
+func (a *H2DataAccessor) GetHPackString() (string, exception2.ProtocolException) {
 	isHuffman := 0
-	length := a.GetHPackInt(7, &isHuffman)
+	length, perr := a.GetHPackInt(7, &isHuffman)
+	if perr != nil {
+		return "", perr
+	}
+	if length > a.Remaining() {
+		return "", exception2.NewProtocolException("HPACK string truncated: len=%d", length)
+	}
 	data := make([]byte, length)
 	a.GetBytes(data, 0, length)
 	if isHuffman == 1 {
 		// Huffman
-		return huffman.HTreeDecode(data)
+		str, ok := huffman.HTreeDecode(data)
+		if !ok {
+			return "", exception2.NewProtocolException("Invalid huffman code")
+		}
+		return str, nil
 
 	} else {
 		// ASCII
-		return string(data)
+		return string(data), nil
 	}
 }
 
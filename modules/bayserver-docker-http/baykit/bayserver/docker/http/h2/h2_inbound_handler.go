@@ -6,9 +6,10 @@ import (
 	"bayserver-core/baykit/bayserver/common/baymessage"
 	"bayserver-core/baykit/bayserver/common/exception"
 	common2 "bayserver-core/baykit/bayserver/common/inboundship/impl"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/docker/file"
 	impl2 "bayserver-core/baykit/bayserver/rudder/impl"
 	ship2 "bayserver-core/baykit/bayserver/ship"
-	ship "bayserver-core/baykit/bayserver/ship/impl"
 	"bayserver-core/baykit/bayserver/symbol"
 	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/tour/impl"
@@ -17,16 +18,24 @@ import (
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/headers"
 	"bayserver-core/baykit/bayserver/util/httpstatus"
+	"bayserver-core/baykit/bayserver/util/sysutil"
+	"bayserver-docker-http/baykit/bayserver/docker/http"
 	"bayserver-docker-http/baykit/bayserver/docker/http/h2/h2_error_code"
 	"net"
+	pathutil "path"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const COMMAND_STATE_READ_HEADER = 1
 const COMMAND_STATE_READ_CONTENT = 2
 const COMMAND_STATE_READ_FINISHED = 3
 
+// Upper limit of assembled header block fragment (HEADERS + CONTINUATION)
+const MAX_HEADER_BLOCK_LEN = 0x100000
+
 type H2InboundHandler struct {
 	protocolHandler *H2ProtocolHandlerImpl
 	headerRead      bool
@@ -39,6 +48,37 @@ type H2InboundHandler struct {
 	analyzer     *HeaderBlockAnalyzer
 	reqHeaderTbl *HeaderTable
 	resHeaderTbl *HeaderTable
+
+	// HEADERS waiting for CONTINUATION frames
+	pendingHeaders *CmdHeaders
+
+	// Encoder state must be updated in the order of sending
+	resLock            sync.Mutex
+	resTableSizeUpdate int
+
+	// Server push
+	serverPush       bool
+	nextPushStreamId int
+	pushedPaths      map[string]bool
+
+	// Extensible priorities (RFC 9218)
+	prioritization bool
+	scheduler      *H2WriteScheduler
+
+	// Flood protection
+	maxConcurrentStreams int
+	maxStreams           int
+	maxHeaderListSize    int
+	activeStreams        map[int]bool
+	streamLock           sync.Mutex
+	streamCount          int
+	lastStreamId         int
+	goingAway            bool
+	rstLimiter           *H2RateLimiter
+	pingLimiter          *H2RateLimiter
+	settingsLimiter      *H2RateLimiter
+	priorityLimiter      *H2RateLimiter
+	emptyDataLimiter     *H2RateLimiter
 }
 
 func NewH2InboundHandler() *H2InboundHandler {
@@ -48,6 +88,16 @@ func NewH2InboundHandler() *H2InboundHandler {
 	h.analyzer = NewHeaderBlockAnalyzer()
 	h.reqHeaderTbl = CreateDynamicTable()
 	h.resHeaderTbl = CreateDynamicTable()
+	h.resTableSizeUpdate = -1
+	h.nextPushStreamId = 2
+	h.pushedPaths = make(map[string]bool)
+	h.scheduler = NewH2WriteScheduler(h.postData)
+	h.activeStreams = make(map[int]bool)
+	h.rstLimiter = NewH2RateLimiter()
+	h.pingLimiter = NewH2RateLimiter()
+	h.settingsLimiter = NewH2RateLimiter()
+	h.priorityLimiter = NewH2RateLimiter()
+	h.emptyDataLimiter = NewH2RateLimiter()
 
 	var _ tour.TourHandler = h // implement check
 	var _ H2Handler = h        // implement check
@@ -72,6 +122,28 @@ func (h *H2InboundHandler) Reset() {
 	h.httpProtocol = ""
 	h.reqContLen = 0
 	h.reqContRead = 0
+	h.settings.Reset()
+	h.reqHeaderTbl = CreateDynamicTable()
+	h.resHeaderTbl = CreateDynamicTable()
+	h.resTableSizeUpdate = -1
+	h.pendingHeaders = nil
+	h.serverPush = false
+	h.nextPushStreamId = 2
+	h.pushedPaths = make(map[string]bool)
+	h.prioritization = false
+	h.scheduler.Reset()
+
+	h.streamLock.Lock()
+	h.activeStreams = make(map[int]bool)
+	h.streamLock.Unlock()
+	h.streamCount = 0
+	h.lastStreamId = 0
+	h.goingAway = false
+	h.rstLimiter.Reset()
+	h.pingLimiter.Reset()
+	h.settingsLimiter.Reset()
+	h.priorityLimiter.Reset()
+	h.emptyDataLimiter.Reset()
 }
 
 /****************************************/
@@ -100,55 +172,72 @@ func (h *H2InboundHandler) OnProtocolError(err exception.ProtocolException) (boo
 /****************************************/
 
 func (h *H2InboundHandler) SendHeaders(tur tour.Tour) exception2.IOException {
-	cmd := NewCmdHeaders(tur.Req().Key(), nil)
+	h.resLock.Lock()
 
-	bld := NewHeaderBlockBuilder()
+	// Promise resources before sending headers which refer to them
+	pushes, ioerr := h.promiseResources(tur, tur.Res().Headers().HeaderValues(headers.LINK))
+	if ioerr != nil {
+		h.resLock.Unlock()
+		return ioerr
+	}
 
-	blk, perr := bld.BuildHeaderBlock(":status", strconv.Itoa(tur.Res().Headers().Status()), h.resHeaderTbl)
-	if perr != nil {
-		return perr
+	// Origin server can override the priority requested by client (RFC 9218 8)
+	if h.prioritization && tur.Res().Headers().Contains(headers.PRIORITY) {
+		stmId := tur.Req().Key()
+		pri := ParsePriority(tur.Res().Headers().Get(headers.PRIORITY), h.scheduler.Priority(stmId))
+		baylog.Debug("%s response priority: stm=%d %s", h.Ship(), stmId, pri)
+		h.scheduler.SetPriority(stmId, pri)
 	}
-	cmd.HeaderBlocks = append(cmd.HeaderBlocks, blk)
 
-	// headers
-	if bayserver.Harbor().TraceHeader() {
-		baylog.Info("%s H2 res status: %d", tur, tur.Res().Headers().Status())
+	ioerr = h.sendHeaderBlock(tur, tur.Res().Headers().Status(), tur.Res().Headers())
+	h.resLock.Unlock()
+	if ioerr != nil {
+		return ioerr
 	}
-	for _, name := range tur.Res().Headers().HeaderNames() {
-		if strings.ToLower(name) == "connection" {
-			baylog.Trace("%s Connection header is discarded", tur)
 
-		} else {
-			for _, value := range tur.Res().Headers().HeaderValues(name) {
-				if bayserver.Harbor().TraceHeader() {
-					baylog.Info("%s H2 res header: %s=%s", tur, name, value)
-				}
-				blk, perr = bld.BuildHeaderBlock(name, value, h.resHeaderTbl)
-				if perr != nil {
-					return perr
-				}
-				cmd.HeaderBlocks = append(cmd.HeaderBlocks, blk)
-			}
+	h.startPushTours(pushes)
+	return nil
+}
+
+func (h *H2InboundHandler) SendInformational(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	if !isClientStream(tur.Req().Key()) {
+		// Pushed response cannot have interim responses
+		return nil
+	}
+
+	h.resLock.Lock()
+
+	var pushes []tour.Tour = nil
+	var ioerr exception2.IOException = nil
+	if status == httpstatus.EARLY_HINTS {
+		pushes, ioerr = h.promiseResources(tur, hdrs.HeaderValues(headers.LINK))
+		if ioerr != nil {
+			h.resLock.Unlock()
+			return ioerr
 		}
 	}
 
-	cmd.flags.SetEndHeaders(true)
-	cmd.Excluded = false
-	// cmd.streamDependency = streamId;
-	cmd.flags.SetPadded(false)
+	ioerr = h.sendHeaderBlock(tur, status, hdrs)
+	h.resLock.Unlock()
+	if ioerr != nil {
+		return ioerr
+	}
 
-	return h.protocolHandler.Post(cmd, nil)
+	h.startPushTours(pushes)
+	return nil
 }
 
 func (h *H2InboundHandler) SendContent(tur tour.Tour, bytes []byte, ofs int, length int, lis common.DataConsumeListener) exception2.IOException {
 	cmd := NewCmdData(tur.Req().Key(), nil, bytes, ofs, length)
-	return h.protocolHandler.Post(cmd, lis)
+	return h.scheduler.Post(cmd, tourListener(tur, lis))
 }
 
 func (h *H2InboundHandler) SendEnd(tur tour.Tour, keepAlive bool, lis common.DataConsumeListener) exception2.IOException {
+	h.removeActiveStream(tur.Req().Key())
+
 	cmd := NewCmdData(tur.Req().Key(), nil, []byte{0}, 0, 0)
 	cmd.flags.SetEndStream(true)
-	return h.protocolHandler.Post(cmd, lis)
+	return h.scheduler.Post(cmd, tourListener(tur, lis))
 }
 
 /****************************************/
@@ -160,11 +249,23 @@ func (h *H2InboundHandler) HandlePreface(cmd *CmdPreface) (common.NextSocketActi
 	baylog.Debug("%s h2: handle_preface: proto=%s", sip, cmd.Protocol)
 
 	h.httpProtocol = cmd.Protocol
+	h.initLimits()
+
+	maxStreams := tourstore.MAX_TOURS
+	if h.maxConcurrentStreams > 0 {
+		maxStreams = h.maxConcurrentStreams
+	}
 
 	set := NewCmdSettings(CTL_STREAM_ID, nil)
 	set.streamId = 0
-	set.items = append(set.items, NewCmdSettingItem(MAX_CONCURRENT_STREAMS, tourstore.MAX_TOURS))
+	set.items = append(set.items, NewCmdSettingItem(MAX_CONCURRENT_STREAMS, maxStreams))
 	set.items = append(set.items, NewCmdSettingItem(INITIAL_WINDOW_SIZE, h.windowSize))
+	if h.maxHeaderListSize > 0 {
+		set.items = append(set.items, NewCmdSettingItem(MAX_HEADER_LIST_SIZE, h.maxHeaderListSize))
+	}
+	if h.prioritization {
+		set.items = append(set.items, NewCmdSettingItem(NO_RFC7540_PRIORITIES, 1))
+	}
 	ioerr := h.protocolHandler.Post(set, nil)
 	if ioerr != nil {
 		return -1, ioerr
@@ -175,8 +276,65 @@ func (h *H2InboundHandler) HandlePreface(cmd *CmdPreface) (common.NextSocketActi
 
 func (h *H2InboundHandler) HandleHeaders(cmd *CmdHeaders) (common.NextSocketAction, exception2.IOException) {
 	baylog.Debug("%s handle_headers: stm=%d dep=%d weight=%d", h.Ship(), cmd.streamId, cmd.StreamDependency, cmd.Weight)
+
+	if !cmd.flags.IsEndHeaders() {
+		// Wait for CONTINUATION frames
+		h.pendingHeaders = cmd
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+	return h.processHeaders(cmd)
+}
+
+func (h *H2InboundHandler) HandleContinuation(cmd *CmdContinuation) (common.NextSocketAction, exception2.IOException) {
+	baylog.Debug("%s handle_continuation: stm=%d len=%d", h.Ship(), cmd.streamId, len(cmd.Fragment))
+
+	hcmd := h.pendingHeaders
+	if hcmd == nil || hcmd.streamId != cmd.streamId {
+		return -1, exception.NewProtocolException("Unexpected CONTINUATION frame: stm=%d", cmd.streamId)
+	}
+
+	hcmd.AppendFragment(cmd.Fragment)
+	if len(hcmd.Fragment) > MAX_HEADER_BLOCK_LEN {
+		return h.enhanceYourCalm("CONTINUATION flood")
+	}
+
+	if !cmd.flags.IsEndHeaders() {
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+
+	h.pendingHeaders = nil
+	hcmd.flags.SetEndHeaders(true)
+	return h.processHeaders(hcmd)
+}
+
+func (h *H2InboundHandler) processHeaders(cmd *CmdHeaders) (common.NextSocketAction, exception2.IOException) {
 	var ioerr exception2.IOException = nil
 
+	perr := cmd.DecodeHeaderBlocks()
+	if perr != nil {
+		return h.compressionError(perr)
+	}
+
+	if !h.isActiveStream(cmd.streamId) {
+		// New stream
+		if cmd.streamId <= h.lastStreamId || h.goingAway {
+			baylog.Debug("%s headers on closed stream (ignore): stm=%d", h.Ship(), cmd.streamId)
+			return h.skipHeaders(cmd)
+		}
+
+		h.streamCount++
+		if h.maxStreams > 0 && h.streamCount > h.maxStreams {
+			return h.enhanceYourCalm("too many streams")
+		}
+		h.lastStreamId = cmd.streamId
+
+		if h.maxConcurrentStreams > 0 && h.activeStreamCount() >= h.maxConcurrentStreams {
+			baylog.Debug("%s refuse stream: stm=%d active=%d", h.Ship(), cmd.streamId, h.activeStreamCount())
+			return h.refuseStream(cmd)
+		}
+		h.addActiveStream(cmd.streamId)
+	}
+
 catch:
 	for { // try catch
 		t := h.getTour(cmd.streamId)
@@ -192,16 +350,21 @@ catch:
 		}
 
 		tur := t.(tour.Tour)
+		headerListSize := 0
+		headerTooLarge := false
 		for _, blk := range cmd.HeaderBlocks {
 			if blk.op == HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE {
 				baylog.Trace("%s header block update table size: %d", t, blk.size)
-				h.reqHeaderTbl.SetSize(blk.size)
+				perr = h.reqHeaderTbl.SetSize(blk.size)
+				if perr != nil {
+					return h.compressionError(perr)
+				}
 				continue
 			}
 
-			ioerr = h.analyzer.AnalyzeHeaderBlock(blk, h.reqHeaderTbl)
-			if ioerr != nil {
-				break catch
+			perr = h.analyzer.AnalyzeHeaderBlock(blk, h.reqHeaderTbl)
+			if perr != nil {
+				return h.compressionError(perr)
 			}
 			if bayserver.Harbor().TraceHeader() {
 				baylog.Info("%s req header: %s=%s :%s", t, h.analyzer.Name, h.analyzer.Value, blk)
@@ -209,6 +372,15 @@ catch:
 
 			if h.analyzer.Name == "" {
 				continue
+			}
+
+			// Keep decoding to maintain HPACK context, but stop accepting headers
+			headerListSize += len(h.analyzer.Name) + len(h.analyzer.Value) + 32
+			if h.maxHeaderListSize > 0 && headerListSize > h.maxHeaderListSize {
+				headerTooLarge = true
+			}
+			if headerTooLarge {
+				continue
 
 			} else if h.analyzer.Name[0] != ':' {
 				tur.Req().Headers().Add(h.analyzer.Name, h.analyzer.Value)
@@ -227,11 +399,25 @@ catch:
 			}
 		}
 
+		if headerTooLarge {
+			baylog.Debug("%s header list too large: size=%d limit=%d", tur, headerListSize, h.maxHeaderListSize)
+			ioerr = tur.Res().SendError(impl.TOUR_ID_NOCHECK, httpstatus.REQUEST_HEADER_FIELDS_TOO_LARGE, "Header list too large", nil)
+			if ioerr != nil {
+				break
+			}
+			return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+		}
+
 		if cmd.flags.IsEndHeaders() {
 			tur.Req().SetProtocol("HTTP/2.0")
 			baylog.Debug("%s H2 read header method=%s protocol=%s uri=%s contlen=%d",
 				h.Ship(), tur.Req().Method(), tur.Req().Protocol(), tur.Req().Uri(), tur.Req().Headers().ContentLength())
 
+			// PRIORITY_UPDATE received before HEADERS takes precedence (RFC 9218 7.1)
+			if h.prioritization && tur.Req().Headers().Contains(headers.PRIORITY) && !h.scheduler.HasPriority(cmd.streamId) {
+				h.scheduler.SetPriority(cmd.streamId, ParsePriority(tur.Req().Headers().Get(headers.PRIORITY), NewH2Priority()))
+			}
+
 			reqContLen := tur.Req().Headers().ContentLength()
 
 			if reqContLen > 0 {
@@ -283,6 +469,25 @@ catch:
 
 func (h *H2InboundHandler) HandleData(cmd *CmdData) (common.NextSocketAction, exception2.IOException) {
 	baylog.Debug("%s handle_data: stm=%d len=%d", h.Ship(), cmd.streamId, cmd.length)
+
+	if cmd.length == 0 && !cmd.flags.IsEndStream() && !h.emptyDataLimiter.Hit() {
+		return h.enhanceYourCalm("empty DATA flood")
+	}
+
+	if !h.isActiveStream(cmd.streamId) {
+		// Stream already closed or reset: only give back the connection window
+		baylog.Debug("%s data on closed stream (ignore): stm=%d", h.Ship(), cmd.streamId)
+		if cmd.length > 0 {
+			upd := NewCmdWindowUpdate(0, nil)
+			upd.WindowSizeIncrement = cmd.length
+			ioerr := h.protocolHandler.Post(upd, nil)
+			if ioerr != nil {
+				return -1, ioerr
+			}
+		}
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+
 	tur := h.getTour(cmd.streamId).(*impl.TourImpl)
 	if tur == nil {
 		return -1, exception2.NewIOException("Invalid stream id: %d", cmd.streamId)
@@ -326,7 +531,7 @@ func (h *H2InboundHandler) HandleData(cmd *CmdData) (common.NextSocketAction, ex
 							}
 
 							if resume {
-								tur.Ship().(*ship.ShipImpl).ResumeRead(tur.ShipId())
+								tur.Ship().(ship2.Ship).ResumeRead(tur.ShipId())
 							}
 							return
 						}
@@ -381,11 +586,17 @@ func (h *H2InboundHandler) HandlePriority(cmd *CmdPriority) (common.NextSocketAc
 	if cmd.streamId == 0 {
 		return -1, exception.NewProtocolException("Invalid streamId")
 	}
+	if !h.priorityLimiter.Hit() {
+		return h.enhanceYourCalm("PRIORITY flood")
+	}
 	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 }
 
 func (h *H2InboundHandler) HandleSettings(cmd *CmdSettings) (common.NextSocketAction, exception2.IOException) {
 	baylog.Debug("%s handleSettings: stmid=%d", h.Ship(), cmd.streamId)
+	if !h.settingsLimiter.Hit() {
+		return h.enhanceYourCalm("SETTINGS flood")
+	}
 	if cmd.flags.IsAck() {
 		return common.NEXT_SOCKET_ACTION_CONTINUE, nil // ignore ACK
 
@@ -396,8 +607,12 @@ func (h *H2InboundHandler) HandleSettings(cmd *CmdSettings) (common.NextSocketAc
 		switch item.id {
 		case HEADER_TABLE_SIZE:
 			h.settings.HeaderTableSize = item.value
+			h.updateResTableSize(item.value)
 
 		case ENABLE_PUSH:
+			if item.value != 0 && item.value != 1 {
+				return -1, exception.NewProtocolException("Invalid ENABLE_PUSH value: %d", item.value)
+			}
 			h.settings.EnablePush = item.value != 0
 
 		case MAX_CONCURRENT_STREAMS:
@@ -407,11 +622,19 @@ func (h *H2InboundHandler) HandleSettings(cmd *CmdSettings) (common.NextSocketAc
 			h.settings.InitialWindowSize = item.value
 
 		case MAX_FRAME_SIZE:
+			if item.value < DEFAULT_MAX_FRAME_SIZE || item.value > MAX_PAYLOAD_MAXLEN {
+				return -1, exception.NewProtocolException("Invalid MAX_FRAME_SIZE value: %d", item.value)
+			}
 			h.settings.MaxFrameSize = item.value
 
 		case MAX_HEADER_LIST_SIZE:
 			h.settings.MaxHeaderListSize = item.value
 
+		case NO_RFC7540_PRIORITIES:
+			if item.value != 0 && item.value != 1 {
+				return -1, exception.NewProtocolException("Invalid NO_RFC7540_PRIORITIES value: %d", item.value)
+			}
+
 		default:
 			baylog.Debug("Invalid settings id (Ignore): %d", item.id)
 		}
@@ -445,6 +668,13 @@ func (h *H2InboundHandler) HandlePing(cmd *CmdPing) (common.NextSocketAction, ex
 	sip := h.Ship()
 	baylog.Debug("%s handle_ping: stm=%d", sip, cmd.streamId)
 
+	if cmd.flags.IsAck() {
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil // ignore ACK
+	}
+	if !h.pingLimiter.Hit() {
+		return h.enhanceYourCalm("PING flood")
+	}
+
 	res := NewCmdPing(cmd.streamId, NewH2Flags(FLAGS_ACK), cmd.opaqueData)
 	ioerr := h.protocolHandler.Post(res, nil)
 	if ioerr != nil {
@@ -456,6 +686,50 @@ func (h *H2InboundHandler) HandlePing(cmd *CmdPing) (common.NextSocketAction, ex
 func (h *H2InboundHandler) HandleRstStream(cmd *CmdRstStream) (common.NextSocketAction, exception2.IOException) {
 	baylog.Debug("%s received RstStream: stmid=%d code=%d desc=%s",
 		h.Ship(), cmd.streamId, cmd.ErrorCode, h2_error_code.Msg.Get(strconv.Itoa(cmd.ErrorCode)))
+
+	if !h.rstLimiter.Hit() {
+		return h.enhanceYourCalm("RST_STREAM flood")
+	}
+
+	h.scheduler.Remove(cmd.streamId)
+	if h.removeActiveStream(cmd.streamId) {
+		// Don't rent new tour for the stream which is already closed
+		tur := h.Ship().GetTour(cmd.streamId, false, false)
+		if tur == nil {
+			baylog.Debug("%s RstStream on closed stream (ignore): stm=%d", h.Ship(), cmd.streamId)
+
+		} else if tur.IsValid() && tur.Req().Abort() {
+			h.Ship().ReturnTour(tur)
+		}
+	}
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+func (h *H2InboundHandler) HandlePushPromise(cmd *CmdPushPromise) (common.NextSocketAction, exception2.IOException) {
+	// Clients must not send PUSH_PROMISE (RFC 9113 8.4)
+	return -1, exception.NewProtocolException("PUSH_PROMISE received from client: stm=%d", cmd.streamId)
+}
+
+func (h *H2InboundHandler) HandlePriorityUpdate(cmd *CmdPriorityUpdate) (common.NextSocketAction, exception2.IOException) {
+	if cmd.streamId != 0 {
+		return -1, exception.NewProtocolException("Invalid streamId of PRIORITY_UPDATE: %d", cmd.streamId)
+	}
+	if cmd.PrioritizedStreamId == 0 {
+		return -1, exception.NewProtocolException("Invalid prioritized streamId of PRIORITY_UPDATE")
+	}
+	if !h.priorityLimiter.Hit() {
+		return h.enhanceYourCalm("PRIORITY_UPDATE flood")
+	}
+
+	stmId := cmd.PrioritizedStreamId
+	if !h.prioritization || (isClientStream(stmId) && stmId <= h.lastStreamId && !h.isActiveStream(stmId)) {
+		// Stream is already closed
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+
+	pri := ParsePriority(cmd.PriorityFieldValue, NewH2Priority())
+	baylog.Debug("%s priority update: stm=%d %s", h.Ship(), stmId, pri)
+	h.scheduler.SetPriority(stmId, pri)
 	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
 }
 
@@ -509,3 +783,435 @@ func (h *H2InboundHandler) startTour(tur tour.Tour) exception.HttpException {
 func (h *H2InboundHandler) endReqContent(checkTourId int, tur tour.Tour) (exception2.IOException, exception.HttpException) {
 	return tur.Req().EndReqContent(checkTourId)
 }
+
+func (h *H2InboundHandler) initLimits() {
+	h.maxConcurrentStreams = http.DEFAULT_H2_MAX_CONCURRENT_STREAMS
+	h.maxStreams = http.DEFAULT_H2_MAX_STREAMS
+	h.maxHeaderListSize = http.DEFAULT_H2_MAX_HEADER_LIST_SIZE
+	rstRate := http.DEFAULT_H2_MAX_RST_STREAM_RATE
+	pingRate := http.DEFAULT_H2_MAX_PING_RATE
+	settingsRate := http.DEFAULT_H2_MAX_SETTINGS_RATE
+	priorityRate := http.DEFAULT_H2_MAX_PRIORITY_RATE
+	emptyDataRate := http.DEFAULT_H2_MAX_EMPTY_DATA_RATE
+	h.prioritization = http.DEFAULT_H2_PRIORITIZATION
+
+	if port, ok := h.Ship().PortDocker().(http.HtpPortDocker); ok {
+		h.maxConcurrentStreams = port.H2MaxConcurrentStreams()
+		h.maxStreams = port.H2MaxStreams()
+		h.maxHeaderListSize = port.H2MaxHeaderListSize()
+		rstRate = port.H2MaxRstStreamRate()
+		pingRate = port.H2MaxPingRate()
+		settingsRate = port.H2MaxSettingsRate()
+		priorityRate = port.H2MaxPriorityRate()
+		emptyDataRate = port.H2MaxEmptyDataRate()
+		h.serverPush = port.H2ServerPush()
+		h.prioritization = port.H2Prioritization()
+	}
+	h.scheduler.Init(!h.prioritization)
+
+	h.rstLimiter.Init(rstRate)
+	h.pingLimiter.Init(pingRate)
+	h.settingsLimiter.Init(settingsRate)
+	h.priorityLimiter.Init(priorityRate)
+	h.emptyDataLimiter.Init(emptyDataRate)
+}
+
+func (h *H2InboundHandler) isActiveStream(streamId int) bool {
+	h.streamLock.Lock()
+	defer h.streamLock.Unlock()
+	return h.activeStreams[streamId]
+}
+
+func (h *H2InboundHandler) activeStreamCount() int {
+	h.streamLock.Lock()
+	defer h.streamLock.Unlock()
+	return len(h.activeStreams)
+}
+
+func (h *H2InboundHandler) addActiveStream(streamId int) {
+	h.streamLock.Lock()
+	defer h.streamLock.Unlock()
+	h.activeStreams[streamId] = true
+}
+
+func (h *H2InboundHandler) removeActiveStream(streamId int) bool {
+	h.streamLock.Lock()
+	defer h.streamLock.Unlock()
+	if !h.activeStreams[streamId] {
+		return false
+	}
+	delete(h.activeStreams, streamId)
+	return true
+}
+
+// Decodes header blocks only to keep the HPACK context consistent
+func (h *H2InboundHandler) skipHeaders(cmd *CmdHeaders) (common.NextSocketAction, exception2.IOException) {
+	for _, blk := range cmd.HeaderBlocks {
+		perr := h.analyzer.AnalyzeHeaderBlock(blk, h.reqHeaderTbl)
+		if perr != nil {
+			return h.compressionError(perr)
+		}
+	}
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+func (h *H2InboundHandler) refuseStream(cmd *CmdHeaders) (common.NextSocketAction, exception2.IOException) {
+	act, ioerr := h.skipHeaders(cmd)
+	if ioerr != nil {
+		return act, ioerr
+	}
+
+	rst := NewCmdRstStream(cmd.streamId, nil)
+	rst.ErrorCode = h2_error_code.REFUSED_STREAM
+	ioerr = h.protocolHandler.Post(rst, nil)
+	if ioerr != nil {
+		return -1, ioerr
+	}
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+func (h *H2InboundHandler) updateResTableSize(tableSize int) {
+	h.resLock.Lock()
+	defer h.resLock.Unlock()
+
+	h.resHeaderTbl.SetLimit(tableSize)
+
+	size := tableSize
+	if size > DEFAULT_HEADER_TABLE_SIZE {
+		size = DEFAULT_HEADER_TABLE_SIZE
+	}
+	if size != h.resHeaderTbl.MaxSize() {
+		// Notified at the beginning of next header block
+		h.resTableSizeUpdate = size
+	}
+}
+
+func (h *H2InboundHandler) postData(cmd *CmdData, lis common.DataConsumeListener) exception2.IOException {
+	return h.protocolHandler.Post(cmd, lis)
+}
+
+func (h *H2InboundHandler) compressionError(perr exception.ProtocolException) (common.NextSocketAction, exception2.IOException) {
+	baylog.DebugE(perr, "%s HPACK decoding error", h.Ship())
+	return h.goAway(h2_error_code.COMPRESSION_ERROR, perr.Error())
+}
+
+func (h *H2InboundHandler) enhanceYourCalm(reason string) (common.NextSocketAction, exception2.IOException) {
+	if h.goingAway {
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+	baylog.Warn("%s %s", h.Ship(), baymessage.Get(symbol.HTP_H2_FLOOD_DETECTED, reason))
+	return h.goAway(h2_error_code.ENHANCE_YOUR_CALM, reason)
+}
+
+func (h *H2InboundHandler) goAway(errorCode int, reason string) (common.NextSocketAction, exception2.IOException) {
+	if h.goingAway {
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+	h.goingAway = true
+
+	cmd := NewCmdGoAway(CTL_STREAM_ID, nil)
+	cmd.LastStreamId = h.lastStreamId
+	cmd.ErrorCode = errorCode
+	cmd.DebugData = []byte(reason)
+	ioerr := h.protocolHandler.Post(cmd, nil)
+	if ioerr != nil {
+		return -1, ioerr
+	}
+	h.protocolHandler.Ship().PostClose(ship2.SHIP_ID_NOCHECK)
+	return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+}
+
+// Sends response headers. Caller must hold resLock
+func (h *H2InboundHandler) sendHeaderBlock(tur tour.Tour, status int, hdrs *headers.Headers) exception2.IOException {
+	streamId := tur.Req().Key()
+	bld := NewHeaderBlockBuilder()
+	blks := []*HeaderBlock{}
+
+	if h.resTableSizeUpdate >= 0 {
+		blks = append(blks, bld.BuildTableSizeUpdate(h.resTableSizeUpdate, h.resHeaderTbl))
+		h.resTableSizeUpdate = -1
+	}
+
+	blk, perr := bld.BuildHeaderBlock(":status", strconv.Itoa(status), h.resHeaderTbl)
+	if perr != nil {
+		return perr
+	}
+	blks = append(blks, blk)
+
+	// headers
+	if bayserver.Harbor().TraceHeader() {
+		baylog.Info("%s H2 res status: %d", tur, status)
+	}
+	for _, name := range hdrs.HeaderNames() {
+		if strings.ToLower(name) == "connection" {
+			baylog.Trace("%s Connection header is discarded", tur)
+
+		} else {
+			for _, value := range hdrs.HeaderValues(name) {
+				if bayserver.Harbor().TraceHeader() {
+					baylog.Info("%s H2 res header: %s=%s", tur, name, value)
+				}
+				blk, perr = bld.BuildHeaderBlock(name, value, h.resHeaderTbl)
+				if perr != nil {
+					return perr
+				}
+				blks = append(blks, blk)
+			}
+		}
+	}
+
+	cmd := NewCmdHeaders(streamId, nil)
+	cmd.Excluded = false
+	// cmd.streamDependency = streamId;
+	cmd.flags.SetPadded(false)
+
+	fragment := EncodeHeaderBlocks(blks)
+	return h.postHeaderBlock(streamId, fragment, func(frag []byte, endHeaders bool) exception2.IOException {
+		cmd.Fragment = frag
+		cmd.flags.SetEndHeaders(endHeaders)
+		return h.protocolHandler.Post(cmd, nil)
+	})
+}
+
+// Sends header block fragment. It is split into CONTINUATION frames if it exceeds frame size
+func (h *H2InboundHandler) postHeaderBlock(
+	streamId int,
+	fragment []byte,
+	postFirst func(frag []byte, endHeaders bool) exception2.IOException) exception2.IOException {
+
+	maxLen := h.settings.MaxFrameSize
+	if len(fragment) <= maxLen {
+		return postFirst(fragment, true)
+	}
+
+	ioerr := postFirst(fragment[:maxLen], false)
+	if ioerr != nil {
+		return ioerr
+	}
+	for pos := maxLen; pos < len(fragment); pos += maxLen {
+		end := pos + maxLen
+		if end > len(fragment) {
+			end = len(fragment)
+		}
+		cont := NewCmdContinuation(streamId, nil)
+		cont.Fragment = fragment[pos:end]
+		cont.flags.SetEndHeaders(end == len(fragment))
+		ioerr = h.protocolHandler.Post(cont, nil)
+		if ioerr != nil {
+			return ioerr
+		}
+	}
+	return nil
+}
+
+// Sends PUSH_PROMISE frames for preload links and returns tours for promised streams.
+// Caller must hold resLock
+func (h *H2InboundHandler) promiseResources(parent tour.Tour, links []string) ([]tour.Tour, exception2.IOException) {
+	if !h.serverPush || !h.settings.EnablePush || h.goingAway || len(links) == 0 {
+		return nil, nil
+	}
+
+	parentId := parent.Req().Key()
+	if !isClientStream(parentId) {
+		return nil, nil
+	}
+
+	var pushes []tour.Tour = nil
+	for _, path := range preloadPaths(links) {
+		if h.pushedPaths[path] || !h.isPushable(parent, path) {
+			continue
+		}
+
+		if h.settings.MaxConcurrentStreams >= 0 && h.pushStreamCount() >= h.settings.MaxConcurrentStreams {
+			baylog.Debug("%s push stream limit reached: %d", h.Ship(), h.settings.MaxConcurrentStreams)
+			break
+		}
+
+		promisedId := h.nextPushStreamId
+		tur := h.Ship().GetTour(promisedId, false, true)
+		if tur == nil {
+			baylog.Debug("%s no tour for push: %s", h.Ship(), path)
+			break
+		}
+		h.nextPushStreamId += 2
+		h.pushedPaths[path] = true
+
+		scheme := "http"
+		if h.Ship().PortDocker().Secure() {
+			scheme = "https"
+		}
+		authority := parent.Req().Headers().Get(headers.HOST)
+
+		bld := NewHeaderBlockBuilder()
+		blks := []*HeaderBlock{}
+		if h.resTableSizeUpdate >= 0 {
+			blks = append(blks, bld.BuildTableSizeUpdate(h.resTableSizeUpdate, h.resHeaderTbl))
+			h.resTableSizeUpdate = -1
+		}
+		for _, nv := range [][]string{
+			{PSEUDO_HEADER_METHOD, "GET"},
+			{PSEUDO_HEADER_SCHEME, scheme},
+			{PSEUDO_HEADER_AUTHORITY, authority},
+			{PSEUDO_HEADER_PATH, path}} {
+			blk, perr := bld.BuildHeaderBlock(nv[0], nv[1], h.resHeaderTbl)
+			if perr != nil {
+				return pushes, perr
+			}
+			blks = append(blks, blk)
+		}
+
+		baylog.Debug("%s push promise: stm=%d promised=%d path=%s", h.Ship(), parentId, promisedId, path)
+		cmd := NewCmdPushPromise(parentId, nil)
+		cmd.PromisedStreamId = promisedId
+		ioerr := h.postHeaderBlock(parentId, EncodeHeaderBlocks(blks), func(frag []byte, endHeaders bool) exception2.IOException {
+			cmd.Fragment = frag
+			cmd.flags.SetEndHeaders(endHeaders)
+			return h.protocolHandler.Post(cmd, nil)
+		})
+		if ioerr != nil {
+			return pushes, ioerr
+		}
+
+		req := tur.Req()
+		req.SetMethod("GET")
+		req.SetUri(path)
+		req.Headers().Add(headers.HOST, authority)
+		for _, name := range []string{headers.USER_AGENT, headers.ACCEPT_ENCODING, headers.ACCEPT_LANGUAGE} {
+			for _, value := range parent.Req().Headers().HeaderValues(name) {
+				req.Headers().Add(name, value)
+			}
+		}
+		req.SetProtocol("HTTP/2.0")
+		h.addActiveStream(promisedId)
+		pushes = append(pushes, tur)
+	}
+
+	return pushes, nil
+}
+
+// Starts tours of promised streams. Must be called without resLock
+func (h *H2InboundHandler) startPushTours(pushes []tour.Tour) {
+	for _, tur := range pushes {
+		var ioerr exception2.IOException = nil
+		hterr := h.startTour(tur)
+		if hterr == nil {
+			ioerr, hterr = h.endReqContent(tur.TourId(), tur)
+		}
+		if hterr != nil {
+			baylog.Debug("%s Http error occurred on push: %s", h, hterr)
+			tur.Req().Abort()
+			ioerr = tur.Res().SendHttpException(impl.TOUR_ID_NOCHECK, hterr)
+		}
+		if ioerr != nil {
+			baylog.ErrorE(ioerr, "%s Cannot start pushed tour", tur)
+		}
+	}
+}
+
+// Checks if the path is served as a static file in the same town as the parent request
+func (h *H2InboundHandler) isPushable(parent tour.Tour, path string) bool {
+	town, ok := parent.Town().(docker.Town)
+	if !ok || town == nil {
+		return false
+	}
+
+	if !strings.HasPrefix(path, town.Name()) || pathutil.Clean(path) != path {
+		return false
+	}
+	relPath := path[len(town.Name()):]
+	if relPath == "" || !sysutil.IsFile(filepath.Join(town.Location(), relPath)) {
+		return false
+	}
+
+	fname := pathutil.Base(relPath)
+	for _, clubs := range [][]docker.Club{town.Clubs(), town.City().Clubs()} {
+		for _, club := range clubs {
+			if (club.FileName() == "*" && club.Extension() == "") || club.Matches(fname) {
+				_, isFile := club.(*file.FileDocker)
+				return isFile
+			}
+		}
+	}
+
+	// Default club serves files
+	return true
+}
+
+func (h *H2InboundHandler) pushStreamCount() int {
+	h.streamLock.Lock()
+	defer h.streamLock.Unlock()
+	n := 0
+	for id := range h.activeStreams {
+		if !isClientStream(id) {
+			n++
+		}
+	}
+	return n
+}
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+// Wraps listener of tour so that it is not called after the tour is returned
+// (Scheduler calls listeners of dropped frames on reset)
+func tourListener(tur tour.Tour, lis common.DataConsumeListener) common.DataConsumeListener {
+	if lis == nil {
+		return nil
+	}
+	tourId := tur.TourId()
+	return func() {
+		if !tur.IsInitialized() || tur.TourId() != tourId {
+			baylog.Debug("%s tour is already returned (ignore listener)", tur)
+			return
+		}
+		lis()
+	}
+}
+
+func isClientStream(streamId int) bool {
+	return streamId%2 == 1
+}
+
+// Extracts same-origin paths from "Link: <path>; rel=preload" header values
+func preloadPaths(links []string) []string {
+	paths := []string{}
+	for _, value := range links {
+		for _, link := range strings.Split(value, ",") {
+			params := strings.Split(link, ";")
+			target := strings.TrimSpace(params[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+			if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") ||
+				strings.ContainsAny(target, "?#") {
+				continue
+			}
+
+			preload := false
+			noPush := false
+			for _, param := range params[1:] {
+				nv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				switch strings.ToLower(strings.TrimSpace(nv[0])) {
+				case "rel":
+					if len(nv) == 2 {
+						for _, rel := range strings.Fields(strings.Trim(nv[1], "\" ")) {
+							if strings.ToLower(rel) == "preload" {
+								preload = true
+							}
+						}
+					}
+
+				case "nopush":
+					noPush = true
+				}
+			}
+
+			if preload && !noPush {
+				paths = append(paths, target)
+			}
+		}
+	}
+	return paths
+}
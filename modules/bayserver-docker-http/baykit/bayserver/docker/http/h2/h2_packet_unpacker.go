@@ -109,6 +109,9 @@ func (pu *H2PacketUnpacker) BytesReceived(buf []byte) (common.NextSocketAction,
 						break
 					}
 				}
+				if ioerr != nil {
+					break
+				}
 				pkt := pu.pktStore.Rent(H2_TYPE_PREFACE)
 				pkt.NewDataAccessor().PutBytes(pu.tmpBuf.Bytes(), 0, pu.tmpBuf.Len())
 				var nstat common.NextSocketAction
@@ -204,6 +207,10 @@ func (pu *H2PacketUnpacker) BytesReceived(buf []byte) (common.NextSocketAction,
 			}
 		}
 
+		if ioerr != nil {
+			break
+		}
+
 		if suspend {
 			return common.NEXT_SOCKET_ACTION_SUSPEND, nil
 
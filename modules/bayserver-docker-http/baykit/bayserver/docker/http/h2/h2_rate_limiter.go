@@ -0,0 +1,43 @@
+package h2
+
+import "time"
+
+/**
+ * Counts frames received in the current one-second window
+ * and reports when the count exceeds the limit.
+ */
+
+type H2RateLimiter struct {
+	limit       int
+	windowStart int64
+	count       int
+}
+
+func NewH2RateLimiter() *H2RateLimiter {
+	return &H2RateLimiter{}
+}
+
+func (l *H2RateLimiter) Init(limit int) {
+	l.limit = limit
+	l.Reset()
+}
+
+func (l *H2RateLimiter) Reset() {
+	l.windowStart = 0
+	l.count = 0
+}
+
+// Hit records one frame and returns false if the limit is exceeded (limit 0 means unlimited)
+func (l *H2RateLimiter) Hit() bool {
+	if l.limit <= 0 {
+		return true
+	}
+
+	now := time.Now().Unix()
+	if now != l.windowStart {
+		l.windowStart = now
+		l.count = 0
+	}
+	l.count++
+	return l.count <= l.limit
+}
@@ -0,0 +1,475 @@
+package h2
+
+import (
+	"bayserver-docker-http/baykit/bayserver/docker/http/h2/h2_error_code"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/**
+ * Conformance tests run against a local BayServer instance
+ * (Based on h2spec test cases of RFC 7540 6.10 and RFC 7541)
+ *
+ *   The bayserver command is built and started on a free port with a temporary home directory.
+ *   Set BSERV_H2SPEC_ADDR (host:port) to run the tests against a server which is already running.
+ *   Frames are sent over h2c with prior knowledge. Skipped in short mode.
+ */
+
+const H2SPEC_ADDR_ENV = "BSERV_H2SPEC_ADDR"
+const H2SPEC_TIMEOUT = 5 * time.Second
+
+var localServer struct {
+	once sync.Once
+	addr string
+	home string
+	cmd  *exec.Cmd
+	err  error
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if localServer.cmd != nil {
+		_ = localServer.cmd.Process.Kill()
+		_, _ = localServer.cmd.Process.Wait()
+	}
+	if localServer.home != "" {
+		_ = os.RemoveAll(localServer.home)
+	}
+	os.Exit(code)
+}
+
+/****************************************/
+/* Helpers                              */
+/****************************************/
+
+type h2Frame struct {
+	typ     int
+	flags   int
+	stmId   int
+	payload []byte
+}
+
+type h2Client struct {
+	t      *testing.T
+	conn   net.Conn
+	encTbl *HeaderTable
+	decTbl *HeaderTable
+}
+
+// Returns address of the local instance. The server is started at the first call.
+func serverAddr(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipped in short mode")
+	}
+	if addr := os.Getenv(H2SPEC_ADDR_ENV); addr != "" {
+		return addr
+	}
+
+	localServer.once.Do(func() {
+		localServer.addr, localServer.err = startLocalServer()
+	})
+	if localServer.err != nil {
+		t.Fatalf("cannot start local server: %v", localServer.err)
+	}
+	return localServer.addr
+}
+
+func startLocalServer() (string, error) {
+	home, err := os.MkdirTemp("", "bserv-h2spec")
+	if err != nil {
+		return "", err
+	}
+	localServer.home = home
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	port := lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+
+	plan := "[harbor]\n" +
+		"    grandAgents 1\n" +
+		"[port " + fmt.Sprint(port) + "]\n" +
+		"    docker http\n" +
+		"[city *]\n" +
+		"    [town /]\n" +
+		"        location www\n" +
+		"        welcome index.html\n" +
+		"        [club *]\n" +
+		"            docker file\n"
+	for _, dir := range []string{"bin", "plan", "www", "log"} {
+		err = os.MkdirAll(filepath.Join(home, dir), 0755)
+		if err != nil {
+			return "", err
+		}
+	}
+	err = os.WriteFile(filepath.Join(home, "plan", "bayserver.plan"), []byte(plan), 0644)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(home, "www", "index.html"), []byte("Hello\n"), 0644)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Main package: modules/bayserver/main
+	bin := filepath.Join(home, "bin", "bayserver")
+	build := exec.Command("go", "build", "-o", bin, ".")
+	build.Dir = filepath.Join("..", "..", "..", "..", "..", "..", "bayserver", "main")
+	out, err := build.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("build failed: %v: %s", err, out)
+	}
+
+	logFile, err := os.Create(filepath.Join(home, "log", "out.log"))
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(bin, "-home="+home)
+	cmd.Dir = home
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	err = cmd.Start()
+	if err != nil {
+		return "", err
+	}
+	localServer.cmd = cmd
+	defer logFile.Close()
+
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	limit := time.Now().Add(20 * time.Second)
+	for time.Now().Before(limit) {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return addr, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	_ = cmd.Process.Kill()
+	return "", fmt.Errorf("server did not start listening on %s (see %s)", addr, logFile.Name())
+}
+
+// Connects to the server and exchanges the connection preface and SETTINGS
+func connect(t *testing.T, settings ...[2]int) *h2Client {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", serverAddr(t), H2SPEC_TIMEOUT)
+	if err != nil {
+		t.Fatalf("cannot connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &h2Client{t: t, conn: conn, encTbl: CreateDynamicTable(), decTbl: CreateDynamicTable()}
+	c.write(CONNECTION_PREFACE)
+
+	var payload []byte
+	for _, s := range settings {
+		payload = binary.BigEndian.AppendUint16(payload, uint16(s[0]))
+		payload = binary.BigEndian.AppendUint32(payload, uint32(s[1]))
+		if s[0] == HEADER_TABLE_SIZE {
+			c.decTbl.SetLimit(s[1])
+		}
+	}
+	c.writeFrame(H2_TYPE_SETTINGS, FLAGS_NONE, 0, payload)
+
+	for {
+		f := c.readFrame()
+		if f == nil {
+			t.Fatal("connection closed during handshake")
+		}
+		if f.typ == H2_TYPE_SETTINGS && f.flags&FLAGS_ACK == 0 {
+			c.writeFrame(H2_TYPE_SETTINGS, FLAGS_ACK, 0, nil)
+			return c
+		}
+	}
+}
+
+func (c *h2Client) write(b []byte) {
+	c.t.Helper()
+	_, err := c.conn.Write(b)
+	if err != nil {
+		c.t.Fatalf("write failed: %v", err)
+	}
+}
+
+func (c *h2Client) writeFrame(typ int, flags int, stmId int, payload []byte) {
+	c.t.Helper()
+	hdr := make([]byte, 9)
+	hdr[0] = byte(len(payload) >> 16)
+	hdr[1] = byte(len(payload) >> 8)
+	hdr[2] = byte(len(payload))
+	hdr[3] = byte(typ)
+	hdr[4] = byte(flags)
+	binary.BigEndian.PutUint32(hdr[5:], uint32(stmId))
+	c.write(append(hdr, payload...))
+}
+
+// Returns nil when the connection is closed
+func (c *h2Client) readFrame() *h2Frame {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(H2SPEC_TIMEOUT))
+	hdr := make([]byte, 9)
+	_, err := io.ReadFull(c.conn, hdr)
+	if err != nil {
+		if isTimeout(err) {
+			c.t.Fatal("timed out waiting for frame")
+		}
+		return nil
+	}
+	f := &h2Frame{
+		typ:     int(hdr[3]),
+		flags:   int(hdr[4]),
+		stmId:   int(binary.BigEndian.Uint32(hdr[5:]) & 0x7fffffff),
+		payload: make([]byte, int(hdr[0])<<16|int(hdr[1])<<8|int(hdr[2])),
+	}
+	_, err = io.ReadFull(c.conn, f.payload)
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+// Request header block of GET method
+func (c *h2Client) requestFragment(path string, extra ...[2]string) []byte {
+	c.t.Helper()
+	bld := NewHeaderBlockBuilder()
+	var blks []*HeaderBlock
+	for _, nv := range append([][2]string{
+		{PSEUDO_HEADER_METHOD, "GET"},
+		{PSEUDO_HEADER_SCHEME, "http"},
+		{PSEUDO_HEADER_PATH, path},
+		{PSEUDO_HEADER_AUTHORITY, "localhost"}}, extra...) {
+		blk, perr := bld.BuildHeaderBlock(nv[0], nv[1], c.encTbl)
+		if perr != nil {
+			c.t.Fatalf("cannot build header block: %v", perr)
+		}
+		blks = append(blks, blk)
+	}
+	return EncodeHeaderBlocks(blks)
+}
+
+// Waits for response header block of the stream and returns status and decoded header blocks
+func (c *h2Client) expectResponse(stmId int) (string, []*HeaderBlock) {
+	c.t.Helper()
+	var fragment []byte
+	for {
+		f := c.readFrame()
+		if f == nil {
+			c.t.Fatal("connection closed before response")
+		}
+		if f.typ == H2_TYPE_GOAWAY {
+			c.t.Fatalf("unexpected GOAWAY: error=%d", binary.BigEndian.Uint32(f.payload[4:]))
+		}
+		if f.stmId != stmId || (f.typ != H2_TYPE_HEADERS && f.typ != H2_TYPE_CONTINUATION) {
+			continue
+		}
+		fragment = append(fragment, f.payload...)
+		if f.flags&FLAGS_END_HEADERS != 0 {
+			break
+		}
+	}
+
+	blks, err := decodeFragment(fragment)
+	if err != nil {
+		c.t.Fatalf("cannot decode response headers: %v", err)
+	}
+	anl := NewHeaderBlockAnalyzer()
+	status := ""
+	for _, blk := range blks {
+		perr := anl.AnalyzeHeaderBlock(blk, c.decTbl)
+		if perr != nil {
+			c.t.Fatalf("invalid response header block: %v", perr)
+		}
+		if anl.Status != "" {
+			status = anl.Status
+		}
+	}
+	return status, blks
+}
+
+// Waits for GOAWAY with the error code. Closing connection without GOAWAY is also accepted like h2spec.
+func (c *h2Client) expectGoAway(errorCode int) {
+	c.t.Helper()
+	for {
+		f := c.readFrame()
+		if f == nil {
+			return
+		}
+		if f.typ == H2_TYPE_GOAWAY {
+			code := int(binary.BigEndian.Uint32(f.payload[4:]))
+			if code != errorCode {
+				c.t.Fatalf("unexpected GOAWAY error code: %d (want %d)", code, errorCode)
+			}
+			return
+		}
+	}
+}
+
+func isTimeout(err error) bool {
+	nerr, ok := err.(net.Error)
+	return ok && nerr.Timeout()
+}
+
+// Literal header field without indexing of new name (RFC 7541 6.2.2)
+func literalField(name string, value string) []byte {
+	b := []byte{0x00}
+	b = append(b, hpackInt(len(name), 7, 0x00)...)
+	b = append(b, name...)
+	b = append(b, hpackInt(len(value), 7, 0x00)...)
+	return append(b, value...)
+}
+
+// Integer representation (RFC 7541 5.1)
+func hpackInt(n int, prefix uint, first byte) []byte {
+	max := 1<<prefix - 1
+	if n < max {
+		return []byte{first | byte(n)}
+	}
+	b := []byte{first | byte(max)}
+	n -= max
+	for n >= 128 {
+		b = append(b, byte(n%128+128))
+		n /= 128
+	}
+	return append(b, byte(n))
+}
+
+/****************************************/
+/* RFC 7540 6.10 CONTINUATION           */
+/****************************************/
+
+func TestServerHeadersWithContinuation(t *testing.T) {
+	c := connect(t)
+	fragment := c.requestFragment("/index.html")
+
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM, 1, fragment[:2])
+	c.writeFrame(H2_TYPE_CONTINUATION, FLAGS_NONE, 1, fragment[2:4])
+	c.writeFrame(H2_TYPE_CONTINUATION, FLAGS_END_HEADERS, 1, fragment[4:])
+
+	if status, _ := c.expectResponse(1); status != "200" {
+		t.Fatalf("unexpected status: %s", status)
+	}
+}
+
+func TestServerHeaderBlockLargerThanFrame(t *testing.T) {
+	c := connect(t)
+	cookie := "id=" + strings.Repeat("x", 3*DEFAULT_MAX_FRAME_SIZE)
+	fragment := append(c.requestFragment("/index.html"), literalField("cookie", cookie)...)
+
+	// Split into frames of the default maximum size
+	flags := FLAGS_END_STREAM
+	typ := H2_TYPE_HEADERS
+	for len(fragment) > DEFAULT_MAX_FRAME_SIZE {
+		c.writeFrame(typ, flags, 1, fragment[:DEFAULT_MAX_FRAME_SIZE])
+		fragment = fragment[DEFAULT_MAX_FRAME_SIZE:]
+		typ = H2_TYPE_CONTINUATION
+		flags = FLAGS_NONE
+	}
+	c.writeFrame(typ, flags|FLAGS_END_HEADERS, 1, fragment)
+
+	if status, _ := c.expectResponse(1); status != "200" {
+		t.Fatalf("unexpected status: %s", status)
+	}
+}
+
+func TestServerHeaderBlockInterruptedByOtherFrame(t *testing.T) {
+	c := connect(t)
+	fragment := c.requestFragment("/index.html")
+
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM, 1, fragment[:2])
+	c.writeFrame(H2_TYPE_PING, FLAGS_NONE, 0, make([]byte, 8))
+	c.expectGoAway(h2_error_code.PROTOCOL_ERROR)
+}
+
+func TestServerContinuationOfOtherStream(t *testing.T) {
+	c := connect(t)
+	fragment := c.requestFragment("/index.html")
+
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM, 1, fragment[:2])
+	c.writeFrame(H2_TYPE_CONTINUATION, FLAGS_END_HEADERS, 3, fragment[2:])
+	c.expectGoAway(h2_error_code.PROTOCOL_ERROR)
+}
+
+func TestServerContinuationAfterEndHeaders(t *testing.T) {
+	c := connect(t)
+	fragment := c.requestFragment("/index.html")
+
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_HEADERS, 1, fragment)
+	c.writeFrame(H2_TYPE_CONTINUATION, FLAGS_END_HEADERS, 1, literalField("x-test", "a"))
+	c.expectGoAway(h2_error_code.PROTOCOL_ERROR)
+}
+
+func TestServerContinuationOnStreamZero(t *testing.T) {
+	c := connect(t)
+	fragment := c.requestFragment("/index.html")
+
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM, 1, fragment[:2])
+	c.writeFrame(H2_TYPE_CONTINUATION, FLAGS_END_HEADERS, 0, fragment[2:])
+	c.expectGoAway(h2_error_code.PROTOCOL_ERROR)
+}
+
+/****************************************/
+/* RFC 7541 HPACK                       */
+/****************************************/
+
+func TestServerInvalidIndex(t *testing.T) {
+	c := connect(t)
+	// Indexed header field of index 70 (no dynamic table entry)
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM|FLAGS_END_HEADERS, 1, hpackInt(70, 7, 0x80))
+	c.expectGoAway(h2_error_code.COMPRESSION_ERROR)
+}
+
+func TestServerTableSizeUpdateOverLimit(t *testing.T) {
+	c := connect(t)
+	fragment := append(hpackInt(DEFAULT_HEADER_TABLE_SIZE+1, 5, 0x20), c.requestFragment("/index.html")...)
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM|FLAGS_END_HEADERS, 1, fragment)
+	c.expectGoAway(h2_error_code.COMPRESSION_ERROR)
+}
+
+func TestServerTableSizeUpdateAfterHeaderField(t *testing.T) {
+	c := connect(t)
+	fragment := append(c.requestFragment("/index.html"), hpackInt(0, 5, 0x20)...)
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM|FLAGS_END_HEADERS, 1, fragment)
+	c.expectGoAway(h2_error_code.COMPRESSION_ERROR)
+}
+
+func TestServerTableSizeUpdate(t *testing.T) {
+	c := connect(t)
+	// Shrink and restore the table used for requests
+	fragment := append(hpackInt(0, 5, 0x20), hpackInt(DEFAULT_HEADER_TABLE_SIZE, 5, 0x20)...)
+	fragment = append(fragment, c.requestFragment("/index.html")...)
+	c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM|FLAGS_END_HEADERS, 1, fragment)
+
+	if status, _ := c.expectResponse(1); status != "200" {
+		t.Fatalf("unexpected status: %s", status)
+	}
+}
+
+func TestServerHonorsHeaderTableSize(t *testing.T) {
+	// Client allows no dynamic table for responses
+	c := connect(t, [2]int{HEADER_TABLE_SIZE, 0})
+
+	for _, stmId := range []int{1, 3} {
+		c.writeFrame(H2_TYPE_HEADERS, FLAGS_END_STREAM|FLAGS_END_HEADERS, stmId, c.requestFragment("/index.html"))
+		status, blks := c.expectResponse(stmId)
+		if status != "200" {
+			t.Fatalf("unexpected status: %s", status)
+		}
+		if stmId == 1 && blks[0].op != HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE {
+			t.Fatal("first response must begin with dynamic table size update")
+		}
+		if c.decTbl.MaxSize() != 0 || c.decTbl.Size() != 0 {
+			t.Fatalf("dynamic table is used: size=%d max=%d", c.decTbl.Size(), c.decTbl.MaxSize())
+		}
+	}
+}
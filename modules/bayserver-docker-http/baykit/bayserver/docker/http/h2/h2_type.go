@@ -11,3 +11,4 @@ const H2_TYPE_PING = 6
 const H2_TYPE_GOAWAY = 7
 const H2_TYPE_WINDOW_UPDATE = 8
 const H2_TYPE_CONTINUATION = 9
+const H2_TYPE_PRIORITY_UPDATE = 0x10
@@ -0,0 +1,346 @@
+package h2
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	"bayserver-core/baykit/bayserver/util/exception"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+ * Write scheduler of DATA frames based on Extensible Prioritization Scheme (RFC 9218)
+ *
+ *   Streams of lower urgency value are served first.
+ *   Non-incremental streams of the same urgency are served one by one in stream ID order,
+ *   and incremental streams of the same urgency share the bandwidth in round robin.
+ */
+
+const PRIORITY_DEFAULT_URGENCY = 3
+const PRIORITY_MAX_URGENCY = 7
+const PRIORITY_DEFAULT_INCREMENTAL = false
+
+// Number of DATA frames passed to transporter at a time
+const SCHEDULER_MAX_IN_FLIGHT = 2
+
+// Upper limit of priorities kept for streams which have not started sending
+const SCHEDULER_MAX_PRIORITIES = 1000
+
+type H2Priority struct {
+	Urgency     int
+	Incremental bool
+}
+
+func NewH2Priority() H2Priority {
+	return H2Priority{Urgency: PRIORITY_DEFAULT_URGENCY, Incremental: PRIORITY_DEFAULT_INCREMENTAL}
+}
+
+func (p H2Priority) String() string {
+	return "u=" + strconv.Itoa(p.Urgency) + ",i=" + strconv.FormatBool(p.Incremental)
+}
+
+type H2DataPoster func(cmd *CmdData, lis common.DataConsumeListener) exception.IOException
+
+type h2WriteEntry struct {
+	cmd *CmdData
+	lis common.DataConsumeListener
+}
+
+type h2StreamQueue struct {
+	streamId int
+	entries  []*h2WriteEntry
+}
+
+type H2WriteScheduler struct {
+	post       H2DataPoster
+	fifo       bool
+	lock       sync.Mutex
+	inFlight   int
+	queues     map[int]*h2StreamQueue
+	priorities map[int]H2Priority
+	lastServed [PRIORITY_MAX_URGENCY + 1]int
+}
+
+func NewH2WriteScheduler(post H2DataPoster) *H2WriteScheduler {
+	s := &H2WriteScheduler{
+		post:       post,
+		queues:     make(map[int]*h2StreamQueue),
+		priorities: make(map[int]H2Priority),
+	}
+	return s
+}
+
+func (s *H2WriteScheduler) String() string {
+	return "H2WriteScheduler"
+}
+
+func (s *H2WriteScheduler) Init(fifo bool) {
+	s.fifo = fifo
+}
+
+/****************************************/
+/* Implements Reusable                  */
+/****************************************/
+
+func (s *H2WriteScheduler) Reset() {
+	s.lock.Lock()
+	dropped := []*h2WriteEntry{}
+	for _, q := range s.queues {
+		dropped = append(dropped, q.entries...)
+	}
+
+	s.fifo = false
+	s.inFlight = 0
+	s.queues = make(map[int]*h2StreamQueue)
+	s.priorities = make(map[int]H2Priority)
+	for i := range s.lastServed {
+		s.lastServed[i] = 0
+	}
+	s.lock.Unlock()
+
+	notifyDropped(dropped)
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+func (s *H2WriteScheduler) SetPriority(streamId int, pri H2Priority) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.priorities[streamId]; !ok && len(s.priorities) >= SCHEDULER_MAX_PRIORITIES {
+		baylog.Debug("%s too many priorities (ignore): stm=%d", s, streamId)
+		return
+	}
+	s.priorities[streamId] = pri
+}
+
+func (s *H2WriteScheduler) HasPriority(streamId int) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.priorities[streamId]
+	return ok
+}
+
+func (s *H2WriteScheduler) Priority(streamId int) H2Priority {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.priority(streamId)
+}
+
+// Remove forgets priority of the stream and drops DATA frames of the stream which are not sent yet
+func (s *H2WriteScheduler) Remove(streamId int) {
+	s.lock.Lock()
+	var dropped []*h2WriteEntry = nil
+	if q := s.queues[streamId]; q != nil {
+		dropped = q.entries
+		delete(s.queues, streamId)
+	}
+	delete(s.priorities, streamId)
+	s.lock.Unlock()
+
+	if len(dropped) > 0 {
+		baylog.Debug("%s drop %d frames of removed stream: stm=%d", s, len(dropped), streamId)
+	}
+	notifyDropped(dropped)
+}
+
+// Post sends DATA frame in order of priority
+func (s *H2WriteScheduler) Post(cmd *CmdData, lis common.DataConsumeListener) exception.IOException {
+	if s.fifo {
+		return s.post(cmd, lis)
+	}
+
+	s.lock.Lock()
+	if s.inFlight < SCHEDULER_MAX_IN_FLIGHT && len(s.queues) == 0 {
+		s.inFlight++
+		if cmd.flags.IsEndStream() {
+			delete(s.priorities, cmd.streamId)
+		}
+		s.lock.Unlock()
+		return s.postEntry(&h2WriteEntry{cmd: cmd, lis: lis})
+	}
+
+	// Copy data because the caller may reuse buffer after return
+	data := make([]byte, cmd.length)
+	copy(data, cmd.data[cmd.start:cmd.start+cmd.length])
+	cmd.data = data
+	cmd.start = 0
+
+	q := s.queues[cmd.streamId]
+	if q == nil {
+		q = &h2StreamQueue{streamId: cmd.streamId}
+		s.queues[cmd.streamId] = q
+	}
+	q.entries = append(q.entries, &h2WriteEntry{cmd: cmd, lis: lis})
+	s.lock.Unlock()
+
+	return s.dispatch()
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (s *H2WriteScheduler) dispatch() exception.IOException {
+	for {
+		s.lock.Lock()
+		if s.inFlight >= SCHEDULER_MAX_IN_FLIGHT {
+			s.lock.Unlock()
+			return nil
+		}
+		ent := s.next()
+		if ent == nil {
+			s.lock.Unlock()
+			return nil
+		}
+		s.inFlight++
+		s.lock.Unlock()
+
+		ioerr := s.postEntry(ent)
+		if ioerr != nil {
+			return ioerr
+		}
+	}
+}
+
+func (s *H2WriteScheduler) postEntry(ent *h2WriteEntry) exception.IOException {
+	ioerr := s.post(ent.cmd, func() {
+		s.lock.Lock()
+		s.inFlight--
+		s.lock.Unlock()
+
+		if ent.lis != nil {
+			ent.lis()
+		}
+
+		ioerr := s.dispatch()
+		if ioerr != nil {
+			baylog.DebugE(ioerr, "%s Cannot send data", s)
+		}
+	})
+
+	if ioerr != nil {
+		s.lock.Lock()
+		s.inFlight--
+		s.lock.Unlock()
+	}
+	return ioerr
+}
+
+// Takes next entry to send. Caller must hold lock
+func (s *H2WriteScheduler) next() *h2WriteEntry {
+	if len(s.queues) == 0 {
+		return nil
+	}
+
+	// Find the most urgent streams
+	urgency := PRIORITY_MAX_URGENCY + 1
+	candidates := []int{}
+	for id := range s.queues {
+		u := s.priority(id).Urgency
+		if u < urgency {
+			urgency = u
+			candidates = candidates[:0]
+		}
+		if u == urgency {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Ints(candidates)
+
+	// Non-incremental streams are served in stream ID order
+	var q *h2StreamQueue = nil
+	for _, id := range candidates {
+		if !s.priority(id).Incremental {
+			q = s.queues[id]
+			break
+		}
+	}
+
+	// Incremental streams are served in round robin
+	if q == nil {
+		last := s.lastServed[urgency]
+		q = s.queues[candidates[0]]
+		for _, id := range candidates {
+			if id > last {
+				q = s.queues[id]
+				break
+			}
+		}
+		s.lastServed[urgency] = q.streamId
+	}
+
+	ent := q.entries[0]
+	q.entries = q.entries[1:]
+	if len(q.entries) == 0 {
+		delete(s.queues, q.streamId)
+		if ent.cmd.flags.IsEndStream() {
+			delete(s.priorities, q.streamId)
+		}
+	}
+	return ent
+}
+
+// Caller must hold lock
+func (s *H2WriteScheduler) priority(streamId int) H2Priority {
+	pri, ok := s.priorities[streamId]
+	if !ok {
+		return NewH2Priority()
+	}
+	return pri
+}
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+// Calls listeners of dropped entries so that the senders do not wait for the data to be consumed
+func notifyDropped(entries []*h2WriteEntry) {
+	for _, ent := range entries {
+		if ent.lis != nil {
+			ent.lis()
+		}
+	}
+}
+
+// ParsePriority parses Priority field value (Structured Field Dictionary) such as "u=1, i"
+func ParsePriority(value string, base H2Priority) H2Priority {
+	pri := base
+	for _, member := range strings.Split(value, ",") {
+		// Ignore parameters
+		member = strings.TrimSpace(strings.SplitN(member, ";", 2)[0])
+		if member == "" {
+			continue
+		}
+
+		kv := strings.SplitN(member, "=", 2)
+		switch strings.TrimSpace(kv[0]) {
+		case "u":
+			if len(kv) == 2 {
+				u, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+				if err == nil && u >= 0 && u <= PRIORITY_MAX_URGENCY {
+					pri.Urgency = u
+				}
+			}
+
+		case "i":
+			if len(kv) == 1 {
+				pri.Incremental = true
+			} else {
+				switch strings.TrimSpace(kv[1]) {
+				case "?1":
+					pri.Incremental = true
+				case "?0":
+					pri.Incremental = false
+				}
+			}
+		}
+	}
+	return pri
+}
@@ -0,0 +1,89 @@
+package h2
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/util/exception"
+	"testing"
+)
+
+type recordingPoster struct {
+	posted []*CmdData
+	lists  []common.DataConsumeListener
+}
+
+func (p *recordingPoster) post(cmd *CmdData, lis common.DataConsumeListener) exception.IOException {
+	p.posted = append(p.posted, cmd)
+	p.lists = append(p.lists, lis)
+	return nil
+}
+
+// Completes sending of the oldest posted frame
+func (p *recordingPoster) consume() {
+	lis := p.lists[0]
+	p.lists = p.lists[1:]
+	lis()
+}
+
+func postData(t *testing.T, s *H2WriteScheduler, stmId int, calls *int) {
+	t.Helper()
+	cmd := NewCmdData(stmId, nil, []byte("data"), 0, 4)
+	ioerr := s.Post(cmd, func() { *calls++ })
+	if ioerr != nil {
+		t.Fatalf("post failed: %v", ioerr)
+	}
+}
+
+func TestRemoveDropsQueuedFrames(t *testing.T) {
+	p := &recordingPoster{}
+	s := NewH2WriteScheduler(p.post)
+
+	calls1, calls3 := 0, 0
+	for i := 0; i < 4; i++ {
+		postData(t, s, 1, &calls1)
+		postData(t, s, 3, &calls3)
+	}
+	if len(p.posted) != SCHEDULER_MAX_IN_FLIGHT {
+		t.Fatalf("unexpected in-flight frames: %d", len(p.posted))
+	}
+
+	inFlight1 := 0
+	for _, cmd := range p.posted {
+		if cmd.streamId == 1 {
+			inFlight1++
+		}
+	}
+
+	s.Remove(1)
+	if calls1 != 4-inFlight1 {
+		t.Fatalf("listeners of dropped frames are not called: %d", calls1)
+	}
+
+	// Frames of removed stream must not be sent after removal
+	for len(p.lists) > 0 {
+		n := len(p.posted)
+		p.consume()
+		for _, cmd := range p.posted[n:] {
+			if cmd.streamId == 1 {
+				t.Fatal("frame of removed stream is sent")
+			}
+		}
+	}
+	if calls1 != 4 || calls3 != 4 {
+		t.Fatalf("unexpected listener calls: stm1=%d stm3=%d", calls1, calls3)
+	}
+}
+
+func TestResetCallsDroppedListeners(t *testing.T) {
+	p := &recordingPoster{}
+	s := NewH2WriteScheduler(p.post)
+
+	calls := 0
+	for i := 0; i < 5; i++ {
+		postData(t, s, 1, &calls)
+	}
+
+	s.Reset()
+	if calls != 5-SCHEDULER_MAX_IN_FLIGHT {
+		t.Fatalf("listeners of dropped frames are not called: %d", calls)
+	}
+}
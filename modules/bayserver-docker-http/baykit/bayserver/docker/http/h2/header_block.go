@@ -1,6 +1,7 @@
 package h2
 
 import (
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/util/exception"
 	"strconv"
 )
@@ -40,7 +41,19 @@ func HeaderBlockPack(blk *HeaderBlock, acc *H2DataAccessor) exception.IOExceptio
 	case HEADER_OP_INDEX:
 		acc.PutHPackInt(blk.index, 7, 1)
 
-	case HEADER_OP_OVERLOAD_KNOWN_HEADER, HEADER_OP_NEW_HEADER, HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE:
+	case HEADER_OP_OVERLOAD_KNOWN_HEADER:
+		acc.PutHPackInt(blk.index, 6, 1)
+		acc.PutHPackString(blk.value, false)
+
+	case HEADER_OP_NEW_HEADER:
+		acc.PutByte(0x40)
+		acc.PutHPackString(blk.name, false)
+		acc.PutHPackString(blk.value, false)
+
+	case HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE:
+		acc.PutHPackInt(blk.size, 5, 1)
+
+	default:
 		return exception.NewIOException("Illegal state")
 
 	case HEADER_OP_KNOWN_HEADER:
@@ -56,8 +69,10 @@ func HeaderBlockPack(blk *HeaderBlock, acc *H2DataAccessor) exception.IOExceptio
 	return nil
 }
 
-func HeaderBlockUnPack(acc *H2DataAccessor) *HeaderBlock {
+func HeaderBlockUnPack(acc *H2DataAccessor) (*HeaderBlock, exception2.ProtocolException) {
 	blk := NewHeaderBlock()
+	var perr exception2.ProtocolException = nil
+	var rest int
 	index := acc.GetByte()
 	//BayServer.debug("index: " + index);
 	indexHeaderField := (index & 0x80) != 0
@@ -71,6 +86,13 @@ func HeaderBlockUnPack(acc *H2DataAccessor) *HeaderBlock {
 		 */
 		blk.op = HEADER_OP_INDEX
 		blk.index = index & 0x7F
+		if blk.index == 0x7F {
+			rest, perr = acc.GetHPackIntRest()
+			if perr != nil {
+				return nil, perr
+			}
+			blk.index += rest
+		}
 
 	} else {
 		// literal header field
@@ -81,7 +103,11 @@ func HeaderBlockUnPack(acc *H2DataAccessor) *HeaderBlock {
 			if overloadIndex {
 				// known header name
 				if index == 0x3F {
-					index = index + acc.GetHPackIntRest()
+					rest, perr = acc.GetHPackIntRest()
+					if perr != nil {
+						return nil, perr
+					}
+					index = index + rest
 				}
 				blk.op = HEADER_OP_OVERLOAD_KNOWN_HEADER
 				blk.index = index
@@ -96,7 +122,7 @@ func HeaderBlockUnPack(acc *H2DataAccessor) *HeaderBlock {
 				 *    | Value String (Length octets)  |
 				 *    +-------------------------------+
 				 */
-				blk.value = acc.GetHPackString()
+				blk.value, perr = acc.GetHPackString()
 
 			} else {
 				// new header name
@@ -115,8 +141,11 @@ func HeaderBlockUnPack(acc *H2DataAccessor) *HeaderBlock {
 				 * +-------------------------------+
 				 */
 				blk.op = HEADER_OP_NEW_HEADER
-				blk.name = acc.GetHPackString()
-				blk.value = acc.GetHPackString()
+				blk.name, perr = acc.GetHPackString()
+				if perr != nil {
+					return nil, perr
+				}
+				blk.value, perr = acc.GetHPackString()
 			}
 
 		} else {
@@ -130,7 +159,11 @@ func HeaderBlockUnPack(acc *H2DataAccessor) *HeaderBlock {
 				 */
 				size := index & 0x1f
 				if size == 0x1f {
-					size = size + acc.GetHPackIntRest()
+					rest, perr = acc.GetHPackIntRest()
+					if perr != nil {
+						return nil, perr
+					}
+					size = size + rest
 				}
 				blk.op = HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE
 				blk.size = size
@@ -161,11 +194,15 @@ func HeaderBlockUnPack(acc *H2DataAccessor) *HeaderBlock {
 					 * +-------------------------------+
 					 */
 					if index == 0xF {
-						index = index + acc.GetHPackIntRest()
+						rest, perr = acc.GetHPackIntRest()
+						if perr != nil {
+							return nil, perr
+						}
+						index = index + rest
 					}
 					blk.op = HEADER_OP_KNOWN_HEADER
 					blk.index = index
-					blk.value = acc.GetHPackString()
+					blk.value, perr = acc.GetHPackString()
 				} else {
 					// literal header field
 					/**
@@ -198,12 +235,17 @@ func HeaderBlockUnPack(acc *H2DataAccessor) *HeaderBlock {
 					 * +-------------------------------+
 					 */
 					blk.op = HEADER_OP_UNKNOWN_HEADER
-					blk.name = acc.GetHPackString()
-					blk.value = acc.GetHPackString()
+					blk.name, perr = acc.GetHPackString()
+					if perr != nil {
+						return nil, perr
+					}
+					blk.value, perr = acc.GetHPackString()
 				}
 			}
 		}
 	}
-	return blk
-
+	if perr != nil {
+		return nil, perr
+	}
+	return blk, nil
 }
@@ -61,7 +61,10 @@ func (a *HeaderBlockAnalyzer) AnalyzeHeaderBlock(blk *HeaderBlock, tbl *HeaderTa
 		a.Value = blk.value
 
 	case HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE:
-		tbl.SetSize(blk.size)
+		perr := tbl.SetSize(blk.size)
+		if perr != nil {
+			return perr
+		}
 
 	default:
 		bayserver.FatalError(exception2.NewSink("Invalid op: %d", blk.op))
@@ -4,6 +4,18 @@ import (
 	exception2 "bayserver-core/baykit/bayserver/common/exception"
 )
 
+// Headers which change on every response are not added to dynamic table
+var noIndexHeaders = map[string]bool{
+	"age":            true,
+	"content-length": true,
+	"content-range":  true,
+	"date":           true,
+	"etag":           true,
+	"expires":        true,
+	"last-modified":  true,
+	"set-cookie":     true,
+}
+
 type HeaderBlockBuilder struct {
 }
 
@@ -18,7 +30,7 @@ func (b *HeaderBlockBuilder) BuildHeaderBlock(name string, value string, tbl *He
 	for _, idx := range idxList {
 		kv := tbl.Get(idx)
 		if kv == nil {
-			return nil, exception2.NewProtocolException("Invalid header index: %d", blk.index)
+			return nil, exception2.NewProtocolException("Invalid header index: %d", idx)
 		}
 		if kv != nil && value == kv.Value {
 			blk = NewHeaderBlock()
@@ -29,17 +41,38 @@ func (b *HeaderBlockBuilder) BuildHeaderBlock(name string, value string, tbl *He
 	}
 	if blk == nil {
 		blk = NewHeaderBlock()
+		indexing := !noIndexHeaders[name] && EntrySize(name, value) <= tbl.MaxSize()
 		if len(idxList) > 0 {
-			blk.op = HEADER_OP_KNOWN_HEADER
+			if indexing {
+				blk.op = HEADER_OP_OVERLOAD_KNOWN_HEADER
+			} else {
+				blk.op = HEADER_OP_KNOWN_HEADER
+			}
 			blk.index = idxList[0]
 			blk.value = value
 		} else {
-			blk.op = HEADER_OP_UNKNOWN_HEADER
+			if indexing {
+				blk.op = HEADER_OP_NEW_HEADER
+			} else {
+				blk.op = HEADER_OP_UNKNOWN_HEADER
+			}
 			blk.name = name
 			blk.value = value
 		}
+
+		if indexing {
+			tbl.Insert(name, value)
+		}
 	}
 
 	return blk, nil
 
 }
+
+func (b *HeaderBlockBuilder) BuildTableSizeUpdate(size int, tbl *HeaderTable) *HeaderBlock {
+	tbl.SetSize(size)
+	blk := NewHeaderBlock()
+	blk.op = HEADER_OP_UPDATE_DYNAMIC_TABLE_SIZE
+	blk.size = size
+	return blk
+}
@@ -2,6 +2,7 @@ package h2
 
 import (
 	"bayserver-core/baykit/bayserver/bayserver"
+	exception2 "bayserver-core/baykit/bayserver/common/exception"
 	"bayserver-core/baykit/bayserver/util"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	"bayserver-core/baykit/bayserver/util/exception"
@@ -23,13 +24,19 @@ const PSEUDO_HEADER_SCHEME = ":scheme"
 
 const PSEUDO_HEADER_STATUS = ":status"
 
+// Overhead of each entry (RFC 7541 4.1)
+const HEADER_ENTRY_OVERHEAD = 32
+
 var staticTable = newHeaderTable()
 var staticSize = 0
 
 type HeaderTable struct {
-	idxMap   []*util.KeyVal
+	idxMap   []*util.KeyVal // dynamic table entries (newest first)
 	addCount int
-	nameMap  map[string][]int
+	nameMap  map[string][]int // name -> insertion numbers
+	size     int
+	maxSize  int
+	limit    int // upper bound of maxSize (SETTINGS_HEADER_TABLE_SIZE)
 }
 
 func newHeaderTable() *HeaderTable {
@@ -37,6 +44,9 @@ func newHeaderTable() *HeaderTable {
 		idxMap:   make([]*util.KeyVal, 0),
 		addCount: 0,
 		nameMap:  make(map[string][]int),
+		size:     0,
+		maxSize:  DEFAULT_HEADER_TABLE_SIZE,
+		limit:    DEFAULT_HEADER_TABLE_SIZE,
 	}
 	return h
 }
@@ -67,8 +77,8 @@ func (t *HeaderTable) GetByName(name string) []int {
 		idxList = append(idxList, staticList...)
 	}
 	if dynamicList != nil {
-		for _, idx := range dynamicList {
-			readIndex := t.addCount - idx + staticSize
+		for _, insNo := range dynamicList {
+			readIndex := t.addCount - insNo + staticSize + 1
 			idxList = append(idxList, readIndex)
 		}
 	}
@@ -76,7 +86,17 @@ func (t *HeaderTable) GetByName(name string) []int {
 }
 
 func (t *HeaderTable) Insert(name string, value string) {
+	entrySize := EntrySize(name, value)
+	for len(t.idxMap) > 0 && t.size+entrySize > t.maxSize {
+		t.evict()
+	}
+	if entrySize > t.maxSize {
+		// Entry larger than table empties the table (RFC 7541 4.4)
+		return
+	}
+
 	t.idxMap = append([]*util.KeyVal{util.NewKeyVal(name, value)}, t.idxMap...)
+	t.size += entrySize
 	t.addCount++
 	t.addToNameMap(name, t.addCount)
 }
@@ -98,8 +118,56 @@ func (t *HeaderTable) addToNameMap(name string, idx int) {
 	t.nameMap[name] = idxList
 }
 
-func (t *HeaderTable) SetSize(size int) {
+// Removes oldest entry
+func (t *HeaderTable) evict() {
+	last := len(t.idxMap) - 1
+	kv := t.idxMap[last]
+	insNo := t.addCount - last
+	t.idxMap = t.idxMap[:last]
+	t.size -= EntrySize(kv.Name, kv.Value)
+
+	idxList := t.nameMap[kv.Name]
+	for i, n := range idxList {
+		if n == insNo {
+			idxList = append(idxList[:i], idxList[i+1:]...)
+			break
+		}
+	}
+	if len(idxList) == 0 {
+		delete(t.nameMap, kv.Name)
+	} else {
+		t.nameMap[kv.Name] = idxList
+	}
+}
+
+func (t *HeaderTable) Size() int {
+	return t.size
+}
+
+func (t *HeaderTable) MaxSize() int {
+	return t.maxSize
+}
+
+// SetSize changes maximum size of dynamic table (Dynamic table size update)
+func (t *HeaderTable) SetSize(size int) exception2.ProtocolException {
+	if size > t.limit {
+		return exception2.NewProtocolException("Dynamic table size exceeds limit: %d/%d", size, t.limit)
+	}
+	t.maxSize = size
+	for t.size > t.maxSize {
+		t.evict()
+	}
+	return nil
+}
+
+// SetLimit changes upper bound of table size (SETTINGS_HEADER_TABLE_SIZE).
+// Table size itself is changed by the following dynamic table size update.
+func (t *HeaderTable) SetLimit(limit int) {
+	t.limit = limit
+}
 
+func EntrySize(name string, value string) int {
+	return len(name) + len(value) + HEADER_ENTRY_OVERHEAD
 }
 
 func CreateDynamicTable() *HeaderTable {
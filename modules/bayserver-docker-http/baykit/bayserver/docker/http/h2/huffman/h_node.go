@@ -1,7 +1,7 @@
 package huffman
 
 type HNode struct {
-	Value int //  if vlaue > 0 leaf node else inter node
+	Value int //  if vlaue >= 0 leaf node else inter node
 	One   *HNode
 	Zero  *HNode
 }
@@ -5,9 +5,14 @@ import "bytes"
 var HTreeRoot = NewHNode()
 var initialized = false
 
-func HTreeDecode(data []byte) string {
+const EOS = 256
+
+// HTreeDecode decodes huffman coded data. Returns false if data is not valid (RFC 7541 5.2)
+func HTreeDecode(data []byte) (string, bool) {
 	w := bytes.Buffer{}
 	cur := HTreeRoot
+	padBits := 0
+	padOnes := true
 	for _, c := range data {
 		for j := 0; j < 8; j++ {
 			bit := c >> (8 - j - 1) & 0x1
@@ -17,16 +22,31 @@ func HTreeDecode(data []byte) string {
 				cur = cur.One
 			} else {
 				cur = cur.Zero
+				padOnes = false
+			}
+			if cur == nil {
+				return "", false
 			}
+			padBits++
 
-			if cur.Value > 0 {
+			if cur.Value >= 0 {
 				// leaf node
+				if cur.Value == EOS {
+					return "", false
+				}
 				w.Write([]byte{byte(cur.Value)})
 				cur = HTreeRoot
+				padBits = 0
+				padOnes = true
 			}
 		}
 	}
-	return w.String()
+
+	// Padding must be shorter than 8 bits and consist of the most significant bits of EOS
+	if padBits > 7 || !padOnes {
+		return "", false
+	}
+	return w.String(), true
 }
 
 func hTreeInsert(code int, lenInBits int, sym int) {
@@ -317,7 +337,7 @@ func HTreeInit() bool {
 	hTreeInsert(0x7ffffef, 27, 253)
 	hTreeInsert(0x7fffff0, 27, 254)
 	hTreeInsert(0x3ffffee, 26, 255)
-	hTreeInsert(0x3fffffff, 30, 256)
+	hTreeInsert(0x3fffffff, 30, EOS)
 
 	initialized = true
 	return true
@@ -2,6 +2,38 @@ package http
 
 const DEFAULT_SUPPORT_H2 = true
 
+// HTTP/2 flood protection defaults (0 means unlimited)
+const DEFAULT_H2_MAX_CONCURRENT_STREAMS = 100
+const DEFAULT_H2_MAX_STREAMS = 10000
+const DEFAULT_H2_MAX_HEADER_LIST_SIZE = 65536
+const DEFAULT_H2_MAX_RST_STREAM_RATE = 100
+const DEFAULT_H2_MAX_PING_RATE = 50
+const DEFAULT_H2_MAX_SETTINGS_RATE = 10
+const DEFAULT_H2_MAX_PRIORITY_RATE = 100
+const DEFAULT_H2_MAX_EMPTY_DATA_RATE = 100
+
+const DEFAULT_H2_SERVER_PUSH = false
+
+const DEFAULT_H2_PRIORITIZATION = true
+
 type HtpPortDocker interface {
 	SupportH2() bool
+
+	// Limits of streams per connection
+	H2MaxConcurrentStreams() int
+	H2MaxStreams() int
+	H2MaxHeaderListSize() int
+
+	// Limits of frames per second per connection
+	H2MaxRstStreamRate() int
+	H2MaxPingRate() int
+	H2MaxSettingsRate() int
+	H2MaxPriorityRate() int
+	H2MaxEmptyDataRate() int
+
+	// Push resources listed in "Link: rel=preload" headers
+	H2ServerPush() bool
+
+	// Schedule DATA frames by priority (RFC 9218). Otherwise, frames are sent in FIFO order
+	H2Prioritization() bool
 }
@@ -24,6 +24,17 @@ var registerd = false
 type HtpPortDockerImpl struct {
 	*base.PortBase
 	supportH2 bool
+
+	h2MaxConcurrentStreams int
+	h2MaxStreams           int
+	h2MaxHeaderListSize    int
+	h2MaxRstStreamRate     int
+	h2MaxPingRate          int
+	h2MaxSettingsRate      int
+	h2MaxPriorityRate      int
+	h2MaxEmptyDataRate     int
+	h2ServerPush           bool
+	h2Prioritization       bool
 }
 
 func NewHtpPort() docker.Port {
@@ -33,6 +44,16 @@ func NewHtpPort() docker.Port {
 	h := &HtpPortDockerImpl{}
 	h.PortBase = base.NewPortBase(h)
 	h.supportH2 = DEFAULT_SUPPORT_H2
+	h.h2MaxConcurrentStreams = http.DEFAULT_H2_MAX_CONCURRENT_STREAMS
+	h.h2MaxStreams = http.DEFAULT_H2_MAX_STREAMS
+	h.h2MaxHeaderListSize = http.DEFAULT_H2_MAX_HEADER_LIST_SIZE
+	h.h2MaxRstStreamRate = http.DEFAULT_H2_MAX_RST_STREAM_RATE
+	h.h2MaxPingRate = http.DEFAULT_H2_MAX_PING_RATE
+	h.h2MaxSettingsRate = http.DEFAULT_H2_MAX_SETTINGS_RATE
+	h.h2MaxPriorityRate = http.DEFAULT_H2_MAX_PRIORITY_RATE
+	h.h2MaxEmptyDataRate = http.DEFAULT_H2_MAX_EMPTY_DATA_RATE
+	h.h2ServerPush = http.DEFAULT_H2_SERVER_PUSH
+	h.h2Prioritization = http.DEFAULT_H2_PRIORITIZATION
 
 	// interface check
 	var _ docker.Docker = h
@@ -83,6 +104,44 @@ func (d *HtpPortDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.Confi
 			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
 		}
 
+	case "h2maxconcurrentstreams":
+		return d.initIntParam(kv, &d.h2MaxConcurrentStreams)
+
+	case "h2maxstreams":
+		return d.initIntParam(kv, &d.h2MaxStreams)
+
+	case "h2maxheaderlistsize":
+		return d.initIntParam(kv, &d.h2MaxHeaderListSize)
+
+	case "h2maxrststreamrate":
+		return d.initIntParam(kv, &d.h2MaxRstStreamRate)
+
+	case "h2maxpingrate":
+		return d.initIntParam(kv, &d.h2MaxPingRate)
+
+	case "h2maxsettingsrate":
+		return d.initIntParam(kv, &d.h2MaxSettingsRate)
+
+	case "h2maxpriorityrate":
+		return d.initIntParam(kv, &d.h2MaxPriorityRate)
+
+	case "h2maxemptydatarate":
+		return d.initIntParam(kv, &d.h2MaxEmptyDataRate)
+
+	case "h2serverpush":
+		var err error
+		d.h2ServerPush, err = strutil.ParseBool(kv.Value)
+		if err != nil {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "h2prioritization":
+		var err error
+		d.h2Prioritization, err = strutil.ParseBool(kv.Value)
+		if err != nil {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
 	default:
 		return d.PortBase.InitKeyVal(kv)
 	}
@@ -118,6 +177,59 @@ func (d *HtpPortDockerImpl) SupportH2() bool {
 	return d.supportH2
 }
 
+func (d *HtpPortDockerImpl) H2MaxConcurrentStreams() int {
+	return d.h2MaxConcurrentStreams
+}
+
+func (d *HtpPortDockerImpl) H2MaxStreams() int {
+	return d.h2MaxStreams
+}
+
+func (d *HtpPortDockerImpl) H2MaxHeaderListSize() int {
+	return d.h2MaxHeaderListSize
+}
+
+func (d *HtpPortDockerImpl) H2MaxRstStreamRate() int {
+	return d.h2MaxRstStreamRate
+}
+
+func (d *HtpPortDockerImpl) H2MaxPingRate() int {
+	return d.h2MaxPingRate
+}
+
+func (d *HtpPortDockerImpl) H2MaxSettingsRate() int {
+	return d.h2MaxSettingsRate
+}
+
+func (d *HtpPortDockerImpl) H2MaxPriorityRate() int {
+	return d.h2MaxPriorityRate
+}
+
+func (d *HtpPortDockerImpl) H2MaxEmptyDataRate() int {
+	return d.h2MaxEmptyDataRate
+}
+
+func (d *HtpPortDockerImpl) H2ServerPush() bool {
+	return d.h2ServerPush
+}
+
+func (d *HtpPortDockerImpl) H2Prioritization() bool {
+	return d.h2Prioritization
+}
+
+/****************************************/
+/* Private functions                    */
+/****************************************/
+
+func (d *HtpPortDockerImpl) initIntParam(kv *bcf.BcfKeyVal, val *int) (bool, exception.ConfigException) {
+	n, err := strutil.ParseInt(kv.Value)
+	if err != nil || n < 0 {
+		return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+	}
+	*val = n
+	return true, nil
+}
+
 /****************************************/
 /* Static function                      */
 /****************************************/
@@ -14,6 +14,7 @@ import (
 	"bayserver-core/baykit/bayserver/rudder/impl"
 	"bayserver-core/baykit/bayserver/ship"
 	"bayserver-core/baykit/bayserver/symbol"
+	"bayserver-core/baykit/bayserver/tour"
 	"bayserver-core/baykit/bayserver/util/baylog"
 	exception2 "bayserver-core/baykit/bayserver/util/exception"
 	"bayserver-core/baykit/bayserver/util/strutil"
@@ -95,6 +96,18 @@ func (d *HtpWarpDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.Confi
 	return true, nil
 }
 
+/****************************************/
+/* Implements Club                      */
+/****************************************/
+
+func (d *HtpWarpDockerImpl) Arrive(tur tour.Tour) exception.HttpException {
+	if tur.Req().ExpectsContinue() {
+		// Expect header is forwarded and the backend decides whether to continue
+		tur.Req().SetContinueRelayed(true)
+	}
+	return d.WarpBase.Arrive(tur)
+}
+
 /****************************************/
 /* Implements WarpSub                  */
 /****************************************/
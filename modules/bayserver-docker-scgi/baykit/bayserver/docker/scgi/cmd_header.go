@@ -0,0 +1,108 @@
+package scgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	"bayserver-core/baykit/bayserver/util/cgiutil"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bytes"
+	"sort"
+	"strconv"
+)
+
+/**
+ * SCGI spec
+ *   https://python.ca/scgi/protocol.txt
+ *
+ * Header command format (netstring)
+ *   [len]":"[name]\0[value]\0 ... ","
+ *
+ *   CONTENT_LENGTH must be the first header and "SCGI" header must be present
+ */
+
+const SCGI = "SCGI"
+
+type CmdHeader struct {
+	*impl.CommandBase
+	Params [][2]string
+}
+
+func NewCmdHeader() *CmdHeader {
+	c := CmdHeader{
+		CommandBase: impl.NewCommandBase(SCG_TYPE_HEADER),
+	}
+	var _ protocol.Command = &c // cast check
+	return &c
+}
+
+func (c *CmdHeader) String() string {
+	return "CmdHeader"
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdHeader) Unpack(pkt protocol.Packet) exception2.IOException {
+	data := pkt.Buf()[pkt.HeaderLen() : pkt.HeaderLen()+pkt.DataLen()]
+	colonPos := bytes.IndexByte(data, ':')
+	if colonPos <= 0 {
+		return exception.NewProtocolException("scgi: Invalid netstring")
+	}
+	length, err := strconv.Atoi(string(data[:colonPos]))
+	if err != nil || colonPos+1+length >= len(data) || data[colonPos+1+length] != ',' {
+		return exception.NewProtocolException("scgi: Invalid netstring")
+	}
+
+	c.Params = c.Params[:0]
+	fields := bytes.Split(data[colonPos+1:colonPos+1+length], []byte{0})
+	for i := 0; i+1 < len(fields); i += 2 {
+		c.Params = append(c.Params, [2]string{string(fields[i]), string(fields[i+1])})
+	}
+	return nil
+}
+
+func (c *CmdHeader) Pack(pkt protocol.Packet) exception2.IOException {
+	body := bytes.Buffer{}
+	for _, nv := range c.Params {
+		body.WriteString(nv[0])
+		body.WriteByte(0)
+		body.WriteString(nv[1])
+		body.WriteByte(0)
+	}
+
+	acc := pkt.NewDataAccessor()
+	acc.PutString(strconv.Itoa(body.Len()))
+	acc.PutByte(':')
+	acc.PutBytes(body.Bytes(), 0, body.Len())
+	acc.PutByte(',')
+	return nil
+}
+
+func (c *CmdHeader) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
+	return h.(ScgCommandHandler).HandleHeader(c)
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+// SetEnv sets header block from CGI environment. CONTENT_LENGTH is put at the head as the spec requires.
+func (c *CmdHeader) SetEnv(env map[string]string, contLen int) {
+	c.Params = c.Params[:0]
+	c.Params = append(c.Params, [2]string{cgiutil.CONTENT_LENGTH, strconv.Itoa(contLen)})
+	c.Params = append(c.Params, [2]string{SCGI, "1"})
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		if name != cgiutil.CONTENT_LENGTH && name != SCGI {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.Params = append(c.Params, [2]string{name, env[name]})
+	}
+}
@@ -0,0 +1,136 @@
+package impl
+
+import (
+	"bayserver-core/baykit/bayserver/agent"
+	"bayserver-core/baykit/bayserver/agent/multiplexer"
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	"bayserver-core/baykit/bayserver/common/cgiwarp"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/docker/base"
+	"bayserver-core/baykit/bayserver/protocol/packetstore"
+	"bayserver-core/baykit/bayserver/protocol/protocolhandlerstore"
+	"bayserver-core/baykit/bayserver/rudder"
+	"bayserver-core/baykit/bayserver/rudder/impl"
+	"bayserver-core/baykit/bayserver/ship"
+	"bayserver-core/baykit/bayserver/symbol"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"bayserver-docker-scgi/baykit/bayserver/docker/scgi"
+	"strings"
+)
+
+var warpRegisterd = false
+
+type ScgWarpDockerImpl struct {
+	*base.WarpBase
+	scriptBase       string
+	docRoot          string
+	maxRequestBuffer int
+}
+
+func NewScgWarpDocker() docker.Docker {
+	if !warpRegisterd {
+		registerWarpProtocols()
+	}
+	dkr := &ScgWarpDockerImpl{
+		maxRequestBuffer: cgiwarp.DEFAULT_MAX_REQUEST_BUFFER,
+	}
+	dkr.WarpBase = base.NewWarpBase(dkr)
+
+	var _ docker.Warp = dkr        // implement check
+	var _ docker.Club = dkr        // implement check
+	var _ scgi.ScgWarpDocker = dkr // implement check
+	return dkr
+}
+
+func (d *ScgWarpDockerImpl) String() string {
+	return "ScgWarp"
+}
+
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (d *ScgWarpDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.ConfigException) {
+	switch strings.ToLower(kv.Key) {
+	case "scriptbase":
+		d.scriptBase = kv.Value
+
+	case "docroot":
+		d.docRoot = kv.Value
+
+	case "maxrequestbuffer":
+		var err exception2.Exception
+		d.maxRequestBuffer, err = strutil.ParseSize(kv.Value)
+		if err != nil || d.maxRequestBuffer < 0 {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	default:
+		return d.WarpBase.InitKeyVal(kv)
+	}
+
+	return true, nil
+}
+
+/****************************************/
+/* Implements WarpSub                  */
+/****************************************/
+
+func (d *ScgWarpDockerImpl) Secure() bool {
+	return false
+}
+
+func (d *ScgWarpDockerImpl) Protocol() string {
+	return scgi.SCG_PROTO_NAME
+}
+
+func (d *ScgWarpDockerImpl) NewTransporter(agt agent.GrandAgent, rd rudder.Rudder, sip ship.Ship) (*multiplexer.PlainTransporter, exception2.IOException) {
+	bufSize, ioerr := rd.(*impl.TcpConnRudder).GetSocketReceiveBufferSize()
+	if ioerr != nil {
+		return nil, ioerr
+	}
+	tp := multiplexer.NewPlainTransporter(
+		agt.NetMultiplexer(),
+		sip,
+		false,
+		bufSize,
+		false)
+	tp.Init()
+	return tp, nil
+}
+
+/****************************************/
+/* Implements ScgWarpDocker             */
+/****************************************/
+
+func (d *ScgWarpDockerImpl) ScriptBase() string {
+	return d.scriptBase
+}
+
+func (d *ScgWarpDockerImpl) DocRoot() string {
+	return d.docRoot
+}
+
+func (d *ScgWarpDockerImpl) MaxRequestBuffer() int {
+	return d.maxRequestBuffer
+}
+
+/****************************************/
+/* Static function                      */
+/****************************************/
+
+func registerWarpProtocols() {
+	packetstore.RegisterPacketProtocol(
+		scgi.SCG_PROTO_NAME,
+		scgi.ScgPacketFactory,
+	)
+	protocolhandlerstore.RegisterProtocol(
+		scgi.SCG_PROTO_NAME,
+		false,
+		scgi.ScgWarpProtocolHandlerFactory,
+	)
+	warpRegisterd = true
+}
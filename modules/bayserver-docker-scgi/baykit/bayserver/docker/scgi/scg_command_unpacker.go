@@ -0,0 +1,59 @@
+package scgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ *
+ * SCGI response rule
+ *
+ *   Content*
+ *
+ */
+
+type ScgCommandUnpacker struct {
+	impl.CommandUnpackerImpl
+	Handler ScgCommandHandler
+}
+
+func NewScgCommandUnpacker(handler ScgCommandHandler) *ScgCommandUnpacker {
+	cu := ScgCommandUnpacker{
+		Handler: handler,
+	}
+	cu.Reset()
+	return &cu
+}
+
+/****************************************/
+/* Implements CommandUnpacker           */
+/****************************************/
+
+func (cu *ScgCommandUnpacker) PacketReceived(pkt protocol.Packet) (common.NextSocketAction, exception2.IOException) {
+	baylog.Debug("scg: read packet type=%d length=%d", pkt.Type(), pkt.DataLen())
+
+	var cmd protocol.Command
+	switch pkt.Type() {
+	case SCG_TYPE_HEADER:
+		cmd = NewCmdHeader()
+
+	case SCG_TYPE_CONTENT:
+		cmd = NewCmdContent(nil, 0, 0)
+
+	default:
+		baylog.FatalE(exception2.NewSink("Illegal State"), "")
+		panic("Error")
+	}
+
+	ioerr := cmd.Unpack(pkt)
+	if ioerr != nil {
+		return -1, ioerr
+	}
+
+	return cmd.Handle(cu.Handler)
+
+}
@@ -0,0 +1,3 @@
+package scgi
+
+const SCG_PROTO_NAME = "scgi"
@@ -0,0 +1,15 @@
+package scgi
+
+import (
+	"bayserver-core/baykit/bayserver/common/exception"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+type ScgHandler interface {
+	ScgCommandHandler
+
+	/**
+	 * Send protocol error to client
+	 */
+	OnProtocolError(e exception.ProtocolException) (bool, exception2.IOException)
+}
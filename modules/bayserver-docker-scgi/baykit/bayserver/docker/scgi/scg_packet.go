@@ -0,0 +1,38 @@
+package scgi
+
+import (
+	"bayserver-core/baykit/bayserver/protocol"
+	"strconv"
+)
+
+/**
+ * SCGI spec
+ *   https://python.ca/scgi/protocol.txt
+ *
+ * SCGI Packet format
+ *   SCGI has no packet framing. The request starts with header block encoded as netstring
+ *   and content follows. Response is CGI response which ends when server closes the connection.
+ *
+ *   Request:  [len]":"[name]\0[value]\0 ... ","[content]
+ *   Response: [CGI headers]\r\n[content]
+ */
+
+const SCG_PACKET_MAXLEN = 65536
+
+type ScgPacket struct {
+	protocol.PacketImpl
+}
+
+func NewScgPacket(typ int) *ScgPacket {
+	p := ScgPacket{}
+	p.ConstructPacket(typ, 0, SCG_PACKET_MAXLEN)
+	return &p
+}
+
+func (p *ScgPacket) Reset() {
+	p.PacketImpl.Reset()
+}
+
+func (p *ScgPacket) String() string {
+	return "ScgPacket(" + strconv.Itoa(p.Type()) + ")"
+}
@@ -0,0 +1,7 @@
+package scgi
+
+import "bayserver-core/baykit/bayserver/protocol"
+
+func ScgPacketFactory(typ int) protocol.Packet {
+	return NewScgPacket(typ)
+}
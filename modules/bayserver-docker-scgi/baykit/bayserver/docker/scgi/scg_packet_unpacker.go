@@ -0,0 +1,78 @@
+package scgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	"bayserver-core/baykit/bayserver/protocol/packetstore"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * Packet unmarshall logic for SCGI
+ *   Since SCGI response has no framing, received bytes are passed as content packets as they are.
+ */
+
+type ScgPacketUnpacker struct {
+	impl.PacketUnpackerImpl
+
+	cmdUnpacker *ScgCommandUnpacker
+	pktStore    *packetstore.PacketStore
+}
+
+func NewScgPacketUnpacker(cmdUnpacker *ScgCommandUnpacker, pktStore *packetstore.PacketStore) *ScgPacketUnpacker {
+	pu := ScgPacketUnpacker{
+		cmdUnpacker: cmdUnpacker,
+		pktStore:    pktStore,
+	}
+	pu.Reset()
+	return &pu
+}
+
+/****************************************/
+/* Implements Reusable                  */
+/****************************************/
+
+func (pu *ScgPacketUnpacker) Reset() {
+}
+
+/****************************************/
+/* Implements PacketUnpacker            */
+/****************************************/
+
+func (pu *ScgPacketUnpacker) BytesReceived(buf []byte) (common.NextSocketAction, exception2.IOException) {
+	nextSuspend := false
+	pos := 0
+
+	for pos < len(buf) {
+		length := len(buf) - pos
+		if length > SCG_PACKET_MAXLEN {
+			length = SCG_PACKET_MAXLEN
+		}
+
+		pkt := pu.pktStore.Rent(SCG_TYPE_CONTENT)
+		pkt.NewDataAccessor().PutBytes(buf, pos, length)
+		pos += length
+
+		nextAct, ioerr := pu.cmdUnpacker.PacketReceived(pkt)
+		pu.pktStore.Return(pkt)
+		if ioerr != nil {
+			return -1, ioerr
+		}
+
+		switch nextAct {
+		case common.NEXT_SOCKET_ACTION_SUSPEND:
+			nextSuspend = true
+		case common.NEXT_SOCKET_ACTION_CONTINUE:
+			break
+		case common.NEXT_SOCKET_ACTION_CLOSE:
+			return nextAct, nil
+		}
+	}
+
+	if nextSuspend {
+		return common.NEXT_SOCKET_ACTION_SUSPEND, nil
+
+	} else {
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+}
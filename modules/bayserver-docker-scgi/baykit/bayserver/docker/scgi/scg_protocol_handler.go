@@ -0,0 +1,50 @@
+package scgi
+
+import (
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+)
+
+type ScgProtocolHandler interface {
+	protocol.ProtocolHandler
+}
+
+type ScgProtocolHandlerImpl struct {
+	impl.ProtocolHandlerImpl
+}
+
+func NewScgProtocolHandler(
+	packetUnpacker protocol.PacketUnpacker,
+	packetPacker protocol.PacketPacker,
+	commandUnpacker protocol.CommandUnpacker,
+	commandPacker protocol.CommandPacker,
+	commandHandler protocol.CommandHandler,
+	serverMode bool) *ScgProtocolHandlerImpl {
+
+	ph := ScgProtocolHandlerImpl{}
+	ph.ProtocolHandlerImpl.ConstructProtocolHandler(packetUnpacker, packetPacker, commandUnpacker, commandPacker, commandHandler, serverMode)
+	return &ph
+}
+
+/****************************************/
+/* Implements Reusable                  */
+/****************************************/
+
+func (h *ScgProtocolHandlerImpl) Reset() {
+	h.ProtocolHandlerImpl.Reset()
+}
+
+/****************************************/
+/* Implements ProtocolHandler           */
+/****************************************/
+
+func (h *ScgProtocolHandlerImpl) Protocol() string {
+	return SCG_PROTO_NAME
+}
+
+func (h *ScgProtocolHandlerImpl) MaxReqPacketDataSize() int {
+	return SCG_PACKET_MAXLEN
+}
+func (h *ScgProtocolHandlerImpl) MaxResPacketDataSize() int {
+	return SCG_PACKET_MAXLEN
+}
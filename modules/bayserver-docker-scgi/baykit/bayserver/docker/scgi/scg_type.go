@@ -0,0 +1,4 @@
+package scgi
+
+const SCG_TYPE_HEADER = 1
+const SCG_TYPE_CONTENT = 2
@@ -0,0 +1,7 @@
+package scgi
+
+import "bayserver-core/baykit/bayserver/common/cgiwarp"
+
+type ScgWarpDocker interface {
+	cgiwarp.CgiWarpDocker
+}
@@ -0,0 +1,63 @@
+package scgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/cgiwarp"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/warpship"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/tour"
+	"bayserver-core/baykit/bayserver/util"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+type ScgWarpHandler struct {
+	*cgiwarp.CgiWarpHandler
+}
+
+func NewScgWarpHandler() *ScgWarpHandler {
+	h := &ScgWarpHandler{
+		CgiWarpHandler: cgiwarp.NewCgiWarpHandler(SCG_PROTO_NAME, newHeader, newContent),
+	}
+
+	var _ tour.TourHandler = h     // cast check
+	var _ warpship.WarpHandler = h // cast check
+	var _ ScgHandler = h           // cast check
+	var _ util.Reusable = h        // cast check
+	return h
+}
+
+func (h *ScgWarpHandler) Init(handler *ScgProtocolHandlerImpl) {
+	h.CgiWarpHandler.Init(handler)
+}
+
+func (h *ScgWarpHandler) String() string {
+	return "ScgWarpHandler"
+}
+
+/****************************************/
+/* Implements ScgCommandHandler         */
+/****************************************/
+
+func (h *ScgWarpHandler) HandleHeader(cmd *CmdHeader) (common.NextSocketAction, exception2.IOException) {
+	return -1, exception.NewProtocolException("Invalid SCGI command: %d", cmd.Type())
+}
+
+func (h *ScgWarpHandler) HandleContent(cmd *CmdContent) (common.NextSocketAction, exception2.IOException) {
+	return h.HandleResData(cmd.Data, cmd.Start, cmd.Length)
+}
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+func newHeader(dkr docker.Warp, env map[string]string, contLen int) protocol.Command {
+	cmd := NewCmdHeader()
+	cmd.SetEnv(env, contLen)
+	return cmd
+}
+
+func newContent(buf []byte, start int, length int) protocol.Command {
+	return NewCmdContent(buf, start, length)
+}
@@ -0,0 +1,24 @@
+package scgi
+
+import (
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	"bayserver-core/baykit/bayserver/protocol/packetstore"
+)
+
+var ScgWarpProtocolHandlerFactory = func(pktStore *packetstore.PacketStore) protocol.ProtocolHandler {
+	warpHandler := NewScgWarpHandler()
+	commandUnpacker := NewScgCommandUnpacker(warpHandler)
+	packetUnpacker := NewScgPacketUnpacker(commandUnpacker, pktStore)
+	packetPacker := impl.NewPacketPacker()
+	commandPacker := impl.NewCommandPacker(packetPacker, pktStore)
+	protocolHandler := NewScgProtocolHandler(
+		packetUnpacker,
+		packetPacker,
+		commandUnpacker,
+		commandPacker,
+		warpHandler,
+		true)
+	warpHandler.Init(protocolHandler)
+	return protocolHandler
+}
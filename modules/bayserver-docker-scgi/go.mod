@@ -0,0 +1,3 @@
+module bayserver-docker-scgi
+
+go 1.18
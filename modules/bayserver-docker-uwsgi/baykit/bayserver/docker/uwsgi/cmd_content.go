@@ -0,0 +1,57 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * uWSGI spec
+ *   https://uwsgi-docs.readthedocs.io/en/latest/Protocol.html
+ *
+ * Content command format
+ *   raw data
+ */
+
+type CmdContent struct {
+	*impl.CommandBase
+	Start  int
+	Length int
+	Data   []byte
+}
+
+func NewCmdContent(data []byte, start int, length int) *CmdContent {
+	c := CmdContent{
+		CommandBase: impl.NewCommandBase(UWS_TYPE_CONTENT),
+		Data:        data,
+		Start:       start,
+		Length:      length,
+	}
+	var _ protocol.Command = &c // cast check
+	return &c
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdContent) Unpack(pkt protocol.Packet) exception2.IOException {
+	c.Start = pkt.HeaderLen()
+	c.Length = pkt.DataLen()
+	c.Data = pkt.Buf()
+	return nil
+}
+
+func (c *CmdContent) Pack(pkt protocol.Packet) exception2.IOException {
+	if c.Data != nil && c.Length > 0 {
+		acc := pkt.NewDataAccessor()
+		acc.PutBytes(c.Data, c.Start, c.Length)
+	}
+	return nil
+}
+
+func (c *CmdContent) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
+	return h.(UwsCommandHandler).HandleContent(c)
+}
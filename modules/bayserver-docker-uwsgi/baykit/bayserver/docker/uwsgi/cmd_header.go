@@ -0,0 +1,142 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	"bayserver-core/baykit/bayserver/util/cgiutil"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"sort"
+	"strconv"
+)
+
+/**
+ * uWSGI spec
+ *   https://uwsgi-docs.readthedocs.io/en/latest/Protocol.html
+ *
+ * Header command format
+ *   [modifier1][datasize(uint16 LE)][modifier2]
+ *   ([key_size(uint16 LE)][key][val_size(uint16 LE)][val])*
+ */
+
+type CmdHeader struct {
+	*impl.CommandBase
+	Modifier1 int
+	Modifier2 int
+	Vars      [][2]string
+}
+
+func NewCmdHeader(modifier1 int, modifier2 int) *CmdHeader {
+	c := CmdHeader{
+		CommandBase: impl.NewCommandBase(UWS_TYPE_HEADER),
+		Modifier1:   modifier1,
+		Modifier2:   modifier2,
+	}
+	var _ protocol.Command = &c // cast check
+	return &c
+}
+
+func (c *CmdHeader) String() string {
+	return "CmdHeader(" + strconv.Itoa(c.Modifier1) + "," + strconv.Itoa(c.Modifier2) + ")"
+}
+
+/****************************************/
+/* Implements Command                   */
+/****************************************/
+
+func (c *CmdHeader) Unpack(pkt protocol.Packet) exception2.IOException {
+	acc := pkt.NewDataAccessor()
+	if pkt.DataLen() < UWS_HEADER_SIZE {
+		return exception.NewProtocolException("uwsgi: Invalid header")
+	}
+	c.Modifier1 = acc.GetByte()
+	dataSize := getShortLE(acc)
+	c.Modifier2 = acc.GetByte()
+	if UWS_HEADER_SIZE+dataSize > pkt.DataLen() {
+		return exception.NewProtocolException("uwsgi: Invalid vars block size: %d", dataSize)
+	}
+
+	c.Vars = c.Vars[:0]
+	for acc.Pos() < UWS_HEADER_SIZE+dataSize {
+		name := getString(acc)
+		value := getString(acc)
+		c.Vars = append(c.Vars, [2]string{name, value})
+	}
+	return nil
+}
+
+func (c *CmdHeader) Pack(pkt protocol.Packet) exception2.IOException {
+	dataSize := 0
+	for _, nv := range c.Vars {
+		if len(nv[0]) > 0xffff || len(nv[1]) > 0xffff {
+			return exception.NewProtocolException("uwsgi: Var is too long: %s", nv[0])
+		}
+		dataSize += 2 + len(nv[0]) + 2 + len(nv[1])
+	}
+	if dataSize > UWS_PACKET_MAXLEN {
+		return exception.NewProtocolException("uwsgi: Vars block is too large: %d", dataSize)
+	}
+
+	acc := pkt.NewDataAccessor()
+	acc.PutByte(c.Modifier1)
+	putShortLE(acc, dataSize)
+	acc.PutByte(c.Modifier2)
+	for _, nv := range c.Vars {
+		putString(acc, nv[0])
+		putString(acc, nv[1])
+	}
+	return nil
+}
+
+func (c *CmdHeader) Handle(h protocol.CommandHandler) (common.NextSocketAction, exception2.IOException) {
+	return h.(UwsCommandHandler).HandleHeader(c)
+}
+
+/****************************************/
+/* Custom functions                     */
+/****************************************/
+
+// SetEnv sets vars block from CGI environment
+func (c *CmdHeader) SetEnv(env map[string]string, contLen int) {
+	c.Vars = c.Vars[:0]
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		if name != cgiutil.CONTENT_LENGTH {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.Vars = append(c.Vars, [2]string{name, env[name]})
+	}
+	c.Vars = append(c.Vars, [2]string{cgiutil.CONTENT_LENGTH, strconv.Itoa(contLen)})
+}
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+func putShortLE(acc *protocol.PacketPartAccessor, val int) {
+	acc.PutByte(val & 0xff)
+	acc.PutByte(val >> 8 & 0xff)
+}
+
+func getShortLE(acc *protocol.PacketPartAccessor) int {
+	l := acc.GetByte()
+	h := acc.GetByte()
+	return h<<8 | l
+}
+
+func putString(acc *protocol.PacketPartAccessor, s string) {
+	putShortLE(acc, len(s))
+	acc.PutString(s)
+}
+
+func getString(acc *protocol.PacketPartAccessor) string {
+	length := getShortLE(acc)
+	buf := make([]byte, length)
+	acc.GetBytes(buf, 0, length)
+	return string(buf)
+}
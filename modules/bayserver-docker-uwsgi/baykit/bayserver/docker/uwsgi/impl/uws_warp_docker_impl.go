@@ -0,0 +1,162 @@
+package impl
+
+import (
+	"bayserver-core/baykit/bayserver/agent"
+	"bayserver-core/baykit/bayserver/agent/multiplexer"
+	"bayserver-core/baykit/bayserver/bcf"
+	"bayserver-core/baykit/bayserver/common/baymessage"
+	"bayserver-core/baykit/bayserver/common/cgiwarp"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/docker/base"
+	"bayserver-core/baykit/bayserver/protocol/packetstore"
+	"bayserver-core/baykit/bayserver/protocol/protocolhandlerstore"
+	"bayserver-core/baykit/bayserver/rudder"
+	"bayserver-core/baykit/bayserver/rudder/impl"
+	"bayserver-core/baykit/bayserver/ship"
+	"bayserver-core/baykit/bayserver/symbol"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+	"bayserver-core/baykit/bayserver/util/strutil"
+	"bayserver-docker-uwsgi/baykit/bayserver/docker/uwsgi"
+	"strings"
+)
+
+var warpRegisterd = false
+
+type UwsWarpDockerImpl struct {
+	*base.WarpBase
+	scriptBase       string
+	docRoot          string
+	maxRequestBuffer int
+	modifier1        int
+	modifier2        int
+}
+
+func NewUwsWarpDocker() docker.Docker {
+	if !warpRegisterd {
+		registerWarpProtocols()
+	}
+	dkr := &UwsWarpDockerImpl{
+		maxRequestBuffer: cgiwarp.DEFAULT_MAX_REQUEST_BUFFER,
+	}
+	dkr.WarpBase = base.NewWarpBase(dkr)
+	dkr.modifier1 = uwsgi.DEFAULT_MODIFIER1
+	dkr.modifier2 = uwsgi.DEFAULT_MODIFIER2
+
+	var _ docker.Warp = dkr         // implement check
+	var _ docker.Club = dkr         // implement check
+	var _ uwsgi.UwsWarpDocker = dkr // implement check
+	return dkr
+}
+
+func (d *UwsWarpDockerImpl) String() string {
+	return "UwsWarp"
+}
+
+/****************************************/
+/* Implements DockerInitializer         */
+/****************************************/
+
+func (d *UwsWarpDockerImpl) InitKeyVal(kv *bcf.BcfKeyVal) (bool, exception.ConfigException) {
+	switch strings.ToLower(kv.Key) {
+	case "scriptbase":
+		d.scriptBase = kv.Value
+
+	case "docroot":
+		d.docRoot = kv.Value
+
+	case "maxrequestbuffer":
+		var err error
+		d.maxRequestBuffer, err = strutil.ParseSize(kv.Value)
+		if err != nil || d.maxRequestBuffer < 0 {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "modifier1":
+		var err error
+		d.modifier1, err = strutil.ParseInt(kv.Value)
+		if err != nil || d.modifier1 < 0 || d.modifier1 > 255 {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	case "modifier2":
+		var err error
+		d.modifier2, err = strutil.ParseInt(kv.Value)
+		if err != nil || d.modifier2 < 0 || d.modifier2 > 255 {
+			return false, exception.NewConfigException(kv.FileName, kv.LineNo, baymessage.Get(symbol.CFG_INVALID_PARAMETER_VALUE, kv.Value))
+		}
+
+	default:
+		return d.WarpBase.InitKeyVal(kv)
+	}
+
+	return true, nil
+}
+
+/****************************************/
+/* Implements WarpSub                  */
+/****************************************/
+
+func (d *UwsWarpDockerImpl) Secure() bool {
+	return false
+}
+
+func (d *UwsWarpDockerImpl) Protocol() string {
+	return uwsgi.UWS_PROTO_NAME
+}
+
+func (d *UwsWarpDockerImpl) NewTransporter(agt agent.GrandAgent, rd rudder.Rudder, sip ship.Ship) (*multiplexer.PlainTransporter, exception2.IOException) {
+	bufSize, ioerr := rd.(*impl.TcpConnRudder).GetSocketReceiveBufferSize()
+	if ioerr != nil {
+		return nil, ioerr
+	}
+	tp := multiplexer.NewPlainTransporter(
+		agt.NetMultiplexer(),
+		sip,
+		false,
+		bufSize,
+		false)
+	tp.Init()
+	return tp, nil
+}
+
+/****************************************/
+/* Implements UwsWarpDocker             */
+/****************************************/
+
+func (d *UwsWarpDockerImpl) ScriptBase() string {
+	return d.scriptBase
+}
+
+func (d *UwsWarpDockerImpl) DocRoot() string {
+	return d.docRoot
+}
+
+func (d *UwsWarpDockerImpl) MaxRequestBuffer() int {
+	return d.maxRequestBuffer
+}
+
+func (d *UwsWarpDockerImpl) Modifier1() int {
+	return d.modifier1
+}
+
+func (d *UwsWarpDockerImpl) Modifier2() int {
+	return d.modifier2
+}
+
+/****************************************/
+/* Static function                      */
+/****************************************/
+
+func registerWarpProtocols() {
+	packetstore.RegisterPacketProtocol(
+		uwsgi.UWS_PROTO_NAME,
+		uwsgi.UwsPacketFactory,
+	)
+	protocolhandlerstore.RegisterProtocol(
+		uwsgi.UWS_PROTO_NAME,
+		false,
+		uwsgi.UwsWarpProtocolHandlerFactory,
+	)
+	warpRegisterd = true
+}
@@ -0,0 +1,13 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/util/exception"
+)
+
+type UwsCommandHandler interface {
+	protocol.CommandHandler
+	HandleHeader(cmd *CmdHeader) (common.NextSocketAction, exception.IOException)
+	HandleContent(cmd *CmdContent) (common.NextSocketAction, exception.IOException)
+}
@@ -0,0 +1,59 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	"bayserver-core/baykit/bayserver/util/baylog"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ *
+ * uWSGI response rule
+ *
+ *   Content*
+ *
+ */
+
+type UwsCommandUnpacker struct {
+	impl.CommandUnpackerImpl
+	Handler UwsCommandHandler
+}
+
+func NewUwsCommandUnpacker(handler UwsCommandHandler) *UwsCommandUnpacker {
+	cu := UwsCommandUnpacker{
+		Handler: handler,
+	}
+	cu.Reset()
+	return &cu
+}
+
+/****************************************/
+/* Implements CommandUnpacker           */
+/****************************************/
+
+func (cu *UwsCommandUnpacker) PacketReceived(pkt protocol.Packet) (common.NextSocketAction, exception2.IOException) {
+	baylog.Debug("uws: read packet type=%d length=%d", pkt.Type(), pkt.DataLen())
+
+	var cmd protocol.Command
+	switch pkt.Type() {
+	case UWS_TYPE_HEADER:
+		cmd = NewCmdHeader(DEFAULT_MODIFIER1, DEFAULT_MODIFIER2)
+
+	case UWS_TYPE_CONTENT:
+		cmd = NewCmdContent(nil, 0, 0)
+
+	default:
+		baylog.FatalE(exception2.NewSink("Illegal State"), "")
+		panic("Error")
+	}
+
+	ioerr := cmd.Unpack(pkt)
+	if ioerr != nil {
+		return -1, ioerr
+	}
+
+	return cmd.Handle(cu.Handler)
+
+}
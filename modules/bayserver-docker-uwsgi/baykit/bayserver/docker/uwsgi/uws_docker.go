@@ -0,0 +1,3 @@
+package uwsgi
+
+const UWS_PROTO_NAME = "uwsgi"
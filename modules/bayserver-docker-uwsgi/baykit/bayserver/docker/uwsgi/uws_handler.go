@@ -0,0 +1,15 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/common/exception"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+type UwsHandler interface {
+	UwsCommandHandler
+
+	/**
+	 * Send protocol error to client
+	 */
+	OnProtocolError(e exception.ProtocolException) (bool, exception2.IOException)
+}
@@ -0,0 +1,44 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/protocol"
+	"strconv"
+)
+
+/**
+ * uWSGI spec
+ *   https://uwsgi-docs.readthedocs.io/en/latest/Protocol.html
+ *
+ * uWSGI Packet format
+ *   The request starts with 4 bytes header and vars block follows. Then content follows.
+ *   Response is HTTP response which ends when server closes the connection.
+ *
+ *         struct uwsgi_packet_header {
+ *             uint8_t modifier1;
+ *             uint16_t datasize;  (little endian)
+ *             uint8_t modifier2;
+ *         };
+ *
+ *   Vars block:  ([key_size(uint16 LE)][key][val_size(uint16 LE)][val])*
+ */
+
+const UWS_HEADER_SIZE = 4
+const UWS_PACKET_MAXLEN = 65535
+
+type UwsPacket struct {
+	protocol.PacketImpl
+}
+
+func NewUwsPacket(typ int) *UwsPacket {
+	p := UwsPacket{}
+	p.ConstructPacket(typ, 0, UWS_PACKET_MAXLEN)
+	return &p
+}
+
+func (p *UwsPacket) Reset() {
+	p.PacketImpl.Reset()
+}
+
+func (p *UwsPacket) String() string {
+	return "UwsPacket(" + strconv.Itoa(p.Type()) + ")"
+}
@@ -0,0 +1,7 @@
+package uwsgi
+
+import "bayserver-core/baykit/bayserver/protocol"
+
+func UwsPacketFactory(typ int) protocol.Packet {
+	return NewUwsPacket(typ)
+}
@@ -0,0 +1,78 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	"bayserver-core/baykit/bayserver/protocol/packetstore"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+/**
+ * Packet unmarshall logic for uWSGI
+ *   Since uWSGI response has no framing, received bytes are passed as content packets as they are.
+ */
+
+type UwsPacketUnpacker struct {
+	impl.PacketUnpackerImpl
+
+	cmdUnpacker *UwsCommandUnpacker
+	pktStore    *packetstore.PacketStore
+}
+
+func NewUwsPacketUnpacker(cmdUnpacker *UwsCommandUnpacker, pktStore *packetstore.PacketStore) *UwsPacketUnpacker {
+	pu := UwsPacketUnpacker{
+		cmdUnpacker: cmdUnpacker,
+		pktStore:    pktStore,
+	}
+	pu.Reset()
+	return &pu
+}
+
+/****************************************/
+/* Implements Reusable                  */
+/****************************************/
+
+func (pu *UwsPacketUnpacker) Reset() {
+}
+
+/****************************************/
+/* Implements PacketUnpacker            */
+/****************************************/
+
+func (pu *UwsPacketUnpacker) BytesReceived(buf []byte) (common.NextSocketAction, exception2.IOException) {
+	nextSuspend := false
+	pos := 0
+
+	for pos < len(buf) {
+		length := len(buf) - pos
+		if length > UWS_PACKET_MAXLEN {
+			length = UWS_PACKET_MAXLEN
+		}
+
+		pkt := pu.pktStore.Rent(UWS_TYPE_CONTENT)
+		pkt.NewDataAccessor().PutBytes(buf, pos, length)
+		pos += length
+
+		nextAct, ioerr := pu.cmdUnpacker.PacketReceived(pkt)
+		pu.pktStore.Return(pkt)
+		if ioerr != nil {
+			return -1, ioerr
+		}
+
+		switch nextAct {
+		case common.NEXT_SOCKET_ACTION_SUSPEND:
+			nextSuspend = true
+		case common.NEXT_SOCKET_ACTION_CONTINUE:
+			break
+		case common.NEXT_SOCKET_ACTION_CLOSE:
+			return nextAct, nil
+		}
+	}
+
+	if nextSuspend {
+		return common.NEXT_SOCKET_ACTION_SUSPEND, nil
+
+	} else {
+		return common.NEXT_SOCKET_ACTION_CONTINUE, nil
+	}
+}
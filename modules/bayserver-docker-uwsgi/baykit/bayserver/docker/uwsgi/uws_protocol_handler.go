@@ -0,0 +1,50 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+)
+
+type UwsProtocolHandler interface {
+	protocol.ProtocolHandler
+}
+
+type UwsProtocolHandlerImpl struct {
+	impl.ProtocolHandlerImpl
+}
+
+func NewUwsProtocolHandler(
+	packetUnpacker protocol.PacketUnpacker,
+	packetPacker protocol.PacketPacker,
+	commandUnpacker protocol.CommandUnpacker,
+	commandPacker protocol.CommandPacker,
+	commandHandler protocol.CommandHandler,
+	serverMode bool) *UwsProtocolHandlerImpl {
+
+	ph := UwsProtocolHandlerImpl{}
+	ph.ProtocolHandlerImpl.ConstructProtocolHandler(packetUnpacker, packetPacker, commandUnpacker, commandPacker, commandHandler, serverMode)
+	return &ph
+}
+
+/****************************************/
+/* Implements Reusable                  */
+/****************************************/
+
+func (h *UwsProtocolHandlerImpl) Reset() {
+	h.ProtocolHandlerImpl.Reset()
+}
+
+/****************************************/
+/* Implements ProtocolHandler           */
+/****************************************/
+
+func (h *UwsProtocolHandlerImpl) Protocol() string {
+	return UWS_PROTO_NAME
+}
+
+func (h *UwsProtocolHandlerImpl) MaxReqPacketDataSize() int {
+	return UWS_PACKET_MAXLEN
+}
+func (h *UwsProtocolHandlerImpl) MaxResPacketDataSize() int {
+	return UWS_PACKET_MAXLEN
+}
@@ -0,0 +1,4 @@
+package uwsgi
+
+const UWS_TYPE_HEADER = 1
+const UWS_TYPE_CONTENT = 2
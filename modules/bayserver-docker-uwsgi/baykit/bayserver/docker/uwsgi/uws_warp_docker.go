@@ -0,0 +1,16 @@
+package uwsgi
+
+import "bayserver-core/baykit/bayserver/common/cgiwarp"
+
+const DEFAULT_MODIFIER1 = 0 // WSGI request
+const DEFAULT_MODIFIER2 = 0
+
+type UwsWarpDocker interface {
+	cgiwarp.CgiWarpDocker
+
+	// Modifier1 returns modifier1 of the packet header which selects the request handler of uWSGI server
+	Modifier1() int
+
+	// Modifier2 returns modifier2 of the packet header
+	Modifier2() int
+}
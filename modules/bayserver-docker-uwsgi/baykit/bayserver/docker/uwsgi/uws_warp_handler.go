@@ -0,0 +1,63 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/common"
+	"bayserver-core/baykit/bayserver/common/cgiwarp"
+	"bayserver-core/baykit/bayserver/common/exception"
+	"bayserver-core/baykit/bayserver/common/warpship"
+	"bayserver-core/baykit/bayserver/docker"
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/tour"
+	"bayserver-core/baykit/bayserver/util"
+	exception2 "bayserver-core/baykit/bayserver/util/exception"
+)
+
+type UwsWarpHandler struct {
+	*cgiwarp.CgiWarpHandler
+}
+
+func NewUwsWarpHandler() *UwsWarpHandler {
+	h := &UwsWarpHandler{
+		CgiWarpHandler: cgiwarp.NewCgiWarpHandler(UWS_PROTO_NAME, newHeader, newContent),
+	}
+
+	var _ tour.TourHandler = h     // cast check
+	var _ warpship.WarpHandler = h // cast check
+	var _ UwsHandler = h           // cast check
+	var _ util.Reusable = h        // cast check
+	return h
+}
+
+func (h *UwsWarpHandler) Init(handler *UwsProtocolHandlerImpl) {
+	h.CgiWarpHandler.Init(handler)
+}
+
+func (h *UwsWarpHandler) String() string {
+	return "UwsWarpHandler"
+}
+
+/****************************************/
+/* Implements UwsCommandHandler         */
+/****************************************/
+
+func (h *UwsWarpHandler) HandleHeader(cmd *CmdHeader) (common.NextSocketAction, exception2.IOException) {
+	return -1, exception.NewProtocolException("Invalid uWSGI command: %d", cmd.Type())
+}
+
+func (h *UwsWarpHandler) HandleContent(cmd *CmdContent) (common.NextSocketAction, exception2.IOException) {
+	return h.HandleResData(cmd.Data, cmd.Start, cmd.Length)
+}
+
+/****************************************/
+/* Static functions                     */
+/****************************************/
+
+func newHeader(dkr docker.Warp, env map[string]string, contLen int) protocol.Command {
+	cmd := NewCmdHeader(dkr.(UwsWarpDocker).Modifier1(), dkr.(UwsWarpDocker).Modifier2())
+	cmd.SetEnv(env, contLen)
+	return cmd
+}
+
+func newContent(buf []byte, start int, length int) protocol.Command {
+	return NewCmdContent(buf, start, length)
+}
@@ -0,0 +1,24 @@
+package uwsgi
+
+import (
+	"bayserver-core/baykit/bayserver/protocol"
+	"bayserver-core/baykit/bayserver/protocol/impl"
+	"bayserver-core/baykit/bayserver/protocol/packetstore"
+)
+
+var UwsWarpProtocolHandlerFactory = func(pktStore *packetstore.PacketStore) protocol.ProtocolHandler {
+	warpHandler := NewUwsWarpHandler()
+	commandUnpacker := NewUwsCommandUnpacker(warpHandler)
+	packetUnpacker := NewUwsPacketUnpacker(commandUnpacker, pktStore)
+	packetPacker := impl.NewPacketPacker()
+	commandPacker := impl.NewCommandPacker(packetPacker, pktStore)
+	protocolHandler := NewUwsProtocolHandler(
+		packetUnpacker,
+		packetPacker,
+		commandUnpacker,
+		commandPacker,
+		warpHandler,
+		true)
+	warpHandler.Init(protocolHandler)
+	return protocolHandler
+}
@@ -0,0 +1,3 @@
+module bayserver-docker-uwsgi
+
+go 1.18